@@ -0,0 +1,408 @@
+package cons
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidCast is returned by ReadCast when a recording is not in the asciicast v2 format.
+var ErrInvalidCast = errors.New("cons: invalid asciicast v2 recording")
+
+// The event codes of an asciicast recording.
+const (
+	CastOutput = "o" // Data written to the terminal.
+	CastInput  = "i" // Data typed by the user.
+	CastResize = "r" // The new size of the terminal, as COLUMNSxROWS.
+	CastMarker = "m" // A marker, with an optional label.
+)
+
+// CastHeader is the first line of an asciicast v2 recording.
+type CastHeader struct {
+	Width, Height int               // The size of the terminal when the recording started.
+	Timestamp     time.Time         // When the recording started, or the zero time if unknown.
+	IdleTimeLimit time.Duration     // The longest pause a player should keep, or 0 for no limit.
+	Title         string            // The title of the recording, or empty.
+	Env           map[string]string // Environment variables such as TERM and SHELL, or nil.
+}
+
+// castHeader is the JSON form of CastHeader.
+type castHeader struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// CastEvent is an event of an asciicast recording.
+type CastEvent struct {
+	Time time.Duration // The time of the event since the start of the recording.
+	Code string        // What happened, such as CastOutput or CastResize.
+	Data string
+}
+
+// Size returns the size of the terminal reported by a CastResize event.
+//
+// Returns:
+//
+//	Coord: The number of columns and rows.
+//	bool: Whether the event is a resize with a valid size.
+func (ev CastEvent) Size() (Coord, bool) {
+	var width, height int16
+	if ev.Code != CastResize {
+		return Coord{}, false
+	}
+
+	if n, err := fmt.Sscanf(ev.Data, "%dx%d", &width, &height); n != 2 || err != nil || width <= 0 || height <= 0 {
+		return Coord{}, false
+	}
+
+	return Coord{X: width, Y: height}, true
+}
+
+// Cast is an asciicast v2 recording, as read by ReadCast.
+type Cast struct {
+	Header CastHeader
+	Events []CastEvent
+}
+
+// ReadCast reads a recording in the asciicast v2 format, such as one made by Recorder or by asciinema.
+//
+// Parameters:
+//
+//	r: The recording, a JSON header line followed by a JSON array per event.
+//
+// Returns:
+//
+//	*Cast: The recording.
+//	error: If the function successfully reads the recording, it returns nil. Otherwise, it returns an error,
+//	wrapping ErrInvalidCast when the recording is malformed.
+func ReadCast(r io.Reader) (*Cast, error) {
+	br := bufio.NewReader(r)
+
+	line, err := br.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var header castHeader
+	if json.Unmarshal(line, &header) != nil || header.Version != 2 {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidCast)
+	}
+
+	cast := &Cast{Header: CastHeader{
+		Width:         header.Width,
+		Height:        header.Height,
+		IdleTimeLimit: time.Duration(math.Round(header.IdleTimeLimit * float64(time.Second))),
+		Title:         header.Title,
+		Env:           header.Env,
+	}}
+	if header.Timestamp != 0 {
+		cast.Header.Timestamp = time.Unix(header.Timestamp, 0)
+	}
+
+	for n := 2; err != io.EOF; n++ {
+		var line []byte
+		if line, err = br.ReadBytes('\n'); err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			var (
+				fields []json.RawMessage
+				at     float64
+				ev     CastEvent
+			)
+			if json.Unmarshal(line, &fields) != nil || len(fields) != 3 || json.Unmarshal(fields[0], &at) != nil ||
+				json.Unmarshal(fields[1], &ev.Code) != nil || json.Unmarshal(fields[2], &ev.Data) != nil || at < 0 {
+				return nil, fmt.Errorf("%w: bad event on line %d", ErrInvalidCast, n)
+			}
+
+			ev.Time = time.Duration(math.Round(at * float64(time.Second)))
+			cast.Events = append(cast.Events, ev)
+		}
+	}
+
+	return cast, nil
+}
+
+// Recorder is an io.Writer that passes what is written to an output and logs it, with the time it was written,
+// as an asciicast v2 recording that asciinema and Player can replay.
+//
+//	f, err := os.Create("demo.cast")
+//	if err != nil {
+//		log.Fatalln(err)
+//	}
+//	defer f.Close()
+//
+//	rec, err := cons.NewRecorder(f, os.Stdout, cons.CastHeader{Width: 80, Height: 24})
+//	if err != nil {
+//		log.Fatalln(err)
+//	}
+//	defer rec.Close()
+//
+//	fmt.Fprintln(rec, "\x1b[1mhello\x1b[0m")
+type Recorder struct {
+	mu      sync.Mutex
+	cast    io.Writer // The destination of the recording.
+	out     io.Writer // The output what is written is passed to, or nil.
+	start   time.Time
+	pending []byte // The start of a UTF-8 sequence cut by the end of the last write, logged with the next one.
+}
+
+// NewRecorder starts a recording, writing its header.
+//
+// Parameters:
+//
+//	cast: The destination of the recording, such as a file.
+//	out: The output what is written to the recorder is passed to, such as os.Stdout, or nil to only record it.
+//	header: The size of the terminal and the other fields of the header; a zero Timestamp is set to now.
+//
+// Returns:
+//
+//	*Recorder: The recorder, whose events are timed from now.
+//	error: If the function successfully writes the header, it returns nil. Otherwise, it returns an error.
+func NewRecorder(cast io.Writer, out io.Writer, header CastHeader) (*Recorder, error) {
+	if header.Width <= 0 || header.Height <= 0 {
+		return nil, ErrInvalidParameter
+	}
+
+	r := &Recorder{cast: cast, out: out, start: time.Now()}
+	if header.Timestamp.IsZero() {
+		header.Timestamp = r.start
+	}
+
+	line, err := json.Marshal(castHeader{
+		Version:       2,
+		Width:         header.Width,
+		Height:        header.Height,
+		Timestamp:     header.Timestamp.Unix(),
+		IdleTimeLimit: header.IdleTimeLimit.Seconds(),
+		Title:         header.Title,
+		Env:           header.Env,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := cast.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Write passes p to the output and records what the output accepted as a CastOutput event.
+//
+// Parameters:
+//
+//	p: The bytes written, usually text with VT sequences.
+//
+// Returns:
+//
+//	int: The number of bytes the output accepted, len(p) without output.
+//	error: If the function successfully writes and records p, it returns nil. Otherwise, it returns an error.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := len(p), error(nil)
+	if r.out != nil {
+		n, err = r.out.Write(p)
+	}
+
+	data := append(r.pending, p[:n]...)
+	r.pending = nil
+
+	// A UTF-8 sequence cut by the end of p is kept until the next write, as events hold whole characters.
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				data, r.pending = data[:i], append([]byte(nil), data[i:]...)
+			}
+			break
+		}
+	}
+
+	if len(data) > 0 {
+		if cerr := r.record(CastOutput, string(data)); err == nil {
+			err = cerr
+		}
+	}
+
+	return n, err
+}
+
+// Resize records the new size of the terminal, such as the size of a ResizeEvent, as a CastResize event.
+//
+// Parameters:
+//
+//	size: The number of columns and rows of the terminal.
+//
+// Returns:
+//
+//	error: If the function successfully records the size, it returns nil. Otherwise, it returns an error.
+func (r *Recorder) Resize(size Coord) error {
+	if size.X <= 0 || size.Y <= 0 {
+		return ErrInvalidParameter
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.record(CastResize, fmt.Sprintf("%dx%d", size.X, size.Y))
+}
+
+// Marker records a marker, which players let the viewer jump to.
+//
+// Parameters:
+//
+//	label: The label of the marker, or empty.
+//
+// Returns:
+//
+//	error: If the function successfully records the marker, it returns nil. Otherwise, it returns an error.
+func (r *Recorder) Marker(label string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.record(CastMarker, label)
+}
+
+// Close records the bytes of an incomplete UTF-8 sequence left by the last write. It closes neither
+// the recording nor the output.
+//
+// Returns:
+//
+//	error: If the function successfully records the bytes left, it returns nil. Otherwise, it returns an error.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	data := string(r.pending)
+	r.pending = nil
+	return r.record(CastOutput, data)
+}
+
+// record writes an event line, timed from the start of the recording.
+func (r *Recorder) record(code, data string) error {
+	var encoded bytes.Buffer
+
+	// The data is terminal output, where escaping <, > and & as for HTML only makes the recording harder to read.
+	enc := json.NewEncoder(&encoded)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	at := strconv.FormatFloat(time.Since(r.start).Seconds(), 'f', 6, 64)
+	_, err := io.WriteString(r.cast, "["+at+", \""+code+"\", "+string(bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))+"]\n")
+	return err
+}
+
+// Player replays an asciicast recording into an output target, interpreting the VT sequences of the recording
+// with a VTWriter, so that it plays on a console without VT processing or into a VirtualConsole, as well as on a
+// terminal. A recording replayed into a VirtualConsole can then be compared with the expected screen.
+type Player struct {
+	// Speed is how many times faster than recorded the events are replayed, 1 for the recorded pace,
+	// or 0 or less to replay them without pausing.
+	Speed float64
+
+	// MaxIdle is the longest pause between two events, after Speed applies, or 0 for the IdleTimeLimit
+	// of the recording, pauses being kept as they are when both are 0.
+	MaxIdle time.Duration
+}
+
+// Play replays the output and resize events of a recording into out. When out can be resized, as a
+// VirtualConsole can, it is first resized to the size of the recording, then to the size of each resize event.
+//
+// Parameters:
+//
+//	ctx: The context stopping the replay when done.
+//	cast: The recording, as read by ReadCast.
+//	out: The output side of the console the recording is replayed into.
+//
+// Returns:
+//
+//	error: If the function replays the whole recording, it returns nil. Otherwise, it returns an error,
+//	the error of ctx when the replay is stopped.
+func (p Player) Play(ctx context.Context, cast *Cast, out OutputTarget) error {
+	resizer, canResize := out.(interface{ Resize(size Coord) error })
+	if canResize && cast.Header.Width > 0 && cast.Header.Height > 0 {
+		size := Coord{X: int16(min(cast.Header.Width, math.MaxInt16)), Y: int16(min(cast.Header.Height, math.MaxInt16))}
+		if err := resizer.Resize(size); err != nil {
+			return err
+		}
+	}
+
+	w, err := NewVTWriter(out)
+	if err != nil {
+		return err
+	}
+
+	maxIdle := p.MaxIdle
+	if maxIdle <= 0 {
+		maxIdle = cast.Header.IdleTimeLimit
+	}
+
+	var last time.Duration
+	for _, ev := range cast.Events {
+		if err := p.wait(ctx, ev.Time-last, maxIdle); err != nil {
+			return err
+		}
+		last = ev.Time
+
+		switch ev.Code {
+		case CastOutput:
+			if _, err := io.WriteString(w, ev.Data); err != nil {
+				return err
+			}
+		case CastResize:
+			if size, ok := ev.Size(); ok && canResize {
+				if err := resizer.Resize(size); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// wait pauses for the time between two events, scaled by Speed and capped by maxIdle.
+func (p Player) wait(ctx context.Context, pause, maxIdle time.Duration) error {
+	if p.Speed <= 0 || pause <= 0 {
+		return ctx.Err()
+	}
+
+	pause = time.Duration(float64(pause) / p.Speed)
+	if maxIdle > 0 {
+		pause = min(pause, maxIdle)
+	}
+
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package cons
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	var cast, out bytes.Buffer
+	rec, err := NewRecorder(&cast, &out, CastHeader{Width: 20, Height: 4, Title: "demo", Env: map[string]string{"TERM": "xterm"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "é" is split across two writes, and recorded whole with the second.
+	for _, s := range []string{"h\xc3", "\xa9llo <&>\r\n", "\x1b[31mred\x1b[0m"} {
+		if n, err := rec.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if err := rec.Resize(Coord{X: 30, Y: 6}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Marker("resized"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Write([]byte("\r\nwide")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "héllo <&>\r\n\x1b[31mred\x1b[0m\r\nwide"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if !strings.Contains(cast.String(), `"éllo <&>\r\n"`) {
+		t.Errorf("recording %q does not hold the text unescaped", cast.String())
+	}
+
+	c, err := ReadCast(&cast)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h := c.Header; h.Width != 20 || h.Height != 4 || h.Title != "demo" || h.Env["TERM"] != "xterm" || h.Timestamp.IsZero() {
+		t.Errorf("header = %+v", h)
+	}
+
+	want := []CastEvent{
+		{Code: CastOutput, Data: "h"},
+		{Code: CastOutput, Data: "éllo <&>\r\n"},
+		{Code: CastOutput, Data: "\x1b[31mred\x1b[0m"},
+		{Code: CastResize, Data: "30x6"},
+		{Code: CastMarker, Data: "resized"},
+		{Code: CastOutput, Data: "\r\nwide"},
+	}
+	if len(c.Events) != len(want) {
+		t.Fatalf("events = %+v, want %+v", c.Events, want)
+	}
+	for i, ev := range c.Events {
+		if ev.Code != want[i].Code || ev.Data != want[i].Data {
+			t.Errorf("event %d = %+v, want %+v", i, ev, want[i])
+		}
+		if i > 0 && ev.Time < c.Events[i-1].Time {
+			t.Errorf("event %d at %v is before the event before it", i, ev.Time)
+		}
+	}
+
+	vc := NewVirtualConsole(10, 2)
+	if err := (Player{}).Play(context.Background(), c, vc); err != nil {
+		t.Fatal(err)
+	}
+
+	var scrbufinfo ScreenBufferInfo
+	vc.GetScreenBufferInfo(&scrbufinfo)
+	if scrbufinfo.Size != (Coord{X: 30, Y: 6}) {
+		t.Errorf("size = %+v, want 30x6", scrbufinfo.Size)
+	}
+	for y, line := range []string{"héllo <&>", "red", "wide"} {
+		if got := vc.Line(int16(y)); got != line {
+			t.Errorf("line %d = %q, want %q", y, got, line)
+		}
+	}
+	if attr := vc.Cell(0, 1).Attributes; attr&0x0f != ForegroundRed {
+		t.Errorf("attributes of red = %#x, want red", attr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := (Player{}).Play(ctx, c, NewVirtualConsole(10, 2)); err != context.Canceled {
+		t.Errorf("Play with a canceled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestRecorderClose(t *testing.T) {
+	var cast bytes.Buffer
+	rec, err := NewRecorder(&cast, nil, CastHeader{Width: 10, Height: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec.Write([]byte("ab\xe2\x94"))
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ReadCast(&cast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each byte of the incomplete sequence is recorded as U+FFFD, as JSON strings hold valid UTF-8 only.
+	if len(c.Events) != 2 || c.Events[0].Data != "ab" || c.Events[1].Data != "\ufffd\ufffd" {
+		t.Errorf("events = %+v, want ab and the incomplete sequence", c.Events)
+	}
+
+	if _, err := NewRecorder(&cast, nil, CastHeader{Width: 0, Height: 2}); err != ErrInvalidParameter {
+		t.Errorf("NewRecorder without a width = %v, want ErrInvalidParameter", err)
+	}
+	if err := rec.Resize(Coord{X: 0, Y: 1}); err != ErrInvalidParameter {
+		t.Errorf("Resize to no columns = %v, want ErrInvalidParameter", err)
+	}
+}
+
+func TestReadCastInvalid(t *testing.T) {
+	const header = `{"version": 2, "width": 80, "height": 24}` + "\n"
+
+	tests := []struct {
+		name string
+		cast string
+	}{
+		{"empty", ""},
+		{"not json", "asciicast\n"},
+		{"version 1", `{"version": 1, "width": 80, "height": 24}` + "\n"},
+		{"event not an array", header + `{"time": 1}` + "\n"},
+		{"event with two fields", header + `[0.5, "o"]` + "\n"},
+		{"negative time", header + `[-1, "o", "x"]` + "\n"},
+		{"time not a number", header + `["1", "o", "x"]` + "\n"},
+		{"data not a string", header + `[1, "o", 5]` + "\n"},
+		{"bad line after a good one", header + `[1, "o", "x"]` + "\n" + "[1,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadCast(strings.NewReader(tt.cast)); !errors.Is(err, ErrInvalidCast) {
+				t.Errorf("ReadCast = %v, want ErrInvalidCast", err)
+			}
+		})
+	}
+
+	c, err := ReadCast(strings.NewReader(header + "\n" + `[1.5, "r", "100x40"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, ok := c.Events[0].Size(); !ok || size != (Coord{X: 100, Y: 40}) || c.Events[0].Time.Seconds() != 1.5 {
+		t.Errorf("event = %+v, want a resize to 100x40 at 1.5s", c.Events[0])
+	}
+}
@@ -0,0 +1,267 @@
+package cons
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Capture is a snapshot of a rectangle of a screen buffer, to attach what the console looked like to a
+// bug report. It exports the cells as plain text, text with SGR sequences, HTML and SVG.
+//
+//	capture, err := cons.CaptureWindow(c.Output())
+//	if err != nil {
+//		log.Fatalln(err)
+//	}
+//
+//	os.WriteFile("screen.svg", []byte(capture.SVG()), 0o644)
+type Capture struct {
+	Size  Coord      // The size of the snapshot, in columns and rows.
+	Cells []CharInfo // The cells of the snapshot, row by row.
+
+	// Palette is the color of each attribute nibble in the HTML and SVG exports, the default palette of the
+	// console when captured; it can be replaced by the palette of the console the snapshot comes from.
+	Palette [16]Color
+}
+
+// Capture cells are drawn in SVG on a grid of captureCellWidth by captureCellHeight pixels,
+// with a font of captureFontSize pixels.
+const (
+	captureCellWidth  = 9
+	captureCellHeight = 18
+	captureFontSize   = 15
+)
+
+// NewCapture creates a snapshot of cells, with the default palette of the console.
+//
+// Parameters:
+//
+//	cells: The cells, row by row, size.X cells per row.
+//	size: The size of the snapshot, in columns and rows.
+//
+// Returns:
+//
+//	*Capture: The snapshot, holding a copy of cells.
+//	error: If cells holds size.X by size.Y cells, it returns nil. Otherwise, it returns ErrInvalidParameter.
+func NewCapture(cells []CharInfo, size Coord) (*Capture, error) {
+	if size.X < 0 || size.Y < 0 || len(cells) != int(size.X)*int(size.Y) {
+		return nil, ErrInvalidParameter
+	}
+
+	c := &Capture{Size: size, Cells: append([]CharInfo(nil), cells...)}
+	for i := range c.Palette {
+		c.Palette[i] = NibbleColor(uint16(i))
+	}
+
+	return c, nil
+}
+
+// CaptureRegion snapshots a rectangle of the screen buffer, read with ReadOutput.
+//
+// Parameters:
+//
+//	out: The output side of the console, such as a Handle or a VirtualConsole.
+//	region: The rectangle to capture, clipped to the screen buffer.
+//
+// Returns:
+//
+//	*Capture: The snapshot of the part of region inside the screen buffer.
+//	error: If the function successfully reads the cells, it returns nil. Otherwise, it returns an error.
+func CaptureRegion(out OutputTarget, region SmallRect) (*Capture, error) {
+	if region.Left > region.Right || region.Top > region.Bottom {
+		return nil, ErrInvalidParameter
+	}
+
+	size := Coord{X: region.Right - region.Left + 1, Y: region.Bottom - region.Top + 1}
+	cells := make([]CharInfo, int(size.X)*int(size.Y))
+	orig := region
+	if err := out.ReadOutput(cells, size, Coord{}, &region); err != nil {
+		return nil, err
+	}
+
+	if region.Left > region.Right || region.Top > region.Bottom {
+		return nil, ErrInvalidParameter
+	}
+
+	// ReadOutput leaves the cells of the clipped rectangle where they would be in the whole one,
+	// shifted right and down by the columns and rows clipped off the left and top.
+	clipped := Coord{X: region.Right - region.Left + 1, Y: region.Bottom - region.Top + 1}
+	offset := int(region.Top-orig.Top)*int(size.X) + int(region.Left-orig.Left)
+	rows := make([]CharInfo, 0, int(clipped.X)*int(clipped.Y))
+	for y := 0; y < int(clipped.Y); y++ {
+		rows = append(rows, cells[offset+y*int(size.X):][:clipped.X]...)
+	}
+
+	return NewCapture(rows, clipped)
+}
+
+// CaptureWindow snapshots the visible window of the screen buffer.
+//
+// Parameters:
+//
+//	out: The output side of the console, such as a Handle or a VirtualConsole.
+//
+// Returns:
+//
+//	*Capture: The snapshot of the window.
+//	error: If the function successfully reads the cells, it returns nil. Otherwise, it returns an error.
+func CaptureWindow(out OutputTarget) (*Capture, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return nil, err
+	}
+
+	return CaptureRegion(out, scrbufinfo.Window)
+}
+
+// Row returns the cells of a row of the snapshot, or nil if y is outside it.
+func (c *Capture) Row(y int16) []CharInfo {
+	if y < 0 || y >= c.Size.Y {
+		return nil
+	}
+
+	return c.Cells[int(y)*int(c.Size.X) : int(y+1)*int(c.Size.X)]
+}
+
+// Text returns the text of the snapshot, one line per row without its trailing spaces.
+func (c *Capture) Text() string {
+	var sb strings.Builder
+	for y := int16(0); y < c.Size.Y; y++ {
+		sb.WriteString(strings.TrimRight(captureText(c.Row(y)), " "))
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// ANSI returns the text of the snapshot with SGR sequences for the attributes, downsampled to a color profile,
+// to be shown again by a VT terminal or by a pager such as less -R.
+//
+// Parameters:
+//
+//	profile: The colors the terminal can display; ProfileNoColor returns the same text as Text.
+//
+// Returns:
+//
+//	string: The text, one line per row, each ending with a reset.
+func (c *Capture) ANSI(profile ColorProfile) string {
+	if profile == ProfileNoColor {
+		return c.Text()
+	}
+
+	var sb strings.Builder
+	for y := int16(0); y < c.Size.Y; y++ {
+		for _, run := range cellRuns(c.Row(y)) {
+			sb.WriteString(StyleFromAttributes(run[0].Attributes).SGR(profile))
+			sb.WriteString(captureText(run))
+		}
+		sb.WriteString("\x1b[0m\n")
+	}
+
+	return sb.String()
+}
+
+// HTML returns the snapshot as a pre element with inline styles, in the colors of the palette,
+// to be pasted in a page or an issue tracker accepting HTML.
+func (c *Capture) HTML() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, `<pre style="background-color:%s;color:%s;font-family:monospace;line-height:1.2;padding:0.5em">`,
+		c.hex(0), c.hex(ForegroundBlue|ForegroundGreen|ForegroundRed))
+	for y := int16(0); y < c.Size.Y; y++ {
+		for _, run := range cellRuns(c.Row(y)) {
+			fg, bg := c.colors(run[0].Attributes)
+
+			style := "color:" + c.hex(fg) + ";background-color:" + c.hex(bg)
+			if run[0].Attributes&CommonLvbUnderscore != 0 {
+				style += ";text-decoration:underline"
+			}
+			fmt.Fprintf(&sb, `<span style="%s">%s</span>`, style, html.EscapeString(captureText(run)))
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("</pre>\n")
+
+	return sb.String()
+}
+
+// SVG returns the snapshot as a standalone SVG image, drawing each cell on a fixed grid in the colors of the
+// palette, so that it looks the same in any viewer with a monospace font.
+func (c *Capture) SVG() string {
+	var sb strings.Builder
+
+	width, height := int(c.Size.X)*captureCellWidth, int(c.Size.Y)*captureCellHeight
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&sb, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", c.hex(0))
+	fmt.Fprintf(&sb, `<g font-family="Consolas,Menlo,'DejaVu Sans Mono',monospace" font-size="%d" xml:space="preserve">`+"\n",
+		captureFontSize)
+
+	for y := int16(0); y < c.Size.Y; y++ {
+		x := 0
+		for _, run := range cellRuns(c.Row(y)) {
+			fg, bg := c.colors(run[0].Attributes)
+			top, left, w := int(y)*captureCellHeight, x*captureCellWidth, len(run)*captureCellWidth
+			x += len(run)
+
+			if bg != 0 {
+				fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+					left, top, w, captureCellHeight, c.hex(bg))
+			}
+
+			text := captureText(run)
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+
+			decoration := ""
+			if run[0].Attributes&CommonLvbUnderscore != 0 {
+				decoration = ` text-decoration="underline"`
+			}
+
+			// textLength stretches the run over its cells, so wide characters and fonts of another width stay on the grid.
+			fmt.Fprintf(&sb, `<text x="%d" y="%d" fill="%s" textLength="%d" lengthAdjust="spacingAndGlyphs"%s>%s</text>`+"\n",
+				left, top+captureCellHeight-4, c.hex(fg), w, decoration, html.EscapeString(text))
+		}
+	}
+	sb.WriteString("</g>\n</svg>\n")
+
+	return sb.String()
+}
+
+// colors returns the foreground and background nibbles a cell is drawn in, swapped by reverse video.
+func (c *Capture) colors(attr uint16) (fg, bg uint16) {
+	fg, bg = attr&0x0f, attr>>4&0x0f
+	if attr&CommonLvbReverseVideo != 0 {
+		fg, bg = bg, fg
+	}
+
+	return fg, bg
+}
+
+// hex returns the color of a nibble in the palette as #rrggbb.
+func (c *Capture) hex(nibble uint16) string {
+	r, g, b := c.Palette[nibble&0x0f].RGB()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// cellRuns splits a row of cells into runs of cells with the same attributes, the halves of wide characters aside.
+func cellRuns(row []CharInfo) [][]CharInfo {
+	var runs [][]CharInfo
+	for i := 0; i < len(row); {
+		n := 1
+		for i+n < len(row) && row[i+n].Attributes&^halfAttributes == row[i].Attributes&^halfAttributes {
+			n++
+		}
+
+		runs = append(runs, row[i:i+n])
+		i += n
+	}
+
+	return runs
+}
+
+// captureText returns the text of a run of cells, with the empty cells of a console buffer as spaces.
+func captureText(cells []CharInfo) string {
+	return strings.ReplaceAll(cellsText(cells), "\x00", " ")
+}
@@ -0,0 +1,136 @@
+package cons
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaptureRegion(t *testing.T) {
+	vc := newLetters(t, "abcdef", "ghijkl", "mnopqr")
+
+	tests := []struct {
+		name   string
+		region SmallRect
+		size   Coord
+		text   string
+		err    error
+	}{
+		{"whole", SmallRect{Right: 5, Bottom: 2}, Coord{X: 6, Y: 3}, "abcdef\nghijkl\nmnopqr\n", nil},
+		{"inside", SmallRect{Left: 1, Top: 1, Right: 3, Bottom: 2}, Coord{X: 3, Y: 2}, "hij\nnop\n", nil},
+		{"negative left", SmallRect{Left: -2, Right: 5}, Coord{X: 6, Y: 1}, "abcdef\n", nil},
+		{"negative top", SmallRect{Left: 2, Top: -3, Right: 3, Bottom: 1}, Coord{X: 2, Y: 2}, "cd\nij\n", nil},
+		{"negative corner", SmallRect{Left: -1, Top: -1, Right: 1, Bottom: 1}, Coord{X: 2, Y: 2}, "ab\ngh\n", nil},
+		{"past the buffer", SmallRect{Left: 4, Top: 1, Right: 9, Bottom: 7}, Coord{X: 2, Y: 2}, "kl\nqr\n", nil},
+		{"outside", SmallRect{Left: 7, Right: 9}, Coord{}, "", ErrInvalidParameter},
+		{"empty", SmallRect{Left: 2, Right: 1}, Coord{}, "", ErrInvalidParameter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := CaptureRegion(vc, tt.region)
+			if err != tt.err {
+				t.Fatalf("CaptureRegion = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if c.Size != tt.size {
+				t.Errorf("Size = %+v, want %+v", c.Size, tt.size)
+			}
+			if text := c.Text(); text != tt.text {
+				t.Errorf("Text = %q, want %q", text, tt.text)
+			}
+		})
+	}
+}
+
+// captureSample returns a snapshot of two rows of six cells: a, <&> in light red, x in reverse video
+// and a NUL cell, then a wide character, uv underlined and two spaces.
+func captureSample(t *testing.T) *Capture {
+	t.Helper()
+
+	const (
+		plain = ForegroundBlue | ForegroundGreen | ForegroundRed
+		red   = ForegroundRed | ForegroundIntensity
+	)
+
+	c, err := NewCapture([]CharInfo{
+		{'a', plain}, {'<', red}, {'&', red}, {'>', red}, {'x', plain | CommonLvbReverseVideo}, {0, plain},
+		{'漢', plain | CommonLvbLeadingByte}, {'漢', plain | CommonLvbTrailingByte},
+		{'u', plain | CommonLvbUnderscore}, {'v', plain | CommonLvbUnderscore}, {' ', plain}, {' ', plain},
+	}, Coord{X: 6, Y: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestCaptureText(t *testing.T) {
+	c := captureSample(t)
+
+	if got, want := c.Text(), "a<&>x\n漢uv\n"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+	if got := c.ANSI(ProfileNoColor); got != c.Text() {
+		t.Errorf("ANSI(ProfileNoColor) = %q, want the text", got)
+	}
+
+	if _, err := NewCapture(c.Cells[1:], c.Size); err != ErrInvalidParameter {
+		t.Errorf("NewCapture of too few cells = %v, want %v", err, ErrInvalidParameter)
+	}
+}
+
+func TestCaptureANSI(t *testing.T) {
+	got := captureSample(t).ANSI(ProfileANSI16)
+	want := "\x1b[0ma\x1b[0;91;40m<&>\x1b[0;7mx\x1b[0m \x1b[0m\n" +
+		"\x1b[0m漢\x1b[0;4muv\x1b[0m  \x1b[0m\n"
+	if got != want {
+		t.Errorf("ANSI =\n%q, want\n%q", got, want)
+	}
+}
+
+func TestCaptureHTML(t *testing.T) {
+	got := captureSample(t).HTML()
+	want := `<pre style="background-color:#0c0c0c;color:#cccccc;font-family:monospace;line-height:1.2;padding:0.5em">` +
+		`<span style="color:#cccccc;background-color:#0c0c0c">a</span>` +
+		`<span style="color:#e74856;background-color:#0c0c0c">&lt;&amp;&gt;</span>` +
+		`<span style="color:#0c0c0c;background-color:#cccccc">x</span>` +
+		`<span style="color:#cccccc;background-color:#0c0c0c"> </span>` + "\n" +
+		`<span style="color:#cccccc;background-color:#0c0c0c">漢</span>` +
+		`<span style="color:#cccccc;background-color:#0c0c0c;text-decoration:underline">uv</span>` +
+		`<span style="color:#cccccc;background-color:#0c0c0c">  </span>` + "\n" +
+		"</pre>\n"
+	if got != want {
+		t.Errorf("HTML =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCaptureSVG(t *testing.T) {
+	got := captureSample(t).SVG()
+
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="54" height="36" viewBox="0 0 54 36">`,
+		`<rect width="100%" height="100%" fill="#0c0c0c"/>`,
+		`<text x="9" y="14" fill="#e74856" textLength="27" lengthAdjust="spacingAndGlyphs">&lt;&amp;&gt;</text>`,
+		// Reverse video draws the background in the foreground color.
+		`<rect x="36" y="0" width="9" height="18" fill="#cccccc"/>`,
+		`<text x="36" y="14" fill="#0c0c0c" textLength="9" lengthAdjust="spacingAndGlyphs">x</text>`,
+		// The wide character spans its two cells.
+		`<text x="0" y="32" fill="#cccccc" textLength="18" lengthAdjust="spacingAndGlyphs">漢</text>`,
+		`<text x="18" y="32" fill="#cccccc" textLength="18" lengthAdjust="spacingAndGlyphs" text-decoration="underline">uv</text>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SVG does not hold %s:\n%s", want, got)
+		}
+	}
+
+	// The NUL cell and the spaces draw nothing on the default background.
+	if n := strings.Count(got, "<text"); n != 5 {
+		t.Errorf("SVG has %d text elements, want 5:\n%s", n, got)
+	}
+	if n := strings.Count(got, "<rect"); n != 2 {
+		t.Errorf("SVG has %d rect elements, want 2:\n%s", n, got)
+	}
+}
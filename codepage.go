@@ -0,0 +1,559 @@
+package cons
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedCodePage is returned by GetEncoding for a code page without a conversion table,
+// such as the double-byte code pages 936, 949 and 950.
+var ErrUnsupportedCodePage = errors.New("cons: unsupported code page")
+
+// Code pages supported by GetEncoding besides Utf7 and Utf8.
+const (
+	CodePageUSASCII  = 20127 // US-ASCII.
+	CodePageLatin1   = 28591 // ISO 8859-1.
+	CodePageOEMUS    = 437   // IBM PC, the OEM code page of the United States.
+	CodePageOEM850   = 850   // Western European DOS.
+	CodePageOEM852   = 852   // Central European DOS.
+	CodePageOEM866   = 866   // Cyrillic DOS.
+	CodePageANSI1250 = 1250  // Central European Windows.
+	CodePageANSI1251 = 1251  // Cyrillic Windows.
+	CodePageANSI1252 = 1252  // Western European Windows.
+	CodePageShiftJIS = 932   // Japanese Shift JIS, both the OEM and the ANSI code page of Japanese Windows.
+)
+
+// Encoding converts text between UTF-8 and a console code page, in pure Go.
+// Characters that a code page cannot represent are encoded as '?', the default character of WideCharToMultiByte,
+// and bytes that do not decode become U+FFFD.
+type Encoding struct {
+	cp      uint32
+	high    *[128]rune // The characters of the single bytes 0x80 to 0xFF, 0 for the lead bytes of Shift JIS.
+	once    sync.Once
+	reverse map[rune]byte // The bytes of the characters of high.
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[uint32]*Encoding{}
+)
+
+// GetEncoding returns the encoding of a code page. Acp, ThreadAcp and Oemcp resolve to the code pages
+// of the system, which are 1252 and 437 outside Windows.
+//
+// Parameters:
+//
+//	cp: The code page number, such as Oemcp, Utf8 or 866.
+//
+// Returns:
+//
+//	*Encoding: The encoding of the code page.
+//	error: If the code page is supported, it returns nil. Otherwise, it returns ErrUnsupportedCodePage.
+func GetEncoding(cp uint32) (*Encoding, error) {
+	switch cp {
+	case Acp, ThreadAcp:
+		cp, _ = systemCodePages()
+	case Oemcp:
+		_, cp = systemCodePages()
+	}
+
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if e, ok := encodings[cp]; ok {
+		return e, nil
+	}
+
+	e := &Encoding{cp: cp}
+	switch cp {
+	case Utf8, Utf7, CodePageUSASCII, CodePageLatin1:
+	case CodePageOEMUS:
+		e.high = &cp437High
+	case CodePageOEM850:
+		e.high = &cp850High
+	case CodePageOEM852:
+		e.high = &cp852High
+	case CodePageOEM866:
+		e.high = &cp866High
+	case CodePageANSI1250:
+		e.high = &cp1250High
+	case CodePageANSI1251:
+		e.high = &cp1251High
+	case CodePageANSI1252:
+		e.high = &cp1252High
+	case CodePageShiftJIS:
+		e.high = &cp932High
+	default:
+		return nil, ErrUnsupportedCodePage
+	}
+
+	encodings[cp] = e
+	return e, nil
+}
+
+// ConsoleEncoding returns the encoding of the output code page of a console.
+//
+// Parameters:
+//
+//	console: The console whose output code page is used.
+//
+// Returns:
+//
+//	*Encoding: The encoding of the output code page.
+//	error: If the code page is retrieved and supported, it returns nil. Otherwise, it returns an error.
+func ConsoleEncoding(console Console) (*Encoding, error) {
+	cp, err := console.GetOutputCodePage()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetEncoding(cp)
+}
+
+// CodePage returns the number of the code page, with Acp and Oemcp resolved.
+func (e *Encoding) CodePage() uint32 {
+	return e.cp
+}
+
+// Decode converts text in the code page to UTF-8.
+//
+// Parameters:
+//
+//	b: The text in the code page.
+//
+// Returns:
+//
+//	string: The text in UTF-8.
+func (e *Encoding) Decode(b []byte) string {
+	d := cpDecoder{e: e}
+	return string(d.decode(nil, b, true))
+}
+
+// Encode converts UTF-8 text to the code page.
+//
+// Parameters:
+//
+//	s: The text in UTF-8.
+//
+// Returns:
+//
+//	[]byte: The text in the code page.
+func (e *Encoding) Encode(s string) []byte {
+	return e.encode(nil, s)
+}
+
+// CanEncode reports whether the code page can represent r.
+func (e *Encoding) CanEncode(r rune) bool {
+	_, ok := e.encodeRune(r)
+	if !ok && e.cp == CodePageShiftJIS {
+		_, ok = shiftJISEncode(r)
+	}
+
+	return ok || e.cp == Utf8 || e.cp == Utf7
+}
+
+// NewReader returns a reader converting the text in the code page read from r to UTF-8.
+func (e *Encoding) NewReader(r io.Reader) io.Reader {
+	return &cpReader{r: r, d: cpDecoder{e: e}}
+}
+
+// NewWriter returns a writer converting UTF-8 text to the code page before writing it to w.
+// A character split across two calls to Write is converted once complete. Close writes a character
+// left incomplete by the last Write as U+FFFD; it does not close w.
+func (e *Encoding) NewWriter(w io.Writer) io.WriteCloser {
+	return &cpWriter{w: w, e: e}
+}
+
+// encodeRune converts a character of the single bytes of a code page.
+func (e *Encoding) encodeRune(r rune) (byte, bool) {
+	switch {
+	case r < 0x80:
+		return byte(r), true
+	case e.cp == CodePageLatin1 && r < 0x100:
+		return byte(r), true
+	case e.high == nil:
+		return '?', false
+	}
+
+	e.once.Do(func() {
+		e.reverse = make(map[rune]byte, len(e.high))
+		for i, c := range e.high {
+			e.reverse[c] = byte(0x80 + i)
+		}
+	})
+
+	b, ok := e.reverse[r]
+	if !ok {
+		return '?', false
+	}
+
+	return b, true
+}
+
+func (e *Encoding) encode(dst []byte, s string) []byte {
+	switch e.cp {
+	case Utf8:
+		return append(dst, strings.ToValidUTF8(s, "\uFFFD")...)
+	case Utf7:
+		return appendUTF7(dst, s)
+	}
+
+	for _, r := range s {
+		b, ok := e.encodeRune(r)
+		if !ok && e.cp == CodePageShiftJIS {
+			if c, ok := shiftJISEncode(r); ok {
+				dst = append(dst, byte(c>>8), byte(c))
+				continue
+			}
+		}
+		dst = append(dst, b)
+	}
+
+	return dst
+}
+
+// cpDecoder decodes a stream in a code page, keeping the bytes of an incomplete character between calls.
+type cpDecoder struct {
+	e       *Encoding
+	pending []byte   // The bytes of an incomplete UTF-8 character, or the lead byte of a Shift JIS character.
+	utf7    utf7Read // The state of the UTF-7 decoder.
+}
+
+func (d *cpDecoder) decode(dst, src []byte, final bool) []byte {
+	switch d.e.cp {
+	case Utf8:
+		src = append(d.pending, src...)
+		d.pending = nil
+		for len(src) > 0 {
+			if !final && !utf8.FullRune(src) {
+				d.pending = append([]byte(nil), src...)
+				break
+			}
+
+			r, size := utf8.DecodeRune(src)
+			dst = utf8.AppendRune(dst, r)
+			src = src[size:]
+		}
+		return dst
+	case Utf7:
+		for _, c := range src {
+			dst = d.utf7.feed(dst, c)
+		}
+		if final {
+			dst = d.utf7.end(dst)
+		}
+		return dst
+	case CodePageShiftJIS:
+		return d.decodeShiftJIS(dst, src, final)
+	}
+
+	for _, c := range src {
+		switch {
+		case c < 0x80:
+			dst = append(dst, c)
+		case d.e.cp == CodePageLatin1:
+			dst = utf8.AppendRune(dst, rune(c))
+		case d.e.high == nil:
+			dst = utf8.AppendRune(dst, utf8.RuneError)
+		default:
+			dst = utf8.AppendRune(dst, d.e.high[c-0x80])
+		}
+	}
+
+	return dst
+}
+
+// decodeShiftJIS decodes single bytes with the high table and pairs of a lead byte and a trail byte
+// with shiftJISDecode. A lead byte whose pair has no character becomes U+FFFD, and the byte after it
+// is decoded by itself, as Python's cp932 codec does.
+func (d *cpDecoder) decodeShiftJIS(dst, src []byte, final bool) []byte {
+	src = append(d.pending, src...)
+	d.pending = nil
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c < 0x80:
+			dst = append(dst, c)
+		case !isShiftJISLead(c):
+			dst = utf8.AppendRune(dst, d.e.high[c-0x80])
+		case i+1 == len(src) && !final:
+			d.pending = []byte{c}
+		case i+1 == len(src):
+			dst = utf8.AppendRune(dst, utf8.RuneError)
+		default:
+			r, ok := shiftJISDecode(c, src[i+1])
+			if ok {
+				i++
+			}
+			dst = utf8.AppendRune(dst, r)
+		}
+	}
+
+	return dst
+}
+
+// isShiftJISLead reports whether c is the first byte of a double-byte Shift JIS character.
+func isShiftJISLead(c byte) bool {
+	return c >= 0x81 && c <= 0x9f || c >= 0xe0 && c <= 0xfc
+}
+
+// shiftJISDecode returns the character of a lead byte and a trail byte, or U+FFFD and false if they have none.
+// The lead bytes 0xF0 to 0xF9 hold the user-defined characters, mapped in order to U+E000 to U+E757.
+func shiftJISDecode(lead, trail byte) (rune, bool) {
+	var i int
+	switch {
+	case trail >= 0x40 && trail <= 0x7e:
+		i = int(trail - 0x40)
+	case trail >= 0x80 && trail <= 0xfc:
+		i = int(trail - 0x41)
+	default:
+		return utf8.RuneError, false
+	}
+
+	if lead >= 0xf0 && lead <= 0xf9 {
+		return rune(0xe000 + int(lead-0xf0)*188 + i), true
+	}
+
+	if row := cp932Double[lead]; row != nil && row[i] != 0 {
+		return rune(row[i]), true
+	}
+
+	return utf8.RuneError, false
+}
+
+var (
+	shiftJISOnce    sync.Once
+	shiftJISReverse map[rune]uint16 // The double bytes of the characters of cp932Double and cp932Extra.
+)
+
+// shiftJISEncode returns the lead byte and trail byte of a double-byte Shift JIS character, in the high and
+// low bytes. A character with several double bytes, such as the NEC and IBM extensions, takes the first one,
+// except that the IBM extensions of rows FA to FC are preferred to the NEC-selected ones of rows ED and EE.
+func shiftJISEncode(r rune) (uint16, bool) {
+	if r >= 0xe000 && r <= 0xe757 {
+		i := int(r - 0xe000)
+		trail := 0x40 + i%188
+		if trail >= 0x7f {
+			trail++
+		}
+		return uint16(0xf0+i/188)<<8 | uint16(trail), true
+	}
+
+	shiftJISOnce.Do(func() {
+		shiftJISReverse = make(map[rune]uint16, len(cp932Extra)+8000)
+		add := func(lead int) {
+			for i, c := range cp932Double[lead] {
+				trail := 0x40 + i
+				if trail >= 0x7f {
+					trail++
+				}
+				if _, ok := shiftJISReverse[rune(c)]; !ok && c != 0 {
+					shiftJISReverse[rune(c)] = uint16(lead)<<8 | uint16(trail)
+				}
+			}
+		}
+
+		// The NEC-selected IBM extensions of rows ED and EE come last, as Windows encodes their characters
+		// with the IBM extensions of rows FA to FC.
+		for lead := range cp932Double {
+			if lead != 0xed && lead != 0xee {
+				add(lead)
+			}
+		}
+		add(0xed)
+		add(0xee)
+		for c, code := range cp932Extra {
+			shiftJISReverse[c] = code
+		}
+	})
+
+	code, ok := shiftJISReverse[r]
+	return code, ok
+}
+
+type cpReader struct {
+	r   io.Reader
+	d   cpDecoder
+	out []byte // Decoded text not read yet.
+	err error
+}
+
+func (cr *cpReader) Read(p []byte) (int, error) {
+	buf := make([]byte, 512)
+	for len(cr.out) == 0 && cr.err == nil {
+		n, err := cr.r.Read(buf)
+		cr.out = cr.d.decode(cr.out, buf[:n], err != nil)
+		cr.err = err
+	}
+
+	n := copy(p, cr.out)
+	cr.out = cr.out[n:]
+	if len(cr.out) == 0 && n == 0 {
+		return 0, cr.err
+	}
+
+	return n, nil
+}
+
+type cpWriter struct {
+	w       io.Writer
+	e       *Encoding
+	pending []byte // The bytes of an incomplete UTF-8 character.
+}
+
+// Close writes the bytes of a character left incomplete by the last Write as U+FFFD converted to the code page.
+func (cw *cpWriter) Close() error {
+	if len(cw.pending) == 0 {
+		return nil
+	}
+
+	cw.pending = nil
+	_, err := cw.w.Write(cw.e.encode(nil, "\uFFFD"))
+	return err
+}
+
+func (cw *cpWriter) Write(p []byte) (int, error) {
+	src := append(cw.pending, p...)
+	cw.pending = nil
+
+	// Hold back a character split at the end of p.
+	end := len(src)
+	for i := len(src) - 1; i >= 0 && i >= len(src)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(src[i]) {
+			if !utf8.FullRune(src[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	cw.pending = append(cw.pending, src[end:]...)
+
+	if _, err := cw.w.Write(cw.e.encode(nil, string(src[:end]))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// utf7Direct are the characters that UTF-7 writes as themselves, set D of RFC 2152 and white space.
+const utf7Direct = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789'(),-./:? \t\r\n"
+
+const utf7Base64 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// appendUTF7 encodes s in UTF-7, closing every base64 run with '-'.
+func appendUTF7(dst []byte, s string) []byte {
+	var units []uint16
+	flush := func() {
+		if len(units) == 0 {
+			return
+		}
+
+		dst = append(dst, '+')
+		var bits, n uint
+		for _, u := range units {
+			bits, n = bits<<16|uint(u), n+16
+			for n >= 6 {
+				n -= 6
+				dst = append(dst, utf7Base64[bits>>n&0x3f])
+			}
+		}
+		if n > 0 {
+			dst = append(dst, utf7Base64[bits<<(6-n)&0x3f])
+		}
+		dst = append(dst, '-')
+		units = units[:0]
+	}
+
+	for _, r := range strings.ToValidUTF8(s, "\uFFFD") {
+		switch {
+		case r == '+' && len(units) == 0:
+			dst = append(dst, '+', '-')
+		case r < 0x80 && strings.ContainsRune(utf7Direct, r):
+			flush()
+			dst = append(dst, byte(r))
+		default:
+			units = utf16.AppendRune(units, r)
+		}
+	}
+
+	flush()
+	return dst
+}
+
+// utf7Read is the state of a UTF-7 decoder.
+type utf7Read struct {
+	shifted bool   // Indicates whether the decoder is inside a base64 run.
+	first   bool   // Indicates whether the previous byte was the '+' opening the run.
+	bits    uint32 // The bits decoded but not yet part of a UTF-16 unit.
+	n       uint   // The number of bits held in bits.
+	high    uint16 // A high surrogate waiting for its low surrogate.
+}
+
+func (u *utf7Read) feed(dst []byte, c byte) []byte {
+	if !u.shifted {
+		if c == '+' {
+			u.shifted, u.first, u.bits, u.n = true, true, 0, 0
+			return dst
+		}
+		if c >= 0x80 {
+			return utf8.AppendRune(dst, utf8.RuneError)
+		}
+		return append(dst, c)
+	}
+
+	first := u.first
+	u.first = false
+	if i := strings.IndexByte(utf7Base64, c); i >= 0 {
+		u.bits, u.n = u.bits<<6|uint32(i), u.n+6
+		if u.n >= 16 {
+			u.n -= 16
+			dst = u.unit(dst, uint16(u.bits>>u.n))
+			u.bits &= 1<<u.n - 1
+		}
+		return dst
+	}
+
+	// Any other byte ends the run; '-' is absorbed, and "+-" stands for '+'.
+	dst = u.end(dst)
+	switch {
+	case c == '-' && first:
+		return append(dst, '+')
+	case c == '-':
+		return dst
+	}
+
+	return u.feed(dst, c)
+}
+
+func (u *utf7Read) unit(dst []byte, unit uint16) []byte {
+	switch {
+	case u.high != 0 && unit >= 0xdc00 && unit < 0xe000:
+		dst = utf8.AppendRune(dst, utf16.DecodeRune(rune(u.high), rune(unit)))
+		u.high = 0
+		return dst
+	case u.high != 0:
+		dst = utf8.AppendRune(dst, utf8.RuneError)
+		u.high = 0
+	}
+
+	if unit >= 0xd800 && unit < 0xdc00 {
+		u.high = unit
+		return dst
+	}
+
+	return utf8.AppendRune(dst, rune(unit))
+}
+
+// end closes a base64 run.
+func (u *utf7Read) end(dst []byte) []byte {
+	if u.high != 0 {
+		dst = utf8.AppendRune(dst, utf8.RuneError)
+	}
+
+	*u = utf7Read{}
+	return dst
+}
@@ -0,0 +1,125 @@
+package cons
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestShiftJIS(t *testing.T) {
+	e, err := GetEncoding(CodePageShiftJIS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		enc  string
+	}{
+		{"kanji", "日本語", "93fa967b8cea"},
+		{"halfwidth katakana", "ｶﾀｶﾅ", "b6c0b6c5"},
+		{"ascii", "a\\~", "615c7e"},
+		{"NEC special", "①", "8740"},
+		{"IBM extension", "髙", "fbfc"},
+		{"IBM extension over NEC-selected", "ⅰ纊", "fa40fa5c"},
+		{"NEC special over IBM extension", "Ⅰ￢∵", "875481ca81e6"},
+		{"user-defined", "\ue000\ue757", "f040f9fc"},
+		{"single bytes outside the JIS range", "\u0080\uf8f0\uf8f3", "80a0ff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(e.Encode(tt.text)); got != tt.enc {
+				t.Errorf("Encode(%q) = %s, want %s", tt.text, got, tt.enc)
+			}
+
+			b, _ := hex.DecodeString(tt.enc)
+			if got := e.Decode(b); got != tt.text {
+				t.Errorf("Decode(%s) = %q, want %q", tt.enc, got, tt.text)
+			}
+		})
+	}
+
+	// The NEC-selected IBM extensions still decode, to the characters encoded in rows FA to FC.
+	if got := e.Decode([]byte("\xed\x40\xee\xe0\xee\xef")); got != "纊髙ⅰ" {
+		t.Errorf("Decode(ed40eee0eeef) = %q, want %q", got, "纊髙ⅰ")
+	}
+
+	// Characters encoded as the double bytes of similar characters, as the cp932 codec of Python does.
+	for r, want := range map[rune]string{'〜': "8160", '−': "817c", '¬': "81ca", '€': "3f"} {
+		if got := hex.EncodeToString(e.Encode(string(r))); got != want {
+			t.Errorf("Encode(%q) = %s, want %s", r, got, want)
+		}
+		if e.CanEncode(r) != (want != "3f") {
+			t.Errorf("CanEncode(%q) = %v", r, e.CanEncode(r))
+		}
+	}
+
+	// A lead byte without a character is U+FFFD, and the byte after it is decoded by itself.
+	for in, want := range map[string]string{
+		"\x81\x20": "� ",
+		"\x85\x40": "�@",
+		"\x81\x7f": "�\x7f",
+		"a\x81":    "a�",
+	} {
+		if got := e.Decode([]byte(in)); got != want {
+			t.Errorf("Decode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEncodingReader(t *testing.T) {
+	e, err := GetEncoding(CodePageShiftJIS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A lead byte read alone waits for its trail byte.
+	r := e.NewReader(iotest.OneByteReader(bytes.NewReader([]byte("\x93\xfa\x96\x7ba\x81"))))
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "日本a�" {
+		t.Errorf("read %q, want %q", got, "日本a�")
+	}
+}
+
+func TestEncodingWriter(t *testing.T) {
+	tests := []struct {
+		cp     uint32
+		writes []string
+		want   string
+	}{
+		{CodePageOEMUS, []string{"\xe2\x94", "\x80x"}, "c478"},
+		{CodePageOEMUS, []string{"a\xe2\x94"}, "613f"},
+		{CodePageShiftJIS, []string{"\xe6\x97", "\xa5", "\xe6"}, "93fa3f"},
+		{Utf8, []string{"\xf0\x9f\x98"}, "efbfbd"},
+		{Utf8, []string{"ok"}, "6f6b"},
+	}
+
+	for _, tt := range tests {
+		e, err := GetEncoding(tt.cp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		w := e.NewWriter(&buf)
+		for _, s := range tt.writes {
+			if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+				t.Fatalf("Write(%q) = %d, %v", s, n, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := hex.EncodeToString(buf.Bytes()); got != tt.want {
+			t.Errorf("code page %d, writes %q: wrote %s, want %s", tt.cp, tt.writes, got, tt.want)
+		}
+	}
+}
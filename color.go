@@ -0,0 +1,349 @@
+package cons
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ColorKind tells how a Color is specified.
+type ColorKind uint8
+
+const (
+	ColorDefault ColorKind = iota // The default color of the console or terminal.
+	Color16                       // One of the 16 console colors, as a color nibble.
+	Color256                      // An entry of the xterm 256 color palette.
+	ColorRGB                      // A 24-bit color.
+)
+
+// ColorProfile is the set of colors an output can display. Colors outside the profile are downsampled
+// to the nearest color inside it.
+type ColorProfile uint8
+
+const (
+	ProfileNoColor   ColorProfile = iota // No color at all, only text attributes such as underline.
+	ProfileANSI16                        // The 16 colors of the console, SGR 30-37, 90-97 and 40-47, 100-107.
+	ProfileANSI256                       // The xterm 256 color palette, SGR 38;5 and 48;5.
+	ProfileTrueColor                     // 24-bit colors, SGR 38;2 and 48;2.
+)
+
+// Color is a foreground or background color: the default color, one of the 16 console colors,
+// an entry of the xterm 256 color palette or a 24-bit color. The zero value is the default color.
+type Color struct {
+	kind  ColorKind
+	value uint32 // The color nibble, the palette index or the color as 0xRRGGBB, depending on kind.
+}
+
+// The 16 console colors, named after the legacy console color names and valued as color nibbles.
+var (
+	Black       = Color{Color16, 0x0}
+	DarkBlue    = Color{Color16, 0x1}
+	DarkGreen   = Color{Color16, 0x2}
+	DarkCyan    = Color{Color16, 0x3}
+	DarkRed     = Color{Color16, 0x4}
+	DarkMagenta = Color{Color16, 0x5}
+	DarkYellow  = Color{Color16, 0x6}
+	Gray        = Color{Color16, 0x7}
+	DarkGray    = Color{Color16, 0x8}
+	Blue        = Color{Color16, 0x9}
+	Green       = Color{Color16, 0xa}
+	Cyan        = Color{Color16, 0xb}
+	Red         = Color{Color16, 0xc}
+	Magenta     = Color{Color16, 0xd}
+	Yellow      = Color{Color16, 0xe}
+	White       = Color{Color16, 0xf}
+)
+
+// NibbleColor returns the console color of a color nibble, the four bits of foreground or background
+// color in an Attributes word.
+func NibbleColor(nibble uint16) Color {
+	return Color{Color16, uint32(nibble & 0xf)}
+}
+
+// PaletteColor returns the color of an entry of the xterm 256 color palette.
+func PaletteColor(index uint8) Color {
+	return Color{Color256, uint32(index)}
+}
+
+// RGBColor returns a 24-bit color.
+func RGBColor(r, g, b uint8) Color {
+	return Color{ColorRGB, uint32(r)<<16 | uint32(g)<<8 | uint32(b)}
+}
+
+// Kind returns how the color is specified.
+func (c Color) Kind() ColorKind {
+	return c.kind
+}
+
+// IsDefault reports whether c is the default color.
+func (c Color) IsDefault() bool {
+	return c.kind == ColorDefault
+}
+
+// RGB returns the components of the color. Console colors take their values from the default console palette,
+// and the default color is reported as black.
+func (c Color) RGB() (r, g, b uint8) {
+	switch c.kind {
+	case Color16:
+		rgb := consolePaletteRGB[c.value]
+		return uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2])
+	case Color256:
+		r, g, b := xterm256RGB(int(c.value))
+		return uint8(r), uint8(g), uint8(b)
+	case ColorRGB:
+		return uint8(c.value >> 16), uint8(c.value >> 8), uint8(c.value)
+	}
+
+	return 0, 0, 0
+}
+
+// Nibble returns the color nibble of the nearest console color. The first 16 entries of the xterm palette
+// are the console colors themselves.
+func (c Color) Nibble() uint16 {
+	switch {
+	case c.kind == Color16:
+		return uint16(c.value)
+	case c.kind == Color256 && c.value < 16:
+		return uint16(consoleNibble(int(c.value&7)) | int(c.value&8))
+	}
+
+	r, g, b := c.RGB()
+	return uint16(nearestConsoleColor(int(r), int(g), int(b)))
+}
+
+// PaletteIndex returns the index of the nearest entry of the xterm 256 color palette.
+// Console colors map to the first 16 entries, the other colors to the color cube and the gray ramp.
+func (c Color) PaletteIndex() uint8 {
+	switch c.kind {
+	case Color16:
+		return uint8(ansiIndex(uint16(c.value)) | int(c.value&8))
+	case Color256:
+		return uint8(c.value)
+	}
+
+	r, g, b := c.RGB()
+	return uint8(nearestPaletteColor(int(r), int(g), int(b)))
+}
+
+// Downsample returns the nearest color that the profile can display. The default color is kept.
+func (c Color) Downsample(profile ColorProfile) Color {
+	switch {
+	case c.kind == ColorDefault:
+		return c
+	case profile == ProfileNoColor:
+		return Color{}
+	case profile == ProfileANSI16 && c.kind != Color16:
+		return NibbleColor(c.Nibble())
+	case profile == ProfileANSI256 && c.kind == ColorRGB:
+		return PaletteColor(c.PaletteIndex())
+	}
+
+	return c
+}
+
+// sgr returns the SGR parameters selecting the color in the foreground, or the background with background true.
+func (c Color) sgr(background bool) string {
+	base := 30
+	if background {
+		base = 40
+	}
+
+	switch c.kind {
+	case Color16:
+		if c.value&8 != 0 {
+			base += 60
+		}
+		return strconv.Itoa(base + ansiIndex(uint16(c.value)))
+	case Color256:
+		return strconv.Itoa(base+8) + ";5;" + strconv.Itoa(int(c.value))
+	case ColorRGB:
+		r, g, b := c.RGB()
+		return strconv.Itoa(base+8) + ";2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b))
+	}
+
+	return strconv.Itoa(base + 9)
+}
+
+// Style is the look of text: its colors and the text attributes a terminal can render.
+// The zero value is the default colors without any attribute.
+type Style struct {
+	Foreground Color
+	Background Color
+	Bold       bool // Rendered as the bright variant of the foreground color on a console.
+	Italic     bool // Not rendered on a console.
+	Underline  bool
+	Reverse    bool // Swaps the foreground and background colors.
+}
+
+// StyleFromAttributes converts a console Attributes word to a style. Light gray on black,
+// the default console colors, becomes the default colors.
+//
+// Parameters:
+//
+//	attr: The console attributes to convert.
+//
+// Returns:
+//
+//	Style: The style with the same colors, underline and reverse video.
+func StyleFromAttributes(attr uint16) Style {
+	s := Style{
+		Underline: attr&CommonLvbUnderscore != 0,
+		Reverse:   attr&CommonLvbReverseVideo != 0,
+	}
+
+	if attr&0xff != ForegroundBlue|ForegroundGreen|ForegroundRed {
+		s.Foreground, s.Background = NibbleColor(attr), NibbleColor(attr>>4)
+	}
+
+	return s
+}
+
+// Attributes converts the style to a console Attributes word, downsampling the colors to the 16 console colors.
+// The default colors become light gray on black, and Bold selects the bright variant of the foreground color.
+//
+// Returns:
+//
+//	uint16: The console attributes.
+func (s Style) Attributes() uint16 {
+	fg, bg := uint16(ForegroundBlue|ForegroundGreen|ForegroundRed), uint16(0)
+	if !s.Foreground.IsDefault() {
+		fg = s.Foreground.Nibble()
+	}
+
+	if !s.Background.IsDefault() {
+		bg = s.Background.Nibble()
+	}
+
+	attr := fg | bg<<4
+	if s.Bold {
+		attr |= ForegroundIntensity
+	}
+
+	if s.Underline {
+		attr |= CommonLvbUnderscore
+	}
+
+	if s.Reverse {
+		attr |= CommonLvbReverseVideo
+	}
+
+	return attr
+}
+
+// SGR converts the style to the SGR escape sequence that selects it on a VT terminal, downsampling the colors to
+// the profile. The sequence always starts with a reset, so it does not depend on the attributes selected before.
+//
+// Parameters:
+//
+//	profile: The colors the terminal can display.
+//
+// Returns:
+//
+//	string: The SGR escape sequence.
+func (s Style) SGR(profile ColorProfile) string {
+	var sb strings.Builder
+
+	sb.WriteString("\x1b[0")
+	for _, attr := range []struct {
+		on    bool
+		param string
+	}{{s.Bold, ";1"}, {s.Italic, ";3"}, {s.Underline, ";4"}, {s.Reverse, ";7"}} {
+		if attr.on {
+			sb.WriteString(attr.param)
+		}
+	}
+
+	if fg := s.Foreground.Downsample(profile); !fg.IsDefault() {
+		sb.WriteString(";" + fg.sgr(false))
+	}
+
+	if bg := s.Background.Downsample(profile); !bg.IsDefault() {
+		sb.WriteString(";" + bg.sgr(true))
+	}
+
+	sb.WriteString("m")
+	return sb.String()
+}
+
+// ansiIndex converts the blue, green and red bits of a console color nibble to the ANSI color index,
+// which orders the same bits as red, green and blue.
+func ansiIndex(nibble uint16) int {
+	return int(nibble&ForegroundRed>>2 | nibble&ForegroundGreen | nibble&ForegroundBlue<<2)
+}
+
+// consoleNibble converts an ANSI color index to the console color nibble, the inverse of ansiIndex.
+func consoleNibble(index int) int {
+	return ansiIndex(uint16(index))
+}
+
+// xterm256RGB returns the color of an entry of the xterm 256 color palette.
+func xterm256RGB(n int) (int, int, int) {
+	switch {
+	case n < 16:
+		rgb := consolePaletteRGB[consoleNibble(n&7)|n&8]
+		return rgb[0], rgb[1], rgb[2]
+	case n < 232:
+		n -= 16
+		return cubeLevel(n / 36), cubeLevel(n / 6 % 6), cubeLevel(n % 6)
+	}
+
+	v := 8 + (n-232)*10
+	return v, v, v
+}
+
+// cubeLevel returns the intensity of a step of the 6x6x6 color cube of the xterm palette.
+func cubeLevel(v int) int {
+	if v == 0 {
+		return 0
+	}
+
+	return 55 + v*40
+}
+
+// consolePaletteRGB is the default palette of the console, indexed by color nibble.
+var consolePaletteRGB = [16][3]int{
+	{12, 12, 12}, {0, 55, 218}, {19, 161, 14}, {58, 150, 221},
+	{197, 15, 31}, {136, 23, 152}, {193, 156, 0}, {204, 204, 204},
+	{118, 118, 118}, {59, 120, 255}, {22, 198, 12}, {97, 214, 214},
+	{231, 72, 86}, {180, 0, 158}, {249, 241, 165}, {242, 242, 242},
+}
+
+// colorDistance is a cheap perceptual distance between two colors, weighting green the most.
+func colorDistance(r1, g1, b1, r2, g2, b2 int) int {
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	return 2*dr*dr + 4*dg*dg + 3*db*db
+}
+
+// nearestConsoleColor returns the color nibble of the palette entry closest to the given color.
+func nearestConsoleColor(r, g, b int) int {
+	best, dist := 0, -1
+	for i, rgb := range consolePaletteRGB {
+		if d := colorDistance(r, g, b, rgb[0], rgb[1], rgb[2]); dist < 0 || d < dist {
+			best, dist = i, d
+		}
+	}
+
+	return best
+}
+
+// nearestPaletteColor returns the index of the entry of the xterm color cube or gray ramp closest to the
+// given color. The first 16 entries are left out, as terminals let users redefine them.
+func nearestPaletteColor(r, g, b int) int {
+	step := func(v int) int {
+		if v < 48 {
+			return 0
+		}
+		return min((v-35)/40, 5)
+	}
+
+	cr, cg, cb := step(r), step(g), step(b)
+	cube := 16 + 36*cr + 6*cg + cb
+	cubeDist := colorDistance(r, g, b, cubeLevel(cr), cubeLevel(cg), cubeLevel(cb))
+
+	gray := min(max((r+g+b)/3-3, 0)/10, 23)
+	v := 8 + gray*10
+	if colorDistance(r, g, b, v, v, v) < cubeDist {
+		return 232 + gray
+	}
+
+	return cube
+}
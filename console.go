@@ -0,0 +1,89 @@
+package cons
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// ErrInvalidParameter is returned by the portable backends when a coordinate, rectangle or size is out of range,
+// which is the condition kernel32 reports as ERROR_INVALID_PARAMETER.
+var ErrInvalidParameter = errors.New("cons: invalid parameter")
+
+// ModeController is implemented by anything that carries a console mode,
+// which is the case for both the input and the output side of a console.
+type ModeController interface {
+	// GetMode retrieves the current console mode.
+	GetMode() (DWord, error)
+	// SetMode replaces the current console mode.
+	SetMode(mode DWord) error
+}
+
+// InputSource is the input side of a console, the counterpart of a standard input handle.
+type InputSource interface {
+	ModeController
+
+	// ReadInput reads up to length input records into buffer and stores the number read in counter.
+	ReadInput(buffer unsafe.Pointer, length uint32, counter *uint32) error
+}
+
+// OutputTarget is the output side of a console, the counterpart of a standard output handle.
+type OutputTarget interface {
+	ModeController
+
+	// GetCursorInfo retrieves the size and visibility of the cursor.
+	GetCursorInfo() (CursorInfo, error)
+	// SetCursorInfo sets the size and visibility of the cursor.
+	SetCursorInfo(p *CursorInfo) error
+	// GetScreenBufferInfo retrieves information about the screen buffer.
+	GetScreenBufferInfo(p *ScreenBufferInfo) error
+	// SetCursorPosition moves the cursor to the given position in the screen buffer.
+	SetCursorPosition(newpos Coord) error
+	// FillCharacter writes char to length consecutive cells starting at wcoord. A character outside the
+	// Basic Multilingual Plane is repeated as its surrogate pair, two cells at a time.
+	FillCharacter(char rune, length int, wcoord Coord) (uint16, error)
+	// FillAttribute writes attribute to length consecutive cells starting at wcoord.
+	FillAttribute(attribute uint16, length int, wcoord Coord) (uint16, error)
+	// WriteAttribute writes attribute to length consecutive cells starting at wcoord.
+	WriteAttribute(attribute uint16, length uint32, wcoord Coord) (uint16, error)
+	// WriteCharacter writes char to length consecutive cells starting at wcoord.
+	WriteCharacter(char uint16, length uint32, wcoord Coord) (uint16, error)
+	// WriteOutput writes the cells of buffer, a rectangle of bufsize cells, from bufcoord to region, and stores
+	// in region the rectangle written once clipped to the screen buffer and to buffer.
+	WriteOutput(buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error
+	// ReadOutput reads the cells of region into buffer, a rectangle of bufsize cells, from bufcoord, and stores
+	// in region the rectangle read once clipped to the screen buffer and to buffer.
+	ReadOutput(buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error
+	// ReadOutputCharacter reads the characters of len(buffer) consecutive cells starting at wcoord.
+	ReadOutputCharacter(buffer []uint16, wcoord Coord) (uint32, error)
+	// ReadOutputAttribute reads the attributes of len(buffer) consecutive cells starting at wcoord.
+	ReadOutputAttribute(buffer []uint16, wcoord Coord) (uint32, error)
+	// SetTextAttribute sets the attributes of the characters written afterwards.
+	SetTextAttribute(attribute uint16) error
+	// ScrollScreenBuffer moves the cells of scrollrect to dest, clipped to cliprect, and fills the vacated cells with fill.
+	ScrollScreenBuffer(scrollrect, cliprect *SmallRect, dest Coord, fill CharInfo) error
+}
+
+// Console groups the input and output sides of a console together with the
+// settings that are shared by the whole console, such as code pages and the window title.
+//
+// Code written against Console instead of raw handles can be compiled and tested
+// with any backend, the kernel32 one returned by NewConsole being only one of them.
+type Console interface {
+	// Input returns the input side of the console.
+	Input() InputSource
+	// Output returns the output side of the console.
+	Output() OutputTarget
+
+	// GetInputCodePage retrieves the input code page of the console.
+	GetInputCodePage() (uint32, error)
+	// SetInputCodePage sets the input code page of the console.
+	SetInputCodePage(cp uint32) error
+	// GetOutputCodePage retrieves the output code page of the console.
+	GetOutputCodePage() (uint32, error)
+	// SetOutputCodePage sets the output code page of the console.
+	SetOutputCodePage(cp uint32) error
+	// GetWindowTitle retrieves the title of the console window.
+	GetWindowTitle() (string, error)
+	// SetWindowTitle sets the title of the console window.
+	SetWindowTitle(title string) error
+}
@@ -0,0 +1,205 @@
+package cons
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// ptyOutput collects what is written to the terminal side of a pseudo-terminal.
+type ptyOutput struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// wait waits until the output holds s, and returns the output.
+func (o *ptyOutput) wait(t *testing.T, s string) string {
+	t.Helper()
+
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		o.mu.Lock()
+		out := o.buf.String()
+		o.mu.Unlock()
+
+		if strings.Contains(out, s) {
+			return out
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("output %q does not hold %q", out, s)
+		}
+	}
+}
+
+// withPTY makes the standard output of the process a pseudo-terminal of the given size for the duration
+// of a test, with the state of the terminal backend reset, and returns what the terminal receives.
+func withPTY(t *testing.T, cols, rows uint16) *ptyOutput {
+	t.Helper()
+
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip("no pseudo-terminal:", err)
+	}
+
+	var (
+		unlock int32
+		num    uint32
+	)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		master.Close()
+		t.Skip("no pseudo-terminal:", errno)
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&num))); errno != 0 {
+		master.Close()
+		t.Skip("no pseudo-terminal:", errno)
+	}
+
+	slave, err := os.OpenFile("/dev/pts/"+strconv.Itoa(int(num)), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		t.Skip("no pseudo-terminal:", err)
+	}
+
+	ws := winsize{Row: rows, Col: cols}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, slave.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws))); errno != 0 {
+		t.Fatal(errno)
+	}
+
+	saved, err := syscall.Dup(syscall.Stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Dup2(int(slave.Fd()), syscall.Stdout); err != nil {
+		t.Fatal(err)
+	}
+
+	resetTTYs := func() {
+		ttyMu.Lock()
+		ttys, activeOutput = map[Handle]*ttyState{}, Handle(syscall.Stdout)
+		ttyMu.Unlock()
+	}
+	resetTTYs()
+
+	out := &ptyOutput{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		buf := make([]byte, 4096)
+		for {
+			n, err := master.Read(buf)
+			out.mu.Lock()
+			out.buf.Write(buf[:n])
+			out.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	t.Cleanup(func() {
+		syscall.Dup2(saved, syscall.Stdout)
+		syscall.Close(saved)
+		slave.Close()
+		master.Close()
+		<-done
+		resetTTYs()
+	})
+
+	return out
+}
+
+func TestHandleRecorderShadow(t *testing.T) {
+	out := withPTY(t, 20, 5)
+	h := Handle(syscall.Stdout)
+
+	var cast bytes.Buffer
+	rec, err := NewHandleRecorder(&cast, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Write([]byte("hello \x1b[31mworld\x1b[0m\r\nnext")); err != nil {
+		t.Fatal(err)
+	}
+	out.wait(t, "next")
+
+	c, err := CaptureRegion(h, SmallRect{Right: 19, Bottom: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := c.Text(); text != "hello world\nnext\n" {
+		t.Errorf("cells = %q, want the recorded text", text)
+	}
+	if attr := c.Row(0)[6].Attributes; attr&0xff != ForegroundRed {
+		t.Errorf("attributes of w = %#x, want red", attr)
+	}
+
+	var scrbufinfo ScreenBufferInfo
+	if err := GetScreenBufferInfo(h, &scrbufinfo); err != nil {
+		t.Fatal(err)
+	}
+	if scrbufinfo.CursorPosition != (Coord{X: 4, Y: 1}) {
+		t.Errorf("cursor = %+v, want after the recorded text", scrbufinfo.CursorPosition)
+	}
+
+	cast2, err := ReadCast(&cast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cast2.Header.Width != 20 || cast2.Header.Height != 5 || len(cast2.Events) != 1 {
+		t.Errorf("recording = %+v, want a 20x5 header and one event", cast2)
+	}
+}
+
+func TestScreenBuffer(t *testing.T) {
+	out := withPTY(t, 20, 5)
+	stdout := Handle(syscall.Stdout)
+	attr := uint16(ForegroundBlue | ForegroundGreen | ForegroundRed)
+
+	if _, err := WriteString(stdout, "before", attr, Coord{}); err != nil {
+		t.Fatal(err)
+	}
+	out.wait(t, "before")
+
+	h, err := CreateScreenBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteString(h, "alternate", attr, Coord{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetActiveScreenBuffer(h); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.wait(t, "alternate"); !strings.Contains(got, "\x1b[?1049h") {
+		t.Errorf("output %q does not switch to the alternate screen", got)
+	}
+
+	// Written while the alternate screen is displayed, the text reaches the main screen once it is back.
+	if _, err := WriteString(stdout, "meanwhile", attr, Coord{Y: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CloseHandle(h); err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.wait(t, "meanwhile")
+	back := strings.Index(got, "\x1b[?1049l")
+	if back < 0 || strings.Index(got, "meanwhile") < back {
+		t.Errorf("output %q does not draw the text after switching back to the main screen", got)
+	}
+	if !strings.Contains(got[back:], "before") {
+		t.Errorf("output %q does not redraw the main screen", got)
+	}
+
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+	if _, ok := ttys[h]; ok || activeOutput != stdout {
+		t.Errorf("state after CloseHandle: buffer kept %v, active output %d", ok, activeOutput)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows && !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package cons
+
+import "os"
+
+const invalidHandle = ^Handle(0)
+
+// NewTerminalStyledWriter creates a writer of plain text, as consoles are not supported on this platform.
+func NewTerminalStyledWriter(f *os.File) *StyledWriter {
+	return NewStyledWriter(f, ProfileNoColor)
+}
+
+// systemCodePages returns the ANSI and OEM code pages of a United States Windows system,
+// as there are no such code pages on this platform.
+func systemCodePages() (acp, oemcp uint32) {
+	return CodePageANSI1252, CodePageOEMUS
+}
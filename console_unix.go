@@ -0,0 +1,1172 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package cons
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+)
+
+const invalidHandle = ^Handle(0)
+
+// ttyState is what the Unix backend remembers about a terminal handle, since a terminal has
+// no notion of console modes, cursor size or cell attributes that could be queried back.
+type ttyState struct {
+	input   bool            // Indicates whether the handle is the input side of the terminal.
+	mode    DWord           // The mode flags that have no termios counterpart.
+	curinfo CursorInfo      // The last cursor information set on the handle.
+	shadow  *VirtualConsole // The cells written to the terminal through this package.
+	decoder *VTInputDecoder // The decoder of the key sequences read from the terminal.
+	pending []byte          // Input bytes that do not form a character yet, in EnableVirtualTerminalInput mode.
+	events  []Event         // Events decoded from the terminal but not yet returned.
+	hidden  bool            // Indicates whether the handle is a screen buffer that is not displayed.
+	stale   bool            // Indicates whether output was held back while hidden, so the terminal lacks it.
+	vt      *VTWriter       // The writer rendering the bytes of writeHandle to the shadow buffer.
+}
+
+var (
+	ttyMu          sync.Mutex
+	ttys           = map[Handle]*ttyState{}
+	inputCodePage  = uint32(Utf8)
+	outputCodePage = uint32(Utf8)
+	windowTitle    string                   // The last title set with SetWindowTitle, as a terminal cannot be asked for its title.
+	activeOutput   = Handle(syscall.Stdout) // The screen buffer displayed, see SetActiveScreenBuffer.
+	winchOnce      sync.Once
+)
+
+// windowInputPoll is how often a read waiting for input checks for the resize records queued on SIGWINCH,
+// in EnableWindowInput mode.
+const windowInputPoll = 100 * time.Millisecond
+
+// ttyOf returns the state of the handle, creating it on first use.
+// Descriptor 0 is the input side of the terminal, every other descriptor is an output side.
+func ttyOf(h Handle) *ttyState {
+	st, ok := ttys[h]
+	if !ok {
+		st = &ttyState{
+			input:   h == Handle(syscall.Stdin),
+			curinfo: CursorInfo{Size: 25, Visible: true},
+			decoder: NewVTInputDecoder(),
+		}
+		if !st.input {
+			st.mode = EnableProcessedOutput | EnableWrapAtEolOutput | EnableVirtualTerminalProcessing
+		}
+		ttys[h] = st
+	}
+
+	return st
+}
+
+func tcgetattr(h Handle) (syscall.Termios, error) {
+	var termios syscall.Termios
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(h), ioctlGetTermios, touintptr(&termios)); err != 0 {
+		return termios, err
+	}
+
+	return termios, nil
+}
+
+func tcsetattr(h Handle, termios *syscall.Termios) error {
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(h), ioctlSetTermios, touintptr(termios)); err != 0 {
+		return err
+	}
+
+	return nil
+}
+
+// setFlag sets or clears flag in a termios flag word, whose width differs between systems.
+func setFlag[T ~uint32 | ~uint64](word *T, flag T, on bool) {
+	if on {
+		*word |= flag
+	} else {
+		*word &^= flag
+	}
+}
+
+// winsize mirrors struct winsize of <sys/ioctl.h>.
+type winsize struct {
+	Row    uint16
+	Col    uint16
+	Xpixel uint16
+	Ypixel uint16
+}
+
+// termSize returns the number of columns and rows of the terminal, 80x24 if it reports none.
+func termSize(h Handle) (Coord, error) {
+	var ws winsize
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(h), syscall.TIOCGWINSZ, touintptr(&ws)); err != 0 {
+		return Coord{}, err
+	}
+
+	if ws.Col == 0 || ws.Row == 0 {
+		ws.Col, ws.Row = 80, 24
+	}
+
+	return Coord{X: int16(ws.Col), Y: int16(ws.Row)}, nil
+}
+
+// screenOf returns the shadow buffer of an output handle, resized to the current size of the terminal.
+func screenOf(h Handle) (*VirtualConsole, error) {
+	size, err := termSize(h)
+	if err != nil {
+		return nil, err
+	}
+
+	st := ttyOf(h)
+	if st.shadow == nil {
+		st.shadow = NewVirtualConsole(size.X, size.Y)
+	} else if err := st.shadow.Resize(size); err != nil {
+		return nil, err
+	}
+
+	return st.shadow, nil
+}
+
+// writeTTY writes the whole string to the handle. Nothing is written for a screen buffer that is not displayed,
+// whose cells are kept in its shadow buffer until it is.
+func writeTTY(h Handle, s string) error {
+	if st, ok := ttys[h]; ok && st.hidden {
+		st.stale = true
+		return nil
+	}
+
+	for b := []byte(s); len(b) > 0; {
+		n, err := syscall.Write(int(h), b)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// writeHandle writes p to the terminal of the handle, or nowhere while it is a hidden screen buffer, and renders
+// its text and VT sequences to the shadow buffer, so that the cells read back and redrawn include it.
+func writeHandle(h Handle, p []byte) (int, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(h)
+	if err != nil {
+		return 0, err
+	}
+
+	st := ttyOf(h)
+	if st.vt == nil {
+		if st.vt, err = NewVTWriter(shadow); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := writeTTY(h, string(p)); err != nil {
+		return 0, err
+	}
+
+	return st.vt.Write(p)
+}
+
+// redraw repaints the cells of the rectangle from the shadow buffer and puts the cursor and the text attributes
+// back as they were.
+func redraw(h Handle, shadow *VirtualConsole, rect SmallRect) error {
+	var (
+		sb         strings.Builder
+		scrbufinfo ScreenBufferInfo
+	)
+
+	if err := shadow.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return err
+	}
+
+	rect, ok := intersectRect(rect, SmallRect{Right: scrbufinfo.Size.X - 1, Bottom: scrbufinfo.Size.Y - 1})
+	if !ok {
+		return nil
+	}
+
+	row := make([]CharInfo, scrbufinfo.Size.X)
+	for y := rect.Top; y <= rect.Bottom; y++ {
+		for x := range row {
+			row[x] = shadow.Cell(int16(x), y)
+		}
+
+		from, to := cellSpan(row, int(rect.Left), int(rect.Right))
+		sb.WriteString(cup(Coord{X: int16(from), Y: y}))
+		appendVTCells(&sb, row[from:to+1])
+	}
+
+	// The attributes of SetTextAttribute stay selected for the text written to the terminal afterwards.
+	sb.WriteString(attributesSGR(scrbufinfo.Attributes) + cup(scrbufinfo.CursorPosition))
+	return writeTTY(h, sb.String())
+}
+
+// runRect returns the rectangle covering length cells starting at wcoord in a buffer of the given width.
+func runRect(wcoord Coord, length int, width int16) SmallRect {
+	end := int(wcoord.Y)*int(width) + int(wcoord.X) + max(length, 1) - 1
+	if int(wcoord.Y) == end/int(width) {
+		return SmallRect{Left: wcoord.X, Top: wcoord.Y, Right: int16(end % int(width)), Bottom: wcoord.Y}
+	}
+
+	return SmallRect{Left: 0, Top: wcoord.Y, Right: width - 1, Bottom: int16(end / int(width))}
+}
+
+// GetStdHandle retrieves the descriptor of the standard input, output or error stream.
+//
+// Parameters:
+//
+//	flag: A flag indicating whether to get the standard input, output, or error handle.
+//
+// Returns:
+//
+//	Handle: The descriptor of the requested standard stream.
+//	error: If the flag names a standard stream, it returns nil. Otherwise, it returns an error.
+func GetStdHandle(flag uint32) (Handle, error) {
+	switch flag {
+	case StdInputHandle:
+		return Handle(syscall.Stdin), nil
+	case StdOutputHandle:
+		return Handle(syscall.Stdout), nil
+	case StdErrorHandle:
+		return Handle(syscall.Stderr), nil
+	}
+
+	return invalidHandle, syscall.EINVAL
+}
+
+// GetMode retrieves the console mode of a terminal handle. On the input side EnableLineInput,
+// EnableEchoInput and EnableProcessedInput reflect ICANON, ECHO and ISIG of the terminal.
+//
+// Parameters:
+//
+//	hStdout: The handle whose console mode is retrieved.
+//
+// Returns:
+//
+//	DWord: The current console mode.
+//	error: If the handle is a terminal, it returns nil. Otherwise, it returns an error.
+func GetMode(hStdout Handle) (DWord, error) {
+	termios, err := tcgetattr(hStdout)
+	if err != nil {
+		return 0, err
+	}
+
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	st := ttyOf(hStdout)
+	if !st.input {
+		return st.mode, nil
+	}
+
+	mode := st.mode &^ (EnableLineInput | EnableEchoInput | EnableProcessedInput)
+	if termios.Lflag&syscall.ICANON != 0 {
+		mode |= EnableLineInput
+	}
+
+	if termios.Lflag&syscall.ECHO != 0 {
+		mode |= EnableEchoInput
+	}
+
+	if termios.Lflag&syscall.ISIG != 0 {
+		mode |= EnableProcessedInput
+	}
+
+	return mode, nil
+}
+
+// SetMode sets the console mode of a terminal handle. On the input side clearing EnableLineInput,
+// EnableEchoInput or EnableProcessedInput switches the terminal to cbreak or raw mode, and setting
+// EnableWindowInput makes every SIGWINCH queue a WindowBufferSizeRecord with the new size of the terminal,
+// as a console reports a change of the size of its screen buffer.
+//
+// Parameters:
+//
+//	hStdout: The handle whose console mode is set.
+//	mode: The new console mode.
+//
+// Returns:
+//
+//	error: If the function successfully sets the console mode, it returns nil. Otherwise, it returns an error.
+func SetMode(hStdout Handle, mode DWord) error {
+	termios, err := tcgetattr(hStdout)
+	if err != nil {
+		return err
+	}
+
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	st := ttyOf(hStdout)
+	st.mode = mode
+	if !st.input {
+		return nil
+	}
+
+	if mode.IsEnableMode(EnableWindowInput) {
+		winchOnce.Do(watchWinch)
+	}
+
+	setFlag(&termios.Lflag, syscall.ICANON, mode.IsEnableMode(EnableLineInput))
+	setFlag(&termios.Lflag, syscall.ECHO, mode.IsEnableMode(EnableEchoInput))
+	setFlag(&termios.Lflag, syscall.ISIG, mode.IsEnableMode(EnableProcessedInput))
+
+	return tcsetattr(hStdout, &termios)
+}
+
+// watchWinch queues a WindowBufferSizeRecord on the input handles in EnableWindowInput mode
+// whenever the terminal is resized.
+func watchWinch() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGWINCH)
+
+	go func() {
+		for range ch {
+			ttyMu.Lock()
+			for h, st := range ttys {
+				if !st.input || !st.mode.IsEnableMode(EnableWindowInput) {
+					continue
+				}
+
+				if size, err := termSize(h); err == nil {
+					st.events = append(st.events, WindowBufferSizeRecord{Size: size})
+				}
+			}
+			ttyMu.Unlock()
+		}
+	}()
+}
+
+// GetCursorInfo retrieves the cursor information last set on the terminal handle.
+//
+// Parameters:
+//
+//	hStdout: The handle for which cursor information is to be obtained.
+//
+// Returns:
+//
+//	CursorInfo: The cursor information, including size and visibility.
+//	error: It always returns nil.
+func GetCursorInfo(hStdout Handle) (CursorInfo, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	return ttyOf(hStdout).curinfo, nil
+}
+
+// SetCursorInfo shows or hides the cursor of the terminal and, when the size changes,
+// selects an underline cursor for sizes below 50 and a block cursor otherwise.
+//
+// Parameters:
+//
+//	hStdout: The handle for which cursor information is to be set.
+//	p: A pointer to a CursorInfo structure containing cursor size and visibility settings.
+//
+// Returns:
+//
+//	error: If the function successfully sets the cursor information, it returns nil. Otherwise, it returns an error.
+func SetCursorInfo(hStdout Handle, p *CursorInfo) error {
+	if p.Size < 1 || p.Size > 100 {
+		return ErrInvalidParameter
+	}
+
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	st := ttyOf(hStdout)
+	seq := "\x1b[?25l"
+	if p.Visible {
+		seq = "\x1b[?25h"
+	}
+
+	if p.Size != st.curinfo.Size {
+		if p.Size < 50 {
+			seq += "\x1b[4 q"
+		} else {
+			seq += "\x1b[2 q"
+		}
+	}
+
+	if err := writeTTY(hStdout, seq); err != nil {
+		return err
+	}
+
+	st.curinfo = *p
+	return nil
+}
+
+// GetInputCodePage retrieves the input code page recorded for the terminal, Utf8 unless changed.
+//
+// Returns:
+//
+//	uint32: The current code page.
+//	error: It always returns nil.
+func GetInputCodePage() (uint32, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	return inputCodePage, nil
+}
+
+// SetInputCodePage records the input code page of the terminal.
+//
+// Parameters:
+//
+//	cp: The code page to set as the input code page.
+//
+// Returns:
+//
+//	error: It always returns nil.
+func SetInputCodePage(cp uint32) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	inputCodePage = cp
+	return nil
+}
+
+// GetOutputCodePage retrieves the output code page recorded for the terminal, Utf8 unless changed.
+//
+// Returns:
+//
+//	uint32: The current output code page.
+//	error: It always returns nil.
+func GetOutputCodePage() (uint32, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	return outputCodePage, nil
+}
+
+// SetOutputCodePage records the output code page of the terminal.
+//
+// Parameters:
+//
+//	cp: The code page to set as the output code page.
+//
+// Returns:
+//
+//	error: It always returns nil.
+func SetOutputCodePage(cp uint32) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	outputCodePage = cp
+	return nil
+}
+
+// GetScreenBufferInfo retrieves the size of the terminal together with the cursor position and
+// attributes tracked by this package. The window always covers the whole terminal.
+//
+// Parameters:
+//
+//	hStdout: The handle for which screen buffer information is to be obtained.
+//	p: A pointer to a ScreenBufferInfo structure where the information will be stored.
+//
+// Returns:
+//
+//	error: If the handle is a terminal, it returns nil. Otherwise, it returns an error.
+func GetScreenBufferInfo(hStdout Handle, p *ScreenBufferInfo) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return err
+	}
+
+	return shadow.GetScreenBufferInfo(p)
+}
+
+// ReadInput reads key events from the terminal. The terminal is put in cbreak mode for the duration
+// of the read, as ReadConsoleInput ignores line input and echo. The key sequences sent by the terminal,
+// such as ESC [ A for the up arrow, are decoded to the records kernel32 reports for the same keys,
+// unless EnableVirtualTerminalInput is set, in which case every character read becomes a key down
+// record as it does on a console in that mode. Characters outside the Basic Multilingual Plane
+// become two records carrying the surrogate pair.
+//
+// Parameters:
+//
+//	hStdin: The handle from which input records will be read.
+//	buffer: A pointer to an array of INPUT_RECORD structures where input records will be stored.
+//	length: The maximum number of input records to read.
+//	counter: A pointer to a counter that will receive the actual number of input records read.
+//
+// Returns:
+//
+//	error: If the function successfully reads the input records, it returns nil. Otherwise, it returns an error.
+func ReadInput(hStdin Handle, buffer unsafe.Pointer, length uint32, counter *uint32) error {
+	*counter = 0
+	if length == 0 {
+		return nil
+	}
+
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	st := ttyOf(hStdin)
+	for len(st.events) == 0 {
+		timeout := time.Duration(-1)
+		if st.decoder.Pending() {
+			timeout = EscapeTimeout
+		} else if st.mode.IsEnableMode(EnableWindowInput) {
+			timeout = windowInputPoll
+		}
+
+		// The lock is released while waiting, for SIGWINCH to queue its records.
+		ttyMu.Unlock()
+		b, err := readRaw(hStdin, timeout)
+		ttyMu.Lock()
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			st.queue(st.decoder.Flush())
+			continue
+		}
+
+		if st.mode&EnableVirtualTerminalInput != 0 {
+			st.pending = append(st.pending, b...)
+			for len(st.pending) > 0 && utf8.FullRune(st.pending) {
+				r, size := utf8.DecodeRune(st.pending)
+				st.events = append(st.events, keyEvents(runeKeyEvents(r))...)
+				st.pending = st.pending[size:]
+			}
+			continue
+		}
+		st.queue(st.decoder.Feed(b))
+	}
+
+	records := unsafe.Slice((*byte)(buffer), int(length)*InputRecordSize)
+	for *counter < length && len(st.events) > 0 {
+		record, err := EncodeInputRecord(st.events[0])
+		if err != nil {
+			return err
+		}
+
+		copy(records[int(*counter)*InputRecordSize:], record)
+		st.events = st.events[1:]
+		*counter++
+	}
+
+	return nil
+}
+
+// queue appends decoded events to the events to return, expanding pasted text to the keys it is made of.
+func (st *ttyState) queue(events []Event) {
+	for _, ev := range events {
+		if paste, ok := ev.(PasteEvent); ok {
+			for _, key := range paste.KeyEvents() {
+				st.events = append(st.events, key)
+			}
+			continue
+		}
+
+		st.events = append(st.events, ev)
+	}
+}
+
+// WaitForInput waits until the terminal has input to read or the timeout elapses.
+//
+// Parameters:
+//
+//	hStdin: The handle to wait on.
+//	timeout: The maximum time to wait; a negative timeout waits forever.
+//
+// Returns:
+//
+//	bool: True if input is available, false if the timeout elapsed.
+//	error: If the function successfully waits, it returns nil. Otherwise, it returns an error.
+func WaitForInput(hStdin Handle, timeout time.Duration) (bool, error) {
+	for {
+		ttyMu.Lock()
+		st := ttyOf(hStdin)
+		// Held back bytes are returned by ReadInput at the latest after EscapeTimeout.
+		ready := len(st.events) > 0 || st.decoder.Pending()
+		window := st.mode.IsEnableMode(EnableWindowInput)
+		ttyMu.Unlock()
+
+		if ready {
+			return true, nil
+		}
+
+		// In EnableWindowInput mode, the wait is cut in slices to notice the records queued on SIGWINCH.
+		wait := timeout
+		if window && (wait < 0 || wait > windowInputPoll) {
+			wait = windowInputPoll
+		}
+
+		var tv *syscall.Timeval
+		if wait >= 0 {
+			t := syscall.NsecToTimeval(wait.Nanoseconds())
+			tv = &t
+		}
+
+		ready, err := waitReadable(int(hStdin), tv)
+		switch {
+		case err == syscall.EINTR:
+			continue
+		case err != nil || ready || wait == timeout:
+			return ready, err
+		case timeout >= 0:
+			timeout -= wait
+		}
+	}
+}
+
+// fdSet adds fd to the words of an fd_set, whose word type differs between systems.
+func fdSet[T ~int32 | ~int64 | ~uint32 | ~uint64](bits []T, fd int) {
+	size := int(unsafe.Sizeof(bits[0])) * 8
+	bits[fd/size] |= 1 << (fd % size)
+}
+
+// fdIsSet checks if fd is in the words of an fd_set.
+func fdIsSet[T ~int32 | ~int64 | ~uint32 | ~uint64](bits []T, fd int) bool {
+	size := int(unsafe.Sizeof(bits[0])) * 8
+	return bits[fd/size]&(1<<(fd%size)) != 0
+}
+
+// readRaw performs a single read from the terminal in cbreak mode, keeping ISIG as configured.
+// With a non-negative timeout, it returns nil if no input arrives in time.
+func readRaw(hStdin Handle, timeout time.Duration) ([]byte, error) {
+	termios, err := tcgetattr(hStdin)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := termios
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN], raw.Cc[syscall.VTIME] = 1, 0
+	if err := tcsetattr(hStdin, &raw); err != nil {
+		return nil, err
+	}
+	defer tcsetattr(hStdin, &termios)
+
+	if timeout >= 0 {
+		tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+		for {
+			ready, err := waitReadable(int(hStdin), &tv)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !ready {
+				return nil, nil
+			}
+			break
+		}
+	}
+
+	buf := make([]byte, 64)
+	for {
+		n, err := syscall.Read(int(hStdin), buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, syscall.EIO
+		}
+
+		return buf[:n], nil
+	}
+}
+
+// SetCursorPosition moves the cursor of the terminal.
+//
+// Parameters:
+//
+//	hStdout: The handle for which the cursor position is to be set.
+//	newpos: The new cursor position specified as a Coord structure.
+//
+// Returns:
+//
+//	error: If the position lies inside the terminal, it returns nil. Otherwise, it returns an error.
+func SetCursorPosition(hStdout Handle, newpos Coord) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return err
+	}
+
+	if err := shadow.SetCursorPosition(newpos); err != nil {
+		return err
+	}
+
+	return writeTTY(hStdout, cup(newpos))
+}
+
+// GetWindowTitle retrieves the title last set with SetWindowTitle, since terminals do not reliably
+// report their title. It is empty until the first SetWindowTitle.
+//
+// Returns:
+//
+//	string: The title of the terminal window.
+//	error: Always nil.
+func GetWindowTitle() (string, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	return windowTitle, nil
+}
+
+// SetTextAttribute sets the attributes of the characters written to the terminal, selecting the
+// corresponding colors with an SGR sequence.
+//
+// Parameters:
+//
+//	hStdout: The handle whose text attributes are to be set.
+//	attribute: The foreground and background colors and other attributes of the characters.
+//
+// Returns:
+//
+//	error: If the function successfully sets the text attributes, it returns nil. Otherwise, it returns an error.
+func SetTextAttribute(hStdout Handle, attribute uint16) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return err
+	}
+
+	if err := shadow.SetTextAttribute(attribute); err != nil {
+		return err
+	}
+
+	return writeTTY(hStdout, attributesSGR(attribute))
+}
+
+// SetWindowTitle sets the title of the terminal window with an OSC 0 sequence on the standard output.
+//
+// Parameters:
+//
+//	title: The string to set as the new title of the terminal window.
+//
+// Returns:
+//
+//	error: If the function successfully sets the title, it returns nil. Otherwise, it returns an error.
+func SetWindowTitle(title string) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	windowTitle = title
+
+	return writeTTY(activeOutput, "\x1b]0;"+strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, title)+"\x07")
+}
+
+// update applies op to the shadow buffer of the handle and repaints the cells it touched.
+func update(hStdout Handle, length int, wcoord Coord, op func(shadow *VirtualConsole) (uint16, error)) (uint16, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return 0, err
+	}
+
+	counter, err := op(shadow)
+	if err != nil || counter == 0 {
+		return counter, err
+	}
+
+	var scrbufinfo ScreenBufferInfo
+	if err := shadow.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return counter, err
+	}
+
+	return counter, redraw(hStdout, shadow, runRect(wcoord, min(length, int(counter)), scrbufinfo.Size.X))
+}
+
+// FillCharacter writes char to length consecutive cells of the terminal starting at wcoord, keeping their attributes.
+// A character outside the Basic Multilingual Plane is repeated as its surrogate pair, two cells at a time.
+//
+// Parameters:
+//
+//	hStdout: The handle where the characters will be filled.
+//	char: The character to be used for filling.
+//	length: The number of character cells to be filled.
+//	wcoord: The starting coordinates for filling.
+//
+// Returns:
+//
+//	uint16: The number of character cells successfully filled.
+//	error: If the function successfully fills the character cells, it returns nil. Otherwise, it returns an error.
+func FillCharacter(hStdout Handle, char rune, length int, wcoord Coord) (uint16, error) {
+	return update(hStdout, length, wcoord, func(shadow *VirtualConsole) (uint16, error) {
+		return shadow.FillCharacter(char, length, wcoord)
+	})
+}
+
+// FillAttribute gives length consecutive cells of the terminal starting at wcoord the attribute,
+// repainting their characters with the matching SGR sequence.
+//
+// Parameters:
+//
+//	hStdout: The handle where the attributes will be filled.
+//	attribute: The attribute to be used for filling.
+//	length: The number of character cells to be filled.
+//	wcoord: The starting coordinates for filling.
+//
+// Returns:
+//
+//	uint16: The number of character cells with attributes successfully filled.
+//	error: If the function successfully fills the character cells with attributes, it returns nil. Otherwise, it returns an error.
+func FillAttribute(hStdout Handle, attribute uint16, length int, wcoord Coord) (uint16, error) {
+	return update(hStdout, length, wcoord, func(shadow *VirtualConsole) (uint16, error) {
+		return shadow.FillAttribute(attribute, length, wcoord)
+	})
+}
+
+// WriteAttribute gives length consecutive cells of the terminal starting at wcoord the attribute.
+//
+// Parameters:
+//
+//	hStdout: The handle where the attributes will be written.
+//	attribute: The attribute to be written to the character cells.
+//	length: The number of character cells to write the attribute to.
+//	wcoord: The starting coordinates for writing the attribute.
+//
+// Returns:
+//
+//	uint16: The number of character cells with attributes successfully written.
+//	error: If the function successfully writes the attribute to the character cells, it returns nil. Otherwise, it returns an error.
+func WriteAttribute(hStdout Handle, attribute uint16, length uint32, wcoord Coord) (uint16, error) {
+	return update(hStdout, int(length), wcoord, func(shadow *VirtualConsole) (uint16, error) {
+		return shadow.WriteAttribute(attribute, length, wcoord)
+	})
+}
+
+// WriteCharacter writes char to length consecutive cells of the terminal starting at wcoord.
+//
+// Parameters:
+//
+//	hStdout: The handle where characters will be written.
+//	char: The character to be written to the character cells.
+//	length: The number of character cells to write the character to.
+//	wcoord: The starting coordinates for writing the character.
+//
+// Returns:
+//
+//	uint16: The number of character cells with the character successfully written.
+//	error: If the function successfully writes the character to the character cells, it returns nil. Otherwise, it returns an error.
+func WriteCharacter(hStdout Handle, char uint16, length uint32, wcoord Coord) (uint16, error) {
+	return update(hStdout, int(length), wcoord, func(shadow *VirtualConsole) (uint16, error) {
+		return shadow.WriteCharacter(char, length, wcoord)
+	})
+}
+
+// writeOutputCells writes a row of cells to the terminal starting at wcoord, clipped at the end of the row.
+func writeOutputCells(hStdout Handle, cells []CharInfo, wcoord Coord) error {
+	_, err := update(hStdout, len(cells), wcoord, func(shadow *VirtualConsole) (uint16, error) {
+		if err := shadow.writeCells(cells, wcoord); err != nil {
+			return 0, err
+		}
+		return uint16(len(cells)), nil
+	})
+
+	return err
+}
+
+// WriteOutput writes a rectangle of cells to the terminal with the semantics of WriteConsoleOutput
+// and repaints it in one write.
+//
+// Parameters:
+//
+//	hStdout: The handle where the cells will be written.
+//	buffer: The cells to write, bufsize.X cells per row.
+//	bufsize: The size of buffer, in columns and rows.
+//	bufcoord: The cell of buffer written to the top left corner of region.
+//	region: The rectangle of the screen to write. On return, it holds the rectangle actually written,
+//	clipped to the screen and to buffer; its Right is less than its Left when nothing was written.
+//
+// Returns:
+//
+//	error: If the function successfully writes the cells, it returns nil. Otherwise, it returns an error.
+func WriteOutput(hStdout Handle, buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return err
+	}
+
+	if err := shadow.WriteOutput(buffer, bufsize, bufcoord, region); err != nil {
+		return err
+	}
+
+	if region.Left > region.Right || region.Top > region.Bottom {
+		return nil
+	}
+
+	return redraw(hStdout, shadow, *region)
+}
+
+// ReadOutput reads a rectangle of cells of the terminal with the semantics of ReadConsoleOutput,
+// from the cells written through this package.
+//
+// Parameters:
+//
+//	hStdout: The handle from which the cells will be read.
+//	buffer: The cells read, bufsize.X cells per row.
+//	bufsize: The size of buffer, in columns and rows.
+//	bufcoord: The cell of buffer receiving the top left corner of region.
+//	region: The rectangle of the screen to read. On return, it holds the rectangle actually read,
+//	clipped to the screen and to buffer; its Right is less than its Left when nothing was read.
+//
+// Returns:
+//
+//	error: If the function successfully reads the cells, it returns nil. Otherwise, it returns an error.
+func ReadOutput(hStdout Handle, buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return err
+	}
+
+	return shadow.ReadOutput(buffer, bufsize, bufcoord, region)
+}
+
+// ReadOutputCharacter reads the characters of consecutive cells of the terminal starting at wcoord,
+// wrapping at the end of each row, from the cells written through this package.
+//
+// Parameters:
+//
+//	hStdout: The handle from which the characters will be read.
+//	buffer: The UTF-16 code units read, one per cell; its length is the number of cells to read.
+//	wcoord: The starting coordinates for reading.
+//
+// Returns:
+//
+//	uint32: The number of cells read, fewer than len(buffer) when the end of the screen is reached.
+//	error: If the function successfully reads the characters, it returns nil. Otherwise, it returns an error.
+func ReadOutputCharacter(hStdout Handle, buffer []uint16, wcoord Coord) (uint32, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return 0, err
+	}
+
+	return shadow.ReadOutputCharacter(buffer, wcoord)
+}
+
+// ReadOutputAttribute reads the attributes of consecutive cells of the terminal starting at wcoord,
+// wrapping at the end of each row, from the cells written through this package.
+//
+// Parameters:
+//
+//	hStdout: The handle from which the attributes will be read.
+//	buffer: The attributes read, one per cell; its length is the number of cells to read.
+//	wcoord: The starting coordinates for reading.
+//
+// Returns:
+//
+//	uint32: The number of cells read, fewer than len(buffer) when the end of the screen is reached.
+//	error: If the function successfully reads the attributes, it returns nil. Otherwise, it returns an error.
+func ReadOutputAttribute(hStdout Handle, buffer []uint16, wcoord Coord) (uint32, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return 0, err
+	}
+
+	return shadow.ReadOutputAttribute(buffer, wcoord)
+}
+
+// ScrollScreenBuffer scrolls a portion of the terminal with the semantics of ScrollConsoleScreenBuffer
+// and repaints the cells that changed.
+//
+// Parameters:
+//
+//	hStdout: The handle where scrolling will be performed.
+//	scrollrect: A pointer to a SmallRect structure specifying the portion of the screen to be scrolled.
+//	cliprect: A pointer to a SmallRect structure specifying the clipping area for scrolling.
+//	dest: A Coord structure specifying the destination coordinates for the scroll operation.
+//	fill: A CharInfo structure specifying the character attributes to be used for filling new space created by scrolling.
+//
+// Returns:
+//
+//	error: If the function successfully performs the scrolling operation, it returns nil. Otherwise, it returns an error.
+func ScrollScreenBuffer(hStdout Handle, scrollrect, cliprect *SmallRect, dest Coord, fill CharInfo) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	shadow, err := screenOf(hStdout)
+	if err != nil {
+		return err
+	}
+
+	if err := shadow.ScrollScreenBuffer(scrollrect, cliprect, dest, fill); err != nil {
+		return err
+	}
+
+	touched := SmallRect{
+		Left:   min(scrollrect.Left, dest.X),
+		Top:    min(scrollrect.Top, dest.Y),
+		Right:  max(scrollrect.Right, dest.X+scrollrect.Right-scrollrect.Left),
+		Bottom: max(scrollrect.Bottom, dest.Y+scrollrect.Bottom-scrollrect.Top),
+	}
+	if cliprect != nil {
+		var ok bool
+		if touched, ok = intersectRect(touched, *cliprect); !ok {
+			return nil
+		}
+	}
+
+	return redraw(hStdout, shadow, touched)
+}
+
+// CreateScreenBuffer creates a screen buffer on the terminal, displayed in its alternate screen once it is made
+// the active screen buffer with SetActiveScreenBuffer. What is written to it is kept off screen until then.
+// The handle is a duplicate of the standard output descriptor.
+//
+// Returns:
+//
+//	Handle: The handle of the new screen buffer, to be closed with CloseHandle.
+//	error: If the function successfully creates the screen buffer, it returns nil. Otherwise, it returns an error.
+func CreateScreenBuffer() (Handle, error) {
+	fd, err := syscall.Dup(syscall.Stdout)
+	if err != nil {
+		return invalidHandle, err
+	}
+
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	h := Handle(fd)
+	ttyOf(h).hidden = true
+	if _, err := screenOf(h); err != nil {
+		delete(ttys, h)
+		syscall.Close(fd)
+		return invalidHandle, err
+	}
+
+	return h, nil
+}
+
+// SetActiveScreenBuffer displays a screen buffer on the terminal. A buffer from CreateScreenBuffer is drawn
+// in the alternate screen, and the standard output switches back to the main screen, which the terminal
+// restores with its scrollback as it was before. The standard output is not drawn while another buffer is,
+// and is redrawn from the cells written to it when it is displayed again if it was written meanwhile.
+//
+// Parameters:
+//
+//	hConsoleOutput: The handle of the screen buffer, from CreateScreenBuffer or GetStdHandle.
+//
+// Returns:
+//
+//	error: If the function successfully activates the screen buffer, it returns nil. Otherwise, it returns an error.
+func SetActiveScreenBuffer(hConsoleOutput Handle) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	return activate(hConsoleOutput)
+}
+
+func activate(h Handle) error {
+	stdout := Handle(syscall.Stdout)
+	if h == activeOutput {
+		return nil
+	}
+
+	next, ok := ttys[h]
+	if h != stdout && (!ok || !next.hidden) {
+		return syscall.EINVAL
+	}
+
+	seq := "\x1b[2J"
+	switch {
+	case h == stdout:
+		seq = "\x1b[?1049l"
+	case activeOutput == stdout:
+		seq = "\x1b[?1049h"
+	}
+
+	if err := writeTTY(activeOutput, seq); err != nil {
+		return err
+	}
+	ttyOf(activeOutput).hidden = true
+	next = ttyOf(h)
+	next.hidden, activeOutput = false, h
+
+	// The terminal restores the main screen as it was left, without what was written to the standard
+	// output meanwhile, which is redrawn from its shadow buffer like a created buffer is.
+	if h == stdout && !next.stale {
+		return nil
+	}
+	next.stale = false
+
+	shadow, err := screenOf(h)
+	if err != nil {
+		return err
+	}
+
+	var scrbufinfo ScreenBufferInfo
+	if err := shadow.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return err
+	}
+
+	if err := redraw(h, shadow, SmallRect{Right: scrbufinfo.Size.X - 1, Bottom: scrbufinfo.Size.Y - 1}); err != nil {
+		return err
+	}
+
+	if next.curinfo.Visible {
+		return writeTTY(h, "\x1b[?25h")
+	}
+	return writeTTY(h, "\x1b[?25l")
+}
+
+// CloseHandle closes a handle, such as a screen buffer created with CreateScreenBuffer. Closing the screen
+// buffer displayed switches the terminal back to the standard output.
+//
+// Parameters:
+//
+//	hObject: The handle to close.
+//
+// Returns:
+//
+//	error: If the function successfully closes the handle, it returns nil. Otherwise, it returns an error.
+func CloseHandle(hObject Handle) error {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	if hObject == activeOutput && hObject != Handle(syscall.Stdout) {
+		if err := activate(Handle(syscall.Stdout)); err != nil {
+			return err
+		}
+	}
+
+	delete(ttys, hObject)
+	return syscall.Close(int(hObject))
+}
+
+// systemCodePages returns the ANSI and OEM code pages of a United States Windows system,
+// as there are no such code pages on this platform.
+func systemCodePages() (acp, oemcp uint32) {
+	return CodePageANSI1252, CodePageOEMUS
+}
@@ -0,0 +1,332 @@
+package cons
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// InputRecordSize is the size in bytes of an INPUT_RECORD: the event type, two bytes of padding
+// and the 16 bytes of the largest member of the event union.
+const InputRecordSize = 20
+
+// ErrUnknownEventType is returned when an input record carries an event type that is not one of
+// KeyEvent, MouseEvent, WindowBufferSizeEvent, MenuEvent or FocusEvent.
+var ErrUnknownEventType = errors.New("cons: unknown input record event type")
+
+// Event is a decoded input record. Its dynamic type is one of KeyEventRecord, MouseEventRecord,
+// WindowBufferSizeRecord, MenuEventRecord or FocusEventRecord.
+type Event interface {
+	// EventType returns the event type constant of the record, such as KeyEvent.
+	EventType() uint16
+}
+
+func (KeyEventRecord) EventType() uint16 { return KeyEvent }
+
+func (MouseEventRecord) EventType() uint16 { return MouseEvent }
+
+func (WindowBufferSizeRecord) EventType() uint16 { return WindowBufferSizeEvent }
+
+func (MenuEventRecord) EventType() uint16 { return MenuEvent }
+
+func (FocusEventRecord) EventType() uint16 { return FocusEvent }
+
+// DecodeInputRecord decodes the raw bytes of an INPUT_RECORD as laid out by ReadConsoleInputW.
+//
+// Parameters:
+//
+//	b: At least InputRecordSize bytes holding the record, in little endian order.
+//
+// Returns:
+//
+//	Event: The decoded record.
+//	error: If the record is complete and its event type is known, it returns nil. Otherwise, it returns an error.
+func DecodeInputRecord(b []byte) (Event, error) {
+	if len(b) < InputRecordSize {
+		return nil, ErrInvalidParameter
+	}
+
+	le := binary.LittleEndian
+	coord := func(off int) Coord {
+		return Coord{X: int16(le.Uint16(b[off:])), Y: int16(le.Uint16(b[off+2:]))}
+	}
+
+	switch le.Uint16(b) {
+	case KeyEvent:
+		return KeyEventRecord{
+			KeyDown:         int32(le.Uint32(b[4:])),
+			RepeatCount:     le.Uint16(b[8:]),
+			VirtualKeyCode:  le.Uint16(b[10:]),
+			VirtualScanCode: le.Uint16(b[12:]),
+			UnicodeChar:     le.Uint16(b[14:]),
+			ControlKeyState: le.Uint32(b[16:]),
+		}, nil
+	case MouseEvent:
+		return MouseEventRecord{
+			MousePosition:   coord(4),
+			ButtonState:     le.Uint32(b[8:]),
+			ControlKeyState: le.Uint32(b[12:]),
+			EventFlags:      le.Uint32(b[16:]),
+		}, nil
+	case WindowBufferSizeEvent:
+		return WindowBufferSizeRecord{Size: coord(4)}, nil
+	case MenuEvent:
+		return MenuEventRecord{CommandId: le.Uint32(b[4:])}, nil
+	case FocusEvent:
+		return FocusEventRecord{SetFocus: le.Uint32(b[4:]) != 0}, nil
+	}
+
+	return nil, ErrUnknownEventType
+}
+
+// DecodeInputRecords decodes consecutive raw INPUT_RECORD structures.
+//
+// Parameters:
+//
+//	b: The raw records, a multiple of InputRecordSize bytes.
+//
+// Returns:
+//
+//	[]Event: The decoded records, in order.
+//	error: If every record decodes, it returns nil. Otherwise, it returns the error of the first record that does not.
+func DecodeInputRecords(b []byte) ([]Event, error) {
+	if len(b)%InputRecordSize != 0 {
+		return nil, ErrInvalidParameter
+	}
+
+	events := make([]Event, 0, len(b)/InputRecordSize)
+	for ; len(b) > 0; b = b[InputRecordSize:] {
+		ev, err := DecodeInputRecord(b)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, ev)
+	}
+
+	return events, nil
+}
+
+// EncodeInputRecord encodes an event to the raw INPUT_RECORD layout, the inverse of DecodeInputRecord.
+//
+// Parameters:
+//
+//	ev: The event to encode.
+//
+// Returns:
+//
+//	[]byte: InputRecordSize bytes holding the record.
+//	error: If the event is one of the record types, it returns nil. Otherwise, it returns ErrUnknownEventType.
+func EncodeInputRecord(ev Event) ([]byte, error) {
+	b := make([]byte, InputRecordSize)
+	le := binary.LittleEndian
+	coord := func(off int, c Coord) {
+		le.PutUint16(b[off:], uint16(c.X))
+		le.PutUint16(b[off+2:], uint16(c.Y))
+	}
+
+	switch ev := ev.(type) {
+	case KeyEventRecord:
+		le.PutUint32(b[4:], uint32(ev.KeyDown))
+		le.PutUint16(b[8:], ev.RepeatCount)
+		le.PutUint16(b[10:], ev.VirtualKeyCode)
+		le.PutUint16(b[12:], ev.VirtualScanCode)
+		le.PutUint16(b[14:], ev.UnicodeChar)
+		le.PutUint32(b[16:], ev.ControlKeyState)
+	case MouseEventRecord:
+		coord(4, ev.MousePosition)
+		le.PutUint32(b[8:], ev.ButtonState)
+		le.PutUint32(b[12:], ev.ControlKeyState)
+		le.PutUint32(b[16:], ev.EventFlags)
+	case WindowBufferSizeRecord:
+		coord(4, ev.Size)
+	case MenuEventRecord:
+		le.PutUint32(b[4:], ev.CommandId)
+	case FocusEventRecord:
+		if ev.SetFocus {
+			le.PutUint32(b[4:], 1)
+		}
+	default:
+		return nil, ErrUnknownEventType
+	}
+
+	le.PutUint16(b, ev.EventType())
+	return b, nil
+}
+
+// ReadEvents reads up to n input records at once and decodes each of them, blocking until at least one is available.
+//
+// Parameters:
+//
+//	hStdin: The input side of the console from which input records will be read.
+//	n: The maximum number of input records to read.
+//
+// Returns:
+//
+//	[]Event: The decoded records, in the order they were read.
+//	error: If the function successfully reads and decodes the input records, it returns nil. Otherwise, it returns an error.
+func ReadEvents(hStdin InputSource, n int) ([]Event, error) {
+	if n <= 0 {
+		return nil, ErrInvalidParameter
+	}
+
+	var (
+		// buffer is made of uint32 so the records keep the alignment of INPUT_RECORD.
+		buffer  = make([]uint32, n*InputRecordSize/4)
+		counter uint32
+	)
+
+	if err := hStdin.ReadInput(unsafe.Pointer(&buffer[0]), uint32(n), &counter); err != nil {
+		return nil, err
+	}
+
+	raw := unsafe.Slice((*byte)(unsafe.Pointer(&buffer[0])), len(buffer)*4)
+	return DecodeInputRecords(raw[:min(int(counter), n)*InputRecordSize])
+}
+
+// VirtualInput is an in-memory InputSource. Events queued with Push are returned by ReadInput in
+// the raw INPUT_RECORD layout, so code reading input can be driven by synthetic records.
+type VirtualInput struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	wake   chan struct{} // Closed and replaced whenever records are queued or the input is closed.
+	mode   DWord
+	queue  [][]byte
+	closed bool
+}
+
+// NewVirtualInput creates an empty virtual input with the default input mode of a console.
+//
+// Returns:
+//
+//	*VirtualInput: The new virtual input.
+func NewVirtualInput() *VirtualInput {
+	vi := &VirtualInput{
+		wake: make(chan struct{}),
+		mode: EnableProcessedInput | EnableLineInput | EnableEchoInput,
+	}
+	vi.cond = sync.NewCond(&vi.mu)
+
+	return vi
+}
+
+// Push queues events to be returned by ReadInput.
+//
+// Parameters:
+//
+//	events: The events to queue, in order.
+//
+// Returns:
+//
+//	error: If every event can be encoded, it returns nil. Otherwise, it returns ErrUnknownEventType and queues nothing.
+func (vi *VirtualInput) Push(events ...Event) error {
+	records := make([][]byte, 0, len(events))
+	for _, ev := range events {
+		b, err := EncodeInputRecord(ev)
+		if err != nil {
+			return err
+		}
+		records = append(records, b)
+	}
+
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	vi.queue = append(vi.queue, records...)
+	vi.notify()
+	return nil
+}
+
+func (vi *VirtualInput) notify() {
+	vi.cond.Broadcast()
+	close(vi.wake)
+	vi.wake = make(chan struct{})
+}
+
+// Pending returns the number of queued records that have not been read yet.
+func (vi *VirtualInput) Pending() int {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	return len(vi.queue)
+}
+
+// Close wakes up blocked readers. Once the queue is drained, ReadInput returns io.EOF.
+func (vi *VirtualInput) Close() error {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	if !vi.closed {
+		vi.closed = true
+		vi.notify()
+	}
+
+	return nil
+}
+
+func (vi *VirtualInput) GetMode() (DWord, error) {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	return vi.mode, nil
+}
+
+func (vi *VirtualInput) SetMode(mode DWord) error {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	vi.mode = mode
+	return nil
+}
+
+// WaitForInput waits until records are queued, the input is closed or the timeout elapses.
+// A negative timeout waits forever.
+func (vi *VirtualInput) WaitForInput(timeout time.Duration) (bool, error) {
+	var deadline <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		vi.mu.Lock()
+		ready, wake := len(vi.queue) > 0 || vi.closed, vi.wake
+		vi.mu.Unlock()
+
+		if ready {
+			return true, nil
+		}
+
+		select {
+		case <-wake:
+		case <-deadline:
+			return false, nil
+		}
+	}
+}
+
+// ReadInput blocks until records are queued, then copies up to length of them to buffer.
+func (vi *VirtualInput) ReadInput(buffer unsafe.Pointer, length uint32, counter *uint32) error {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	*counter = 0
+	for len(vi.queue) == 0 {
+		if vi.closed {
+			return io.EOF
+		}
+		vi.cond.Wait()
+	}
+
+	raw := unsafe.Slice((*byte)(buffer), int(length)*InputRecordSize)
+	for *counter < length && len(vi.queue) > 0 {
+		copy(raw[int(*counter)*InputRecordSize:], vi.queue[0])
+		vi.queue = vi.queue[1:]
+		*counter++
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package cons
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// record builds a raw INPUT_RECORD from its event type and the bytes of its union, from offset 4.
+func record(eventType uint16, union ...byte) []byte {
+	b := make([]byte, InputRecordSize)
+	b[0], b[1] = byte(eventType), byte(eventType>>8)
+	copy(b[4:], union)
+
+	return b
+}
+
+func TestInputRecordRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		want Event
+	}{
+		{"key", record(KeyEvent,
+			1, 0, 0, 0, // KeyDown
+			3, 0, // RepeatCount
+			0x41, 0, // VirtualKeyCode
+			0x1e, 0, // VirtualScanCode
+			0xe9, 0, // UnicodeChar
+			0x08, 0x01, 0, 0), // ControlKeyState
+			KeyEventRecord{KeyDown: 1, RepeatCount: 3, VirtualKeyCode: 0x41, VirtualScanCode: 0x1e, UnicodeChar: 0xe9, ControlKeyState: LeftCtrlPressed | EnhancedKey}},
+		{"mouse", record(MouseEvent,
+			0x0a, 0, 0xfe, 0xff, // MousePosition, with a negative row
+			0x01, 0, 0, 0, // ButtonState
+			0x10, 0, 0, 0, // ControlKeyState
+			0x04, 0, 0, 0), // EventFlags
+			MouseEventRecord{MousePosition: Coord{X: 10, Y: -2}, ButtonState: 1, ControlKeyState: ShiftPressed, EventFlags: 4}},
+		{"window buffer size", record(WindowBufferSizeEvent, 0x50, 0, 0x19, 0),
+			WindowBufferSizeRecord{Size: Coord{X: 80, Y: 25}}},
+		{"menu", record(MenuEvent, 0x78, 0x56, 0x34, 0x12), MenuEventRecord{CommandId: 0x12345678}},
+		{"focus", record(FocusEvent, 1), FocusEventRecord{SetFocus: true}},
+		{"focus lost", record(FocusEvent), FocusEventRecord{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, err := DecodeInputRecord(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ev != tt.want {
+				t.Errorf("DecodeInputRecord() = %#v, want %#v", ev, tt.want)
+			}
+
+			raw, err := EncodeInputRecord(ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(raw, tt.raw) {
+				t.Errorf("EncodeInputRecord() = % x, want % x", raw, tt.raw)
+			}
+		})
+	}
+}
+
+func TestDecodeInputRecordPadding(t *testing.T) {
+	// The padding after the event type and the bytes past the member of the union are not part of the record.
+	raw := record(WindowBufferSizeEvent, 0x50, 0, 0x19, 0, 0xff, 0xff, 0xff, 0xff)
+	raw[2], raw[3] = 0xcc, 0xcc
+	raw = append(raw, 0xff)
+
+	ev, err := DecodeInputRecord(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (WindowBufferSizeRecord{Size: Coord{X: 80, Y: 25}}); ev != want {
+		t.Errorf("DecodeInputRecord() = %#v, want %#v", ev, want)
+	}
+
+	// Any nonzero value of the BOOL gains focus.
+	if ev, _ := DecodeInputRecord(record(FocusEvent, 0, 0, 0, 1)); ev != (FocusEventRecord{SetFocus: true}) {
+		t.Errorf("DecodeInputRecord() = %#v, want focus set", ev)
+	}
+}
+
+func TestDecodeInputRecordErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		err  error
+	}{
+		{"empty", nil, ErrInvalidParameter},
+		{"short", record(KeyEvent)[:InputRecordSize-1], ErrInvalidParameter},
+		{"no event type", record(0), ErrUnknownEventType},
+		{"unknown event type", record(0x0020), ErrUnknownEventType},
+		{"combined event types", record(KeyEvent | MouseEvent), ErrUnknownEventType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ev, err := DecodeInputRecord(tt.raw); !errors.Is(err, tt.err) {
+				t.Errorf("DecodeInputRecord() = %#v, %v, want %v", ev, err, tt.err)
+			}
+		})
+	}
+
+	if _, err := EncodeInputRecord(ResizeEvent{}); !errors.Is(err, ErrUnknownEventType) {
+		t.Errorf("EncodeInputRecord(ResizeEvent{}) error = %v, want %v", err, ErrUnknownEventType)
+	}
+}
+
+func TestDecodeInputRecords(t *testing.T) {
+	focus, menu := record(FocusEvent, 1), record(MenuEvent, 7)
+
+	events, err := DecodeInputRecords(append(append([]byte{}, focus...), menu...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0] != (FocusEventRecord{SetFocus: true}) || events[1] != (MenuEventRecord{CommandId: 7}) {
+		t.Errorf("DecodeInputRecords() = %#v, want the focus and menu events", events)
+	}
+
+	if _, err := DecodeInputRecords(focus[:InputRecordSize-2]); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("DecodeInputRecords() of a partial record error = %v, want %v", err, ErrInvalidParameter)
+	}
+
+	// The records before an unknown one are returned with the error.
+	events, err = DecodeInputRecords(append(append([]byte{}, focus...), record(0x0040)...))
+	if !errors.Is(err, ErrUnknownEventType) || len(events) != 1 {
+		t.Errorf("DecodeInputRecords() = %#v, %v, want the focus event and %v", events, err, ErrUnknownEventType)
+	}
+}
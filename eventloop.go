@@ -0,0 +1,259 @@
+package cons
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// InputWaiter is implemented by input sources that can wait for input with a timeout, such as Handle and
+// VirtualInput. An EventLoop reading from an InputWaiter never blocks in ReadInput, so it can be stopped at once.
+type InputWaiter interface {
+	// WaitForInput waits until input is available or the timeout elapses; a negative timeout waits forever.
+	WaitForInput(timeout time.Duration) (bool, error)
+}
+
+// eventLoopPoll is how long the reading goroutine waits for input before checking for cancellation.
+const eventLoopPoll = 50 * time.Millisecond
+
+// EventLoop reads input records on a goroutine and delivers them, decoded, on a channel,
+// so input can be selected on together with timers and other channels.
+type EventLoop struct {
+	in      InputSource
+	decoder *VTInputDecoder // The decoder of key sequences, in EnableVirtualTerminalInput mode.
+	events  chan Event
+	cancel  context.CancelFunc
+	done    chan struct{}
+	mu      sync.Mutex
+	err     error // The reason the loop stopped.
+	mode    DWord // The input mode to restore on Close.
+	restore bool  // Indicates whether the input mode was changed by the loop.
+	once    sync.Once
+	cerr    error        // The result of Close.
+	resize  OutputTarget // The output whose size is reported, see WatchResize.
+	settle  time.Time    // When the postponed ResizeEvent is due, zero if none is.
+}
+
+// NewEventLoop switches the input to the given mode and starts reading events from it until ctx is done,
+// an error occurs or Close is called.
+//
+// When the mode includes EnableVirtualTerminalInput, the console reports key sequences such as ESC [ A
+// character by character; the loop decodes them with a VTInputDecoder, so the same KeyEventRecord values
+// are delivered in both modes, along with a PasteEvent for bracketed paste.
+//
+// Parameters:
+//
+//	ctx: The context whose cancellation stops the loop.
+//	in: The input side of the console to read from.
+//	mode: The input mode to use while the loop runs, such as EnableWindowInput | EnableMouseInput,
+//	      or 0 to keep the current mode.
+//
+// Returns:
+//
+//	*EventLoop: The running loop.
+//	error: If the function successfully switches the input mode, it returns nil. Otherwise, it returns an error.
+func NewEventLoop(ctx context.Context, in InputSource, mode DWord) (*EventLoop, error) {
+	l := &EventLoop{
+		in:     in,
+		events: make(chan Event, 64),
+		done:   make(chan struct{}),
+	}
+
+	saved, err := in.GetMode()
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != 0 {
+		if err := in.SetMode(mode); err != nil {
+			return nil, err
+		}
+		l.mode, l.restore = saved, true
+	} else {
+		mode = saved
+	}
+
+	if mode&EnableVirtualTerminalInput != 0 {
+		l.decoder = NewVTInputDecoder()
+	}
+
+	ctx, l.cancel = context.WithCancel(ctx)
+	go l.run(ctx)
+
+	return l, nil
+}
+
+func (l *EventLoop) run(ctx context.Context) {
+	defer close(l.done)
+	defer close(l.events)
+
+	waiter, _ := l.in.(InputWaiter)
+	for {
+		if err := ctx.Err(); err != nil {
+			l.stop(err)
+			return
+		}
+
+		if !l.settleResize(ctx) {
+			return
+		}
+
+		if waiter != nil {
+			held := l.decoder != nil && l.decoder.Pending()
+			timeout := eventLoopPoll
+			if held {
+				timeout = EscapeTimeout
+			}
+			if !l.settle.IsZero() {
+				timeout = max(min(timeout, time.Until(l.settle)), 0)
+			}
+
+			ready, err := waiter.WaitForInput(timeout)
+			if err != nil {
+				l.stop(err)
+				return
+			}
+			if !ready {
+				if held && !l.send(ctx, l.decoder.Flush()) {
+					return
+				}
+				continue
+			}
+		}
+
+		events, err := ReadEvents(l.in, 16)
+		if err != nil {
+			l.stop(err)
+			return
+		}
+
+		if l.decoder != nil {
+			events = l.decode(events)
+		}
+
+		if !l.send(ctx, l.filterResize(events)) {
+			return
+		}
+	}
+}
+
+// decode runs the keys read in EnableVirtualTerminalInput mode through the decoder.
+func (l *EventLoop) decode(events []Event) []Event {
+	var decoded []Event
+	for _, ev := range events {
+		if key, ok := ev.(KeyEventRecord); ok {
+			decoded = append(decoded, l.decoder.FeedKey(key)...)
+			continue
+		}
+
+		decoded = append(decoded, ev)
+	}
+
+	return decoded
+}
+
+// send delivers events on the channel, reporting false if ctx is done first.
+func (l *EventLoop) send(ctx context.Context, events []Event) bool {
+	for _, ev := range events {
+		select {
+		case l.events <- ev:
+		case <-ctx.Done():
+			l.stop(ctx.Err())
+			return false
+		}
+	}
+
+	return true
+}
+
+func (l *EventLoop) stop(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+// Events returns the channel on which the events are delivered. It is closed when the loop stops.
+func (l *EventLoop) Events() <-chan Event {
+	return l.events
+}
+
+// Err returns the reason the loop stopped, or nil while it is running.
+// It is context.Canceled after Close.
+func (l *EventLoop) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Next waits for the next event.
+//
+// Parameters:
+//
+//	ctx: The context bounding the wait.
+//
+// Returns:
+//
+//	Event: The next event.
+//	error: If an event arrives, it returns nil. Otherwise, it returns the error of ctx, the reason the loop stopped, or io.EOF.
+func (l *EventLoop) Next(ctx context.Context) (Event, error) {
+	select {
+	case ev, ok := <-l.events:
+		if ok {
+			return ev, nil
+		}
+
+		if err := l.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// NextTimeout waits for the next event for at most timeout.
+//
+// Parameters:
+//
+//	timeout: The maximum time to wait.
+//
+// Returns:
+//
+//	Event: The next event.
+//	error: If an event arrives in time, it returns nil. Otherwise, it returns context.DeadlineExceeded or the reason the loop stopped.
+func (l *EventLoop) NextTimeout(timeout time.Duration) (Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return l.Next(ctx)
+}
+
+// Close stops the loop and restores the input mode that was active before NewEventLoop.
+// When the input source is an InputWaiter, Close also waits for the reading goroutine to exit;
+// otherwise the goroutine exits after the read it is blocked in returns.
+//
+// Returns:
+//
+//	error: If the function successfully restores the input mode, it returns nil. Otherwise, it returns an error.
+func (l *EventLoop) Close() error {
+	l.once.Do(func() {
+		l.cancel()
+		if _, ok := l.in.(InputWaiter); ok {
+			<-l.done
+		}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.restore {
+			l.cerr = l.in.SetMode(l.mode)
+		}
+	})
+
+	return l.cerr
+}
@@ -1,9 +1,6 @@
 package cons
 
-import (
-	"fmt"
-	"unsafe"
-)
+import "fmt"
 
 // IsValidHandle checks if the current Handle is a valid handle.
 //
@@ -27,8 +24,18 @@ func (mode DWord) IsEnableMode(flag int) bool {
 	return mode&DWord(flag) != 0
 }
 
-func IsEnableMode(hStdout Handle, flag int) bool {
-	mode, err := GetMode(hStdout)
+// IsEnableMode checks if a specific console mode flag is enabled on the given input or output side of a console.
+//
+// Parameters:
+//
+//	hStdout: The input or output side of the console whose mode is checked.
+//	flag: The mode flag to check.
+//
+// Returns:
+//
+//	bool: True if the mode flag is enabled, otherwise false.
+func IsEnableMode(hStdout ModeController, flag int) bool {
+	mode, err := hStdout.GetMode()
 	if err != nil {
 		return false
 	}
@@ -36,7 +43,7 @@ func IsEnableMode(hStdout Handle, flag int) bool {
 	return mode&DWord(flag) != 0
 }
 
-// Enables or modifies console modes for the specified standard output handle in Windows.
+// Enables or modifies console modes for the specified standard output handle.
 //
 // Parameters:
 //
@@ -46,8 +53,8 @@ func IsEnableMode(hStdout Handle, flag int) bool {
 // Returns:
 //
 //	error: If the function successfully enables or modifies the console modes, it returns nil. Otherwise, it returns an error.
-func EnableMode(hStdout Handle, flag ...int) error {
-	mode, err := GetMode(hStdout)
+func EnableMode(hStdout ModeController, flag ...int) error {
+	mode, err := hStdout.GetMode()
 	if err != nil {
 		return err
 	}
@@ -56,10 +63,33 @@ func EnableMode(hStdout Handle, flag ...int) error {
 		mode |= DWord(f)
 	}
 
-	return SetMode(hStdout, mode)
+	return hStdout.SetMode(mode)
 }
 
-// Sets the visibility of the cursor for the specified standard output handle in Windows.
+// Disables console modes for the specified standard handle, the counterpart of EnableMode.
+//
+// Parameters:
+//
+//	hStdout: The input or output side of the console for which modes are to be disabled.
+//	flag: An optional variadic list of mode flags to disable.
+//
+// Returns:
+//
+//	error: If the function successfully disables the console modes, it returns nil. Otherwise, it returns an error.
+func DisableMode(hStdout ModeController, flag ...int) error {
+	mode, err := hStdout.GetMode()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range flag {
+		mode &^= DWord(f)
+	}
+
+	return hStdout.SetMode(mode)
+}
+
+// Sets the visibility of the cursor for the specified standard output handle.
 //
 // Parameters:
 //
@@ -69,17 +99,17 @@ func EnableMode(hStdout Handle, flag ...int) error {
 // Returns:
 //
 //	error: If the function successfully sets the cursor visibility, it returns nil. Otherwise, it returns an error.
-func SetCursorVisible(hStdout Handle, visible bool) error {
-	curinfo, err := GetCursorInfo(hStdout)
+func SetCursorVisible(hStdout OutputTarget, visible bool) error {
+	curinfo, err := hStdout.GetCursorInfo()
 	if err != nil {
 		return err
 	}
 
 	curinfo.Visible = visible
-	return SetCursorInfo(hStdout, &curinfo)
+	return hStdout.SetCursorInfo(&curinfo)
 }
 
-// Sets the size of the cursor for the specified standard output handle in Windows.
+// Sets the size of the cursor for the specified standard output handle.
 //
 // Parameters:
 //
@@ -89,17 +119,17 @@ func SetCursorVisible(hStdout Handle, visible bool) error {
 // Returns:
 //
 //	error: If the function successfully sets the cursor size, it returns nil. Otherwise, it returns an error.
-func SetCursorSize(hStdout Handle, size uint32) error {
-	curinfo, err := GetCursorInfo(hStdout)
+func SetCursorSize(hStdout OutputTarget, size uint32) error {
+	curinfo, err := hStdout.GetCursorInfo()
 	if err != nil {
 		return err
 	}
 
 	curinfo.Size = size
-	return SetCursorInfo(hStdout, &curinfo)
+	return hStdout.SetCursorInfo(&curinfo)
 }
 
-// retrieves the current cursor position within the screen buffer of the specified standard output handle in Windows.
+// retrieves the current cursor position within the screen buffer of the specified standard output handle.
 //
 // Parameters:
 //
@@ -109,9 +139,9 @@ func SetCursorSize(hStdout Handle, size uint32) error {
 //
 //	Coord: The current cursor position represented as a Coord structure.
 //	error: If the function successfully retrieves the cursor position, it returns nil. Otherwise, it returns an error.
-func GetCursorPosition(hStdout Handle) (Coord, error) {
+func GetCursorPosition(hStdout OutputTarget) (Coord, error) {
 	var scrbufinfo ScreenBufferInfo
-	if err := GetScreenBufferInfo(hStdout, &scrbufinfo); err != nil {
+	if err := hStdout.GetScreenBufferInfo(&scrbufinfo); err != nil {
 		return Coord{}, err
 	}
 
@@ -128,7 +158,7 @@ func GetCursorPosition(hStdout Handle) (Coord, error) {
 // Returns:
 //
 //	error: If the function successfully updates the cursor position, it returns nil. Otherwise, it returns an error.
-func MoveCursor(hStdout Handle, offset Coord) error {
+func MoveCursor(hStdout OutputTarget, offset Coord) error {
 	curpos, err := GetCursorPosition(hStdout)
 	if err != nil {
 		return err
@@ -137,7 +167,7 @@ func MoveCursor(hStdout Handle, offset Coord) error {
 	curpos.X += offset.X
 	curpos.Y += offset.Y
 
-	if err := SetCursorPosition(hStdout, curpos); err != nil {
+	if err := hStdout.SetCursorPosition(curpos); err != nil {
 		return err
 	}
 
@@ -154,7 +184,7 @@ func MoveCursor(hStdout Handle, offset Coord) error {
 // Returns:
 //
 //	error: If the function successfully updates the cursor position, it returns nil. Otherwise, it returns an error.
-func MoveCursorVert(hStdout Handle, offset int16) error {
+func MoveCursorVert(hStdout OutputTarget, offset int16) error {
 	return MoveCursor(hStdout, Coord{X: 0, Y: offset})
 }
 
@@ -168,11 +198,11 @@ func MoveCursorVert(hStdout Handle, offset int16) error {
 // Returns:
 //
 //	error: If the function successfully updates the cursor position, it returns nil. Otherwise, it returns an error.
-func MoveCursorHorz(hStdout Handle, offset int16) error {
+func MoveCursorHorz(hStdout OutputTarget, offset int16) error {
 	return MoveCursor(hStdout, Coord{X: offset, Y: 0})
 }
 
-// retrieves a key press event from the specified standard input handle in Windows.
+// retrieves a key press event from the specified standard input handle.
 //
 // Parameters:
 //
@@ -182,24 +212,22 @@ func MoveCursorHorz(hStdout Handle, offset int16) error {
 //
 //	uint16: The Unicode character representing the key press event.
 //	error: If the function successfully retrieves a key press event, it returns nil. Otherwise, it returns an error.
-func GetKeyValue(hStdin Handle) (uint16, error) {
-	var (
-		buffer  = InputRecord[KeyEventRecord]{}
-		counter uint32
-	)
-
-	if err := ReadInput(hStdin, unsafe.Pointer(&buffer), 1, &counter); err != nil {
+func GetKeyValue(hStdin InputSource) (uint16, error) {
+	events, err := ReadEvents(hStdin, 1)
+	if err != nil {
 		return 0, err
 	}
 
-	if buffer.EventType == KeyEvent && buffer.Event.KeyDown != 0 {
-		return buffer.Event.UnicodeChar, nil
+	for _, ev := range events {
+		if key, ok := ev.(KeyEventRecord); ok && key.KeyDown != 0 {
+			return key.UnicodeChar, nil
+		}
 	}
 
 	return 0, nil
 }
 
-// Pause displays an optional message and waits for a key press event on the specified standard input handle in Windows.
+// Pause displays an optional message and waits for a key press event on the specified standard input handle.
 //
 // Parameters:
 //
@@ -209,37 +237,26 @@ func GetKeyValue(hStdin Handle) (uint16, error) {
 // Returns:
 //
 //	error: If the function successfully waits for a key press event, it returns nil. Otherwise, it returns an error.
-func Pause(hStdin Handle, msg string) error {
-	var (
-		// buffer is a structure used to store console input records, including event type and KeyEventRecord.
-		// It is used to read and store input records from the standard input handle in Windows.
-		buffer = struct {
-			EventType uint16
-			KeyEvent  KeyEventRecord
-		}{
-			EventType: 0,
-			KeyEvent:  KeyEventRecord{},
-		}
-		counter uint32
-	)
-
+func Pause(hStdin InputSource, msg string) error {
 	if msg != "" {
 		fmt.Println(msg)
 	}
 
 	for {
-		if err := ReadInput(hStdin, unsafe.Pointer(&buffer), 1, &counter); err != nil {
+		events, err := ReadEvents(hStdin, 1)
+		if err != nil {
 			return err
 		}
 
-		if buffer.EventType == KeyEvent && buffer.KeyEvent.KeyDown != 0 {
-			return nil
+		for _, ev := range events {
+			if key, ok := ev.(KeyEventRecord); ok && key.KeyDown != 0 {
+				return nil
+			}
 		}
 	}
-
 }
 
-// Fill updates a specified portion of the console screen buffer with the given character and attributes for the specified standard output handle in Windows.
+// Fill updates a specified portion of the console screen buffer with the given character and attributes for the specified standard output handle.
 //
 // Parameters:
 //
@@ -251,19 +268,19 @@ func Pause(hStdin Handle, msg string) error {
 // Returns:
 //
 //	error: If the function successfully updates the screen buffer, it returns nil. Otherwise, it returns an error.
-func Fill(hStdout Handle, fill CharInfo, length int, wcoord Coord) error {
-	if _, err := FillCharacter(hStdout, rune(fill.UnicodeChar), length, wcoord); err != nil {
+func Fill(hStdout OutputTarget, fill CharInfo, length int, wcoord Coord) error {
+	if _, err := hStdout.FillCharacter(rune(fill.UnicodeChar), length, wcoord); err != nil {
 		return err
 	}
 
-	if _, err := FillAttribute(hStdout, fill.Attributes, length, wcoord); err != nil {
+	if _, err := hStdout.FillAttribute(fill.Attributes, length, wcoord); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// clears the entire console screen buffer by filling it with spaces and default attributes for the specified standard output handle in Windows.
+// clears the entire console screen buffer by filling it with spaces and default attributes for the specified standard output handle.
 //
 // Parameters:
 //
@@ -272,14 +289,14 @@ func Fill(hStdout Handle, fill CharInfo, length int, wcoord Coord) error {
 // Returns:
 //
 //	error: If the function successfully clears the screen buffer, it returns nil. Otherwise, it returns an error.
-func ClearScreenBuffer(hStdout Handle) error {
+func ClearScreenBuffer(hStdout OutputTarget) error {
 	var (
 		fill       CharInfo
 		length     int
 		scrbufinfo ScreenBufferInfo
 	)
 
-	if err := GetScreenBufferInfo(hStdout, &scrbufinfo); err != nil {
+	if err := hStdout.GetScreenBufferInfo(&scrbufinfo); err != nil {
 		return err
 	}
 
@@ -291,5 +308,5 @@ func ClearScreenBuffer(hStdout Handle) error {
 		return err
 	}
 
-	return SetCursorPosition(hStdout, Coord{})
+	return hStdout.SetCursorPosition(Coord{})
 }
@@ -0,0 +1,229 @@
+//go:build windows || linux || darwin || freebsd || netbsd || openbsd
+
+package cons
+
+import (
+	"io"
+	"os"
+	"time"
+	"unsafe"
+)
+
+// GetMode retrieves the console mode of the handle, see GetMode.
+func (h Handle) GetMode() (DWord, error) {
+	return GetMode(h)
+}
+
+// SetMode sets the console mode of the handle, see SetMode.
+func (h Handle) SetMode(mode DWord) error {
+	return SetMode(h, mode)
+}
+
+// ReadInput reads console input records from the handle, see ReadInput.
+func (h Handle) ReadInput(buffer unsafe.Pointer, length uint32, counter *uint32) error {
+	return ReadInput(h, buffer, length, counter)
+}
+
+// WaitForInput waits for input records on the handle, see WaitForInput.
+func (h Handle) WaitForInput(timeout time.Duration) (bool, error) {
+	return WaitForInput(h, timeout)
+}
+
+// GetCursorInfo retrieves the cursor information of the handle, see GetCursorInfo.
+func (h Handle) GetCursorInfo() (CursorInfo, error) {
+	return GetCursorInfo(h)
+}
+
+// SetCursorInfo sets the cursor information of the handle, see SetCursorInfo.
+func (h Handle) SetCursorInfo(p *CursorInfo) error {
+	return SetCursorInfo(h, p)
+}
+
+// GetScreenBufferInfo retrieves the screen buffer information of the handle, see GetScreenBufferInfo.
+func (h Handle) GetScreenBufferInfo(p *ScreenBufferInfo) error {
+	return GetScreenBufferInfo(h, p)
+}
+
+// SetCursorPosition sets the cursor position of the handle, see SetCursorPosition.
+func (h Handle) SetCursorPosition(newpos Coord) error {
+	return SetCursorPosition(h, newpos)
+}
+
+// FillCharacter fills character cells of the handle, see FillCharacter.
+func (h Handle) FillCharacter(char rune, length int, wcoord Coord) (uint16, error) {
+	return FillCharacter(h, char, length, wcoord)
+}
+
+// FillAttribute fills attribute cells of the handle, see FillAttribute.
+func (h Handle) FillAttribute(attribute uint16, length int, wcoord Coord) (uint16, error) {
+	return FillAttribute(h, attribute, length, wcoord)
+}
+
+// WriteAttribute writes attribute cells of the handle, see WriteAttribute.
+func (h Handle) WriteAttribute(attribute uint16, length uint32, wcoord Coord) (uint16, error) {
+	return WriteAttribute(h, attribute, length, wcoord)
+}
+
+// WriteCharacter writes character cells of the handle, see WriteCharacter.
+func (h Handle) WriteCharacter(char uint16, length uint32, wcoord Coord) (uint16, error) {
+	return WriteCharacter(h, char, length, wcoord)
+}
+
+// WriteOutput writes a rectangle of cells to the handle, see WriteOutput.
+func (h Handle) WriteOutput(buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error {
+	return WriteOutput(h, buffer, bufsize, bufcoord, region)
+}
+
+// ReadOutput reads a rectangle of cells of the handle, see ReadOutput.
+func (h Handle) ReadOutput(buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error {
+	return ReadOutput(h, buffer, bufsize, bufcoord, region)
+}
+
+// ReadOutputCharacter reads character cells of the handle, see ReadOutputCharacter.
+func (h Handle) ReadOutputCharacter(buffer []uint16, wcoord Coord) (uint32, error) {
+	return ReadOutputCharacter(h, buffer, wcoord)
+}
+
+// ReadOutputAttribute reads attribute cells of the handle, see ReadOutputAttribute.
+func (h Handle) ReadOutputAttribute(buffer []uint16, wcoord Coord) (uint32, error) {
+	return ReadOutputAttribute(h, buffer, wcoord)
+}
+
+// writeCells writes a row of cells to the handle, implementing cellWriter.
+func (h Handle) writeCells(cells []CharInfo, wcoord Coord) error {
+	return writeOutputCells(h, cells, wcoord)
+}
+
+// SetTextAttribute sets the text attributes of the handle, see SetTextAttribute.
+func (h Handle) SetTextAttribute(attribute uint16) error {
+	return SetTextAttribute(h, attribute)
+}
+
+// ScrollScreenBuffer scrolls the screen buffer of the handle, see ScrollScreenBuffer.
+func (h Handle) ScrollScreenBuffer(scrollrect, cliprect *SmallRect, dest Coord, fill CharInfo) error {
+	return ScrollScreenBuffer(h, scrollrect, cliprect, dest, fill)
+}
+
+// GetOutputCodePage retrieves the output code page of the console of the handle, see GetOutputCodePage.
+func (h Handle) GetOutputCodePage() (uint32, error) {
+	return GetOutputCodePage()
+}
+
+// handleWriter is the io.Writer writing the bytes given to a handle.
+type handleWriter Handle
+
+func (w handleWriter) Write(p []byte) (int, error) {
+	return writeHandle(Handle(w), p)
+}
+
+// NewHandleRecorder starts a recording of what is written to the recorder, passing it to an output handle,
+// with the size of its window in the header. On Unix the text and VT sequences written are also rendered to
+// the cells kept for the terminal, so ReadOutput and CaptureRegion on the handle see them.
+//
+// Parameters:
+//
+//	cast: The destination of the recording, such as a file.
+//	hStdout: The output handle what is written to the recorder is passed to.
+//
+// Returns:
+//
+//	*Recorder: The recorder, whose events are timed from now.
+//	error: If the function successfully reads the window and writes the header, it returns nil. Otherwise, it returns an error.
+func NewHandleRecorder(cast io.Writer, hStdout Handle) (*Recorder, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := GetScreenBufferInfo(hStdout, &scrbufinfo); err != nil {
+		return nil, err
+	}
+
+	window := scrbufinfo.Window
+	header := CastHeader{Width: int(window.Right - window.Left + 1), Height: int(window.Bottom - window.Top + 1)}
+	if term := os.Getenv("TERM"); term != "" {
+		header.Env = map[string]string{"TERM": term}
+	}
+
+	return NewRecorder(cast, handleWriter(hStdout), header)
+}
+
+// stdConsole is the Console backend built on the standard handles of the process,
+// talking to kernel32 on Windows and to the terminal on Unix.
+type stdConsole struct {
+	hStdin  Handle // The standard input handle.
+	hStdout Handle // The standard output handle.
+}
+
+// NewConsole returns the Console backed by the standard input and output handles of the process.
+//
+// Returns:
+//
+//	Console: The console backed by the standard handles.
+//	error: If the function successfully retrieves the standard handles, it returns nil. Otherwise, it returns an error.
+func NewConsole() (Console, error) {
+	hStdin, err := GetStdHandle(StdInputHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	hStdout, err := GetStdHandle(StdOutputHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stdConsole{hStdin: hStdin, hStdout: hStdout}, nil
+}
+
+func (c *stdConsole) Input() InputSource {
+	return c.hStdin
+}
+
+func (c *stdConsole) Output() OutputTarget {
+	return c.hStdout
+}
+
+func (c *stdConsole) GetInputCodePage() (uint32, error) {
+	return GetInputCodePage()
+}
+
+func (c *stdConsole) SetInputCodePage(cp uint32) error {
+	return SetInputCodePage(cp)
+}
+
+func (c *stdConsole) GetOutputCodePage() (uint32, error) {
+	return GetOutputCodePage()
+}
+
+func (c *stdConsole) SetOutputCodePage(cp uint32) error {
+	return SetOutputCodePage(cp)
+}
+
+func (c *stdConsole) GetWindowTitle() (string, error) {
+	return GetWindowTitle()
+}
+
+func (c *stdConsole) SetWindowTitle(title string) error {
+	return SetWindowTitle(title)
+}
+
+// NewTerminalStyledWriter creates the writer suited to a file: SGR sequences when it is a console or terminal
+// processing VT sequences, console cell attributes when it is a console without VT processing, and plain text
+// when it is not a console, such as a pipe or a regular file. The color profile of a terminal is guessed from
+// the NO_COLOR, COLORTERM, TERM and WT_SESSION environment variables.
+//
+// Parameters:
+//
+//	f: The file to write to, such as os.Stdout.
+//
+// Returns:
+//
+//	*StyledWriter: The new writer.
+func NewTerminalStyledWriter(f *os.File) *StyledWriter {
+	h := Handle(f.Fd())
+	mode, err := h.GetMode()
+	switch {
+	case err != nil:
+		return NewStyledWriter(f, ProfileNoColor)
+	case mode&EnableVirtualTerminalProcessing == 0:
+		return NewConsoleStyledWriter(h)
+	}
+
+	return NewStyledWriter(f, EnvColorProfile())
+}
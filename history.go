@@ -0,0 +1,135 @@
+package cons
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// History is the list of lines entered in a LineEditor, oldest first, optionally kept in a file
+// with one line per entry so that it persists across runs.
+type History struct {
+	mu      sync.Mutex
+	entries []string
+	max     int    // The number of entries kept, 0 for no limit.
+	path    string // The file the entries are appended to, empty for a history held in memory only.
+}
+
+// NewHistory creates a history held in memory.
+//
+// Parameters:
+//
+//	max: The number of entries kept, the oldest being dropped first, or 0 for no limit.
+//
+// Returns:
+//
+//	*History: The new, empty history.
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// LoadHistory creates a history kept in a file, reading the entries the file already holds.
+// A missing file is created by the first Add, and a file holding more than max entries is rewritten
+// with the newest ones.
+//
+// Parameters:
+//
+//	path: The history file.
+//	max: The number of entries kept, the oldest being dropped first, or 0 for no limit.
+//
+// Returns:
+//
+//	*History: The history.
+//	error: If the function successfully reads the file, it returns nil. Otherwise, it returns an error.
+func LoadHistory(path string, max int) (*History, error) {
+	h := &History{max: max, path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+
+	if h.trim() {
+		if err := os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// Add appends a line to the history and to its file. Empty lines and a line equal to the newest entry
+// are skipped, and line breaks inside the line are replaced with spaces.
+//
+// Parameters:
+//
+//	line: The line to add.
+//
+// Returns:
+//
+//	error: If the function successfully writes the line to the file, it returns nil. Otherwise, it returns an error.
+func (h *History) Add(line string) error {
+	line = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(line)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if strings.TrimSpace(line) == "" || len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return nil
+	}
+
+	h.entries = append(h.entries, line)
+	h.trim()
+
+	if h.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Entries returns a copy of the entries, oldest first.
+func (h *History) Entries() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]string(nil), h.entries...)
+}
+
+// Len returns the number of entries.
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.entries)
+}
+
+// trim drops the oldest entries beyond max, reporting whether any was dropped.
+func (h *History) trim() bool {
+	if h.max <= 0 || len(h.entries) <= h.max {
+		return false
+	}
+
+	h.entries = append([]string(nil), h.entries[len(h.entries)-h.max:]...)
+	return true
+}
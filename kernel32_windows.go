@@ -2,6 +2,8 @@ package cons
 
 import "syscall"
 
+const invalidHandle = Handle(0)
+
 var (
 	kernel32                        = syscall.NewLazyDLL("kernel32.dll")
 	procGetStdHandle                = kernel32.NewProc("GetStdHandle")
@@ -22,4 +24,15 @@ var (
 	procWriteConsoleOutputAttribute = kernel32.NewProc("WriteConsoleOutputAttribute")
 	procWriteConsoleOutputCharacter = kernel32.NewProc("WriteConsoleOutputCharacterW")
 	procScrollConsoleScreenBuffer   = kernel32.NewProc("ScrollConsoleScreenBufferW")
+	procWaitForSingleObject         = kernel32.NewProc("WaitForSingleObject")
+	procSetConsoleTextAttribute     = kernel32.NewProc("SetConsoleTextAttribute")
+	procGetConsoleTitle             = kernel32.NewProc("GetConsoleTitleW")
+	procGetACP                      = kernel32.NewProc("GetACP")
+	procGetOEMCP                    = kernel32.NewProc("GetOEMCP")
+	procWriteConsoleOutput          = kernel32.NewProc("WriteConsoleOutputW")
+	procReadConsoleOutput           = kernel32.NewProc("ReadConsoleOutputW")
+	procReadConsoleOutputCharacter  = kernel32.NewProc("ReadConsoleOutputCharacterW")
+	procReadConsoleOutputAttribute  = kernel32.NewProc("ReadConsoleOutputAttribute")
+	procCreateConsoleScreenBuffer   = kernel32.NewProc("CreateConsoleScreenBuffer")
+	procSetConsoleActiveBuffer      = kernel32.NewProc("SetConsoleActiveScreenBuffer")
 )
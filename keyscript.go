@@ -0,0 +1,125 @@
+package cons
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// keyNames are the keys a key script names between angle brackets.
+var keyNames = map[string]KeyEventRecord{
+	"enter":     {KeyDown: 1, RepeatCount: 1, VirtualKeyCode: VkReturn, UnicodeChar: '\r'},
+	"tab":       {KeyDown: 1, RepeatCount: 1, VirtualKeyCode: VkTab, UnicodeChar: '\t'},
+	"esc":       {KeyDown: 1, RepeatCount: 1, VirtualKeyCode: VkEscape, UnicodeChar: 0x1b},
+	"backspace": {KeyDown: 1, RepeatCount: 1, VirtualKeyCode: VkBack, UnicodeChar: 0x08},
+	"space":     {KeyDown: 1, RepeatCount: 1, VirtualKeyCode: VkSpace, UnicodeChar: ' '},
+	"up":        specialKey(VkUp, 0),
+	"down":      specialKey(VkDown, 0),
+	"left":      specialKey(VkLeft, 0),
+	"right":     specialKey(VkRight, 0),
+	"home":      specialKey(VkHome, 0),
+	"end":       specialKey(VkEnd, 0),
+	"pgup":      specialKey(VkPrior, 0),
+	"pgdn":      specialKey(VkNext, 0),
+	"insert":    specialKey(VkInsert, 0),
+	"delete":    specialKey(VkDelete, 0),
+}
+
+// KeyScript converts a key script to the key events of the keys it types, to push to a VirtualInput so
+// that an interactive program can be driven without a keyboard. A script is text typed as it is, with
+// keys named between angle brackets:
+//
+//	<Enter> <Tab> <Esc> <Backspace> <Space> <Up> <Down> <Left> <Right> <Home> <End> <PgUp> <PgDn>
+//	<Insert> <Delete> <F1> to <F12>
+//
+// Names are case-insensitive and take the prefixes C- for Ctrl, A- for Alt and S- for Shift, as in <C-c>,
+// <A-f> or <C-Left>. "<<" types '<', and a '<' that does not start a known name is typed as it is.
+//
+// Parameters:
+//
+//	script: The key script, such as "yes<Enter>" or "<Down><Down><Space><Enter>".
+//
+// Returns:
+//
+//	[]Event: The key down records of the keys, in order.
+func KeyScript(script string) []Event {
+	var keys []KeyEventRecord
+	for script != "" {
+		if strings.HasPrefix(script, "<<") {
+			keys = append(keys, runeKeyEvents('<')...)
+			script = script[2:]
+			continue
+		}
+
+		if script[0] == '<' {
+			if end := strings.IndexByte(script, '>'); end > 0 {
+				if key, ok := namedKey(script[1:end]); ok {
+					keys = append(keys, key)
+					script = script[end+1:]
+					continue
+				}
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(script)
+		keys = append(keys, runeKeyEvents(r)...)
+		script = script[size:]
+	}
+
+	return keyEvents(keys)
+}
+
+// namedKey returns the key of a name of a key script, with its modifier prefixes.
+func namedKey(name string) (KeyEventRecord, bool) {
+	var state uint32
+	for len(name) > 2 && name[1] == '-' {
+		switch name[0] {
+		case 'c', 'C':
+			state |= LeftCtrlPressed
+		case 'a', 'A':
+			state |= LeftAltPressed
+		case 's', 'S':
+			state |= ShiftPressed
+		default:
+			return KeyEventRecord{}, false
+		}
+		name = name[2:]
+	}
+
+	lower := strings.ToLower(name)
+	key, ok := keyNames[lower]
+	switch {
+	case ok:
+	case len(lower) >= 2 && lower[0] == 'f':
+		n, err := strconv.Atoi(lower[1:])
+		if err != nil || n < 1 || n > 12 {
+			return KeyEventRecord{}, false
+		}
+		key = specialKey(VkF1+uint16(n-1), 0)
+	case utf8.RuneCountInString(name) == 1 && state != 0:
+		r, _ := utf8.DecodeRuneInString(name)
+		key = runeKeyEvents(r)[0]
+		if state&LeftCtrlPressed != 0 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			// Ctrl with a letter types the matching control character, as on a console.
+			key = runeKeyEvents(r & 0x1f)[0]
+		}
+	default:
+		return KeyEventRecord{}, false
+	}
+
+	key.ControlKeyState |= state
+	return key, true
+}
+
+// Type pushes the key events of a key script, see KeyScript.
+//
+// Parameters:
+//
+//	script: The key script, such as "yes<Enter>".
+//
+// Returns:
+//
+//	error: If the function successfully queues the events, it returns nil. Otherwise, it returns an error.
+func (vi *VirtualInput) Type(script string) error {
+	return vi.Push(KeyScript(script)...)
+}
@@ -0,0 +1,608 @@
+package cons
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInterrupted is returned by LineEditor.ReadLine when Ctrl+C is pressed.
+var ErrInterrupted = errors.New("cons: interrupted")
+
+// Completer returns the completions of the word before the cursor, called when Tab is pressed.
+//
+// Parameters:
+//
+//	line: The line being edited.
+//	pos: The byte offset of the cursor in line.
+//
+// Returns:
+//
+//	int: The byte offset in line where the word being completed starts; each candidate replaces line[start:pos].
+//	[]string: The candidates.
+type Completer func(line string, pos int) (start int, candidates []string)
+
+// LineEditor reads lines from the key events of a console, with the editing keys of Emacs and of the console,
+// a history browsed with the arrow keys, incremental reverse search and completion. It draws the prompt and the
+// line itself, wrapping them over as many rows as they need, so the input must not be in line mode and the
+// output must not be written to while a line is read; ReadLine switches the input mode for its duration.
+//
+// The keys are: Left, Right, Ctrl+B and Ctrl+F move by character, Ctrl+Left, Ctrl+Right, Alt+B and Alt+F by word,
+// Home, End, Ctrl+A and Ctrl+E to the ends of the line; Backspace, Delete and Ctrl+D delete a character, Ctrl+W
+// and Alt+D a word, Ctrl+U and Ctrl+K up to the ends of the line; Ctrl+Y inserts the text last deleted by those,
+// Ctrl+T swaps two characters; Up, Down, Ctrl+P and Ctrl+N browse the history and Ctrl+R searches it; Tab
+// completes; Enter accepts the line, Ctrl+D on an empty line reports io.EOF and Ctrl+C reports ErrInterrupted.
+// A key held down, reported once with its RepeatCount, is applied that many times.
+type LineEditor struct {
+	Prompt    string    // The text displayed before the line.
+	History   *History  // The history browsed and extended by ReadLine, or nil for none.
+	Completer Completer // The completion function called by Tab, or nil for none.
+
+	in   InputSource
+	out  OutputTarget
+	keys *RuneReader
+	kill []rune // The text last deleted by a kill command, inserted back by Ctrl+Y.
+}
+
+// NewLineEditor creates a line editor reading keys from a console input and drawing on its output.
+//
+// Parameters:
+//
+//	in: The input side of the console.
+//	out: The output side of the console.
+//
+// Returns:
+//
+//	*LineEditor: The new line editor, without history or completion.
+func NewLineEditor(in InputSource, out OutputTarget) *LineEditor {
+	return &LineEditor{in: in, out: out, keys: NewRuneReader(in)}
+}
+
+// ReadLine displays the prompt at the cursor and reads a line. Once the line is accepted, the cursor
+// moves to the start of the next row, and a line that is not empty is added to the history.
+//
+// Returns:
+//
+//	string: The line, without line break.
+//	error: If a line is accepted, it returns nil or the error of adding it to the history file. Otherwise,
+//	it returns io.EOF for Ctrl+D on an empty line, ErrInterrupted for Ctrl+C or the error reading the keys.
+func (le *LineEditor) ReadLine() (string, error) {
+	// Ctrl+C must arrive as a key rather than as a signal.
+	restore, err := rawKeyMode(le.in)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	var scrbufinfo ScreenBufferInfo
+	if err := le.out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return "", err
+	}
+
+	st := &lineState{le: le, prompt: le.Prompt, origin: scrbufinfo.CursorPosition, attr: scrbufinfo.Attributes}
+	if le.History != nil {
+		st.entries = le.History.Entries()
+	}
+	st.history = len(st.entries)
+
+	if err := st.draw(); err != nil {
+		return "", err
+	}
+
+	for {
+		key, r, err := le.keys.ReadKey()
+		if err != nil {
+			return string(st.line), st.abort(err)
+		}
+
+		// A key held down arrives as one record with the number of times it repeated.
+		for n := max(key.RepeatCount, 1); n > 0; n-- {
+			if done, err := st.apply(key, r); done || err != nil {
+				return string(st.line), err
+			}
+		}
+	}
+}
+
+// lineState is the state of a line being read.
+type lineState struct {
+	le      *LineEditor
+	prompt  string
+	line    []rune
+	pos     int    // The index in line of the character under the cursor.
+	origin  Coord  // The position of the first cell of the prompt.
+	attr    uint16 // The attributes of the prompt and the line.
+	drawn   int16  // The number of rows drawn.
+	entries []string
+	history int    // The index in entries of the line shown, len(entries) for the line being edited.
+	saved   []rune // The line being edited, kept while the history is browsed.
+	tabbed  bool   // Indicates whether the previous key was a Tab that did not complete anything.
+
+	searching bool   // Indicates whether a reverse search is in progress.
+	query     []rune // The text searched for.
+	match     int    // The index in entries of the match, or -1 for none.
+	before    []rune // The line before the search, restored when it is cancelled.
+	beforePos int
+}
+
+// apply applies a key to the reverse search in progress or to the line, and draws the line again,
+// reporting whether the line is done.
+func (st *lineState) apply(key KeyEventRecord, r rune) (bool, error) {
+	if st.searching {
+		var err error
+		if key, r, err = st.search(key, r); err != nil || key.KeyDown == 0 {
+			return false, err
+		}
+	}
+
+	done, err := st.handle(key, r)
+	if !done && err == nil {
+		err = st.draw()
+	}
+
+	return done, err
+}
+
+// handle applies a key, reporting whether the line is done.
+func (st *lineState) handle(key KeyEventRecord, r rune) (bool, error) {
+	ctrl := key.ControlKeyState&(LeftCtrlPressed|RightCtrlPressed) != 0
+	alt := key.ControlKeyState&(LeftAltPressed|RightAltPressed) != 0 && !ctrl
+	tabbed := st.tabbed
+	st.tabbed = false
+
+	switch vk := key.VirtualKeyCode; {
+	case vk == VkReturn || r == '\r' || r == '\n':
+		return true, st.accept()
+	case r == 0x03:
+		return true, st.abort(ErrInterrupted)
+	case r == 0x04 && len(st.line) == 0:
+		return true, st.abort(io.EOF)
+	case r == 0x04 || vk == VkDelete && r == 0:
+		st.delete(st.pos, st.nextBoundary())
+	case r == 0x08 || r == 0x7f:
+		st.delete(st.prevBoundary(), st.pos)
+	case r == 0x01 || vk == VkHome && r == 0:
+		st.pos = 0
+	case r == 0x05 || vk == VkEnd && r == 0:
+		st.pos = len(st.line)
+	case vk == VkLeft && r == 0 && ctrl || alt && (r == 'b' || r == 'B'):
+		st.pos = st.wordStart()
+	case vk == VkRight && r == 0 && ctrl || alt && (r == 'f' || r == 'F'):
+		st.pos = st.wordEnd()
+	case r == 0x02 || vk == VkLeft && r == 0:
+		st.pos = st.prevBoundary()
+	case r == 0x06 || vk == VkRight && r == 0:
+		st.pos = st.nextBoundary()
+	case r == 0x0b:
+		st.killText(st.pos, len(st.line))
+	case r == 0x15:
+		st.killText(0, st.pos)
+	case r == 0x17:
+		st.killText(st.wordStart(), st.pos)
+	case alt && (r == 'd' || r == 'D'):
+		st.killText(st.pos, st.wordEnd())
+	case r == 0x19:
+		st.insert(st.le.kill...)
+	case r == 0x14:
+		st.transpose()
+	case r == 0x10 || vk == VkUp && r == 0:
+		st.browse(-1)
+	case r == 0x0e || vk == VkDown && r == 0:
+		st.browse(1)
+	case r == 0x12:
+		st.startSearch()
+	case r == '\t':
+		if st.le.Completer != nil {
+			return false, st.complete(tabbed)
+		}
+	case r >= ' ' && !alt:
+		st.insert(r)
+	}
+
+	return false, nil
+}
+
+// accept ends the line and adds it to the history.
+func (st *lineState) accept() error {
+	if err := st.finish(); err != nil {
+		return err
+	}
+
+	if st.le.History != nil {
+		return st.le.History.Add(string(st.line))
+	}
+
+	return nil
+}
+
+// abort ends the line without accepting it, returning err unless the line cannot be finished.
+func (st *lineState) abort(err error) error {
+	if ferr := st.finish(); ferr != nil {
+		return ferr
+	}
+
+	return err
+}
+
+// finish draws the whole line and moves the cursor to the start of the row below it.
+func (st *lineState) finish() error {
+	st.prompt, st.searching = st.le.Prompt, false
+	st.pos = len(st.line)
+	if err := st.draw(); err != nil {
+		return err
+	}
+
+	y, err := st.row(st.origin.Y + st.drawn)
+	if err != nil {
+		return err
+	}
+
+	return st.le.out.SetCursorPosition(Coord{X: 0, Y: y})
+}
+
+func (st *lineState) insert(runes ...rune) {
+	st.line = append(st.line[:st.pos], append(append([]rune(nil), runes...), st.line[st.pos:]...)...)
+	st.pos += len(runes)
+}
+
+func (st *lineState) delete(from, to int) {
+	st.line = append(st.line[:from], st.line[to:]...)
+	st.pos = from
+}
+
+func (st *lineState) killText(from, to int) {
+	if from < to {
+		st.le.kill = append([]rune(nil), st.line[from:to]...)
+		st.delete(from, to)
+	}
+}
+
+// transpose swaps the characters before and at the cursor, or the two before it at the end of the line.
+func (st *lineState) transpose() {
+	if len(st.line) < 2 || st.pos == 0 {
+		return
+	}
+
+	i := min(st.pos, len(st.line)-1)
+	st.line[i-1], st.line[i] = st.line[i], st.line[i-1]
+	st.pos = i + 1
+}
+
+// boundaries returns the indices in line where grapheme clusters start, followed by the length of line.
+func (st *lineState) boundaries() []int {
+	bounds := []int{0}
+	for rest, i := string(st.line), 0; rest != ""; {
+		var cluster string
+		cluster, rest, _ = NextGrapheme(rest)
+		i += utf8.RuneCountInString(cluster)
+		bounds = append(bounds, i)
+	}
+
+	return bounds
+}
+
+func (st *lineState) prevBoundary() int {
+	prev := 0
+	for _, b := range st.boundaries() {
+		if b >= st.pos {
+			break
+		}
+		prev = b
+	}
+
+	return prev
+}
+
+func (st *lineState) nextBoundary() int {
+	for _, b := range st.boundaries() {
+		if b > st.pos {
+			return b
+		}
+	}
+
+	return len(st.line)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// wordStart returns the start of the word before the cursor.
+func (st *lineState) wordStart() int {
+	i := st.pos
+	for i > 0 && !isWordRune(st.line[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(st.line[i-1]) {
+		i--
+	}
+
+	return i
+}
+
+// wordEnd returns the end of the word after the cursor.
+func (st *lineState) wordEnd() int {
+	i := st.pos
+	for i < len(st.line) && !isWordRune(st.line[i]) {
+		i++
+	}
+	for i < len(st.line) && isWordRune(st.line[i]) {
+		i++
+	}
+
+	return i
+}
+
+// browse shows the previous entry of the history for a negative step, the next one otherwise.
+func (st *lineState) browse(step int) {
+	next := st.history + step
+	if next < 0 || next > len(st.entries) {
+		return
+	}
+
+	if st.history == len(st.entries) {
+		st.saved = append([]rune(nil), st.line...)
+	}
+
+	st.history = next
+	if next == len(st.entries) {
+		st.line = st.saved
+	} else {
+		st.line = []rune(st.entries[next])
+	}
+	st.pos = len(st.line)
+}
+
+func (st *lineState) startSearch() {
+	st.searching, st.query, st.match = true, nil, -1
+	st.before, st.beforePos = append([]rune(nil), st.line...), st.pos
+	st.find(st.history - 1)
+}
+
+// search applies a key during a reverse search. A key that ends the search is returned to be handled
+// as an editing key; otherwise the returned key has KeyDown 0.
+func (st *lineState) search(key KeyEventRecord, r rune) (KeyEventRecord, rune, error) {
+	switch {
+	case r == 0x12:
+		if st.match > 0 {
+			st.find(st.match - 1)
+		}
+	case r == 0x07 || r == 0x1b:
+		st.searching, st.prompt = false, st.le.Prompt
+		st.line, st.pos = st.before, st.beforePos
+	case r == 0x08 || r == 0x7f:
+		if len(st.query) > 0 {
+			st.query = st.query[:len(st.query)-1]
+			st.find(st.history - 1)
+		}
+	case r >= ' ' && key.ControlKeyState&(LeftAltPressed|RightAltPressed|LeftCtrlPressed|RightCtrlPressed) == 0:
+		st.query = append(st.query, r)
+		st.find(max(st.match, st.history-1))
+	default:
+		st.searching, st.prompt = false, st.le.Prompt
+		if st.match >= 0 {
+			st.history = st.match
+		}
+		return key, r, nil
+	}
+
+	return KeyEventRecord{}, 0, st.draw()
+}
+
+// find shows the newest entry containing the query, starting at the entry from.
+func (st *lineState) find(from int) {
+	query := string(st.query)
+	if query == "" {
+		st.match, st.line, st.pos = -1, st.before, st.beforePos
+		st.prompt = "(reverse-i-search)`': "
+		return
+	}
+
+	for i := min(from, len(st.entries)-1); i >= 0; i-- {
+		if at := strings.LastIndex(st.entries[i], query); at >= 0 {
+			st.match = i
+			st.line = []rune(st.entries[i])
+			st.pos = utf8.RuneCountInString(st.entries[i][:at])
+			st.prompt = "(reverse-i-search)`" + query + "': "
+			return
+		}
+	}
+
+	st.prompt = "(failed reverse-i-search)`" + query + "': "
+}
+
+// complete replaces the word before the cursor with its only completion or with the prefix shared by all
+// of them, and lists them when Tab is pressed twice without anything to complete.
+func (st *lineState) complete(tabbed bool) error {
+	line := string(st.line)
+	head := string(st.line[:st.pos])
+	start, candidates := st.le.Completer(line, len(head))
+	if len(candidates) == 0 || start < 0 || start > len(head) {
+		return nil
+	}
+
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+
+	switch word := head[start:]; {
+	case len(candidates) == 1:
+		prefix = candidates[0]
+	case len(prefix) > len(word):
+	case tabbed:
+		return st.list(candidates)
+	default:
+		st.tabbed = true
+		return nil
+	}
+
+	st.line = []rune(head[:start] + prefix + line[len(head):])
+	st.pos = utf8.RuneCountInString(head[:start] + prefix)
+	return nil
+}
+
+// list writes the candidates in columns below the line and draws the line again below them.
+func (st *lineState) list(candidates []string) error {
+	var scrbufinfo ScreenBufferInfo
+	if err := st.le.out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return err
+	}
+
+	colWidth := 0
+	for _, c := range candidates {
+		colWidth = max(colWidth, StringWidth(c)+2)
+	}
+	cols := max(1, int(scrbufinfo.Size.X)/colWidth)
+
+	y := st.origin.Y + st.drawn
+	for i := 0; i < len(candidates); i += cols {
+		var sb strings.Builder
+		for _, c := range candidates[i:min(i+cols, len(candidates))] {
+			sb.WriteString(c + strings.Repeat(" ", colWidth-StringWidth(c)))
+		}
+
+		var err error
+		if y, err = st.row(y); err != nil {
+			return err
+		}
+
+		if err := writeCells(st.le.out, st.padRow(fitCells(sb.String(), st.attr, int(scrbufinfo.Size.X)), 0, scrbufinfo.Size.X), Coord{Y: y}); err != nil {
+			return err
+		}
+		y++
+	}
+
+	st.origin, st.drawn = Coord{Y: y}, 0
+	return nil
+}
+
+// row makes row y of the screen buffer exist and returns it, moving the origin up with the scrolled rows.
+func (st *lineState) row(y int16) (int16, error) {
+	n, err := scrollToRow(st.le.out, y, st.attr)
+	st.origin.Y -= n
+	return y - n, err
+}
+
+// scrollToRow scrolls the screen buffer up when row y is below its last row, so that y becomes the last row,
+// filling the rows scrolled in with blanks in attr. It returns the number of rows scrolled.
+func scrollToRow(out OutputTarget, y int16, attr uint16) (int16, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return 0, err
+	}
+
+	size := scrbufinfo.Size
+	if y < size.Y {
+		return 0, nil
+	}
+
+	n := y - size.Y + 1
+	scroll := SmallRect{Top: n, Right: size.X - 1, Bottom: size.Y - 1}
+	if err := out.ScrollScreenBuffer(&scroll, nil, Coord{}, CharInfo{UnicodeChar: ' ', Attributes: attr}); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// padRow fills a row of cells starting at column x with blanks up to the width of the screen buffer.
+func (st *lineState) padRow(cells []CharInfo, x, width int16) []CharInfo {
+	return padCells(cells, int(width-x), st.attr)
+}
+
+// draw lays out the prompt and the line from the origin, wrapping at the end of each row, writes the rows,
+// blanks the rows left over from the previous draw and places the cursor.
+func (st *lineState) draw() error {
+	var scrbufinfo ScreenBufferInfo
+	if err := st.le.out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return err
+	}
+
+	var (
+		width  = scrbufinfo.Size.X
+		rows   = [][]CharInfo{nil}
+		x      = st.origin.X
+		cursor = Coord{X: -1} // The position of the cursor, relative to the origin row.
+	)
+
+	left := func(row int) int16 {
+		if row == 0 {
+			return st.origin.X
+		}
+		return 0
+	}
+
+	// put appends a grapheme cluster to the rows, moving to a new row when it does not fit.
+	put := func(cluster string, w int) {
+		if x+int16(w) > width {
+			last := len(rows) - 1
+			rows[last] = st.padRow(rows[last], left(last), width)
+			rows, x = append(rows, nil), 0
+		}
+
+		r, _ := utf8.DecodeRuneInString(cluster)
+		rows[len(rows)-1] = append(rows[len(rows)-1], layoutRune(r, w, st.attr)...)
+		x += int16(w)
+	}
+
+	for rest := st.prompt; rest != ""; {
+		cluster, next, w := NextGrapheme(rest)
+		put(cluster, w)
+		rest = next
+	}
+
+	i := 0
+	for rest := string(st.line); rest != ""; {
+		cluster, next, w := NextGrapheme(rest)
+		if i >= st.pos && cursor.X < 0 {
+			// The cursor goes on the first cell of the cluster, which wraps with it.
+			if x+int16(w) > width {
+				cursor = Coord{Y: int16(len(rows))}
+			} else {
+				cursor = Coord{X: x, Y: int16(len(rows) - 1)}
+			}
+		}
+
+		put(cluster, w)
+		i += utf8.RuneCountInString(cluster)
+		rest = next
+	}
+
+	if cursor.X < 0 {
+		if x >= width {
+			rows, x = append(rows, nil), 0
+		}
+		cursor = Coord{X: x, Y: int16(len(rows) - 1)}
+	}
+
+	// Scroll the buffer when the rows go past its end.
+	if _, err := st.row(st.origin.Y + int16(len(rows)) - 1); err != nil {
+		return err
+	}
+
+	for y := 0; y < max(len(rows), int(st.drawn)); y++ {
+		var cells []CharInfo
+		if y < len(rows) {
+			cells = rows[y]
+		}
+
+		if st.origin.Y+int16(y) < 0 {
+			continue
+		}
+
+		if err := writeCells(st.le.out, st.padRow(cells, left(y), width), Coord{X: left(y), Y: st.origin.Y + int16(y)}); err != nil {
+			return err
+		}
+	}
+	st.drawn = int16(len(rows))
+
+	return st.le.out.SetCursorPosition(Coord{X: cursor.X, Y: st.origin.Y + cursor.Y})
+}
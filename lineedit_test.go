@@ -0,0 +1,275 @@
+package cons
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readLine reads a line typed with a key script in a line editor with the prompt "> ".
+func readLine(t *testing.T, vc *VirtualConsole, configure func(le *LineEditor)) (string, error) {
+	t.Helper()
+
+	le := NewLineEditor(vc.Input(), vc.Output())
+	le.Prompt = "> "
+	if configure != nil {
+		configure(le)
+	}
+
+	return le.ReadLine()
+}
+
+func TestLineEditorKeys(t *testing.T) {
+	tests := []struct {
+		script string
+		want   string
+		err    error
+	}{
+		{"hello<Enter>", "hello", nil},
+		{"world<C-a>hello <Enter>", "hello world", nil},
+		{"abc<Home><End>d<Enter>", "abcd", nil},
+		{"bc<C-a>a<C-e>d<Enter>", "abcd", nil},
+		{"ac<Left>b<Right>d<Enter>", "abcd", nil},
+		{"ac<C-b>b<C-f>d<Enter>", "abcd", nil},
+		{"foo bar<C-w><Enter>", "foo ", nil},
+		{"foo bar<C-w><C-w><Enter>", "", nil},
+		{"foo bar<Left><Left><Left><C-u><Enter>", "bar", nil},
+		{"foo bar<C-a><C-f><C-k><Enter>", "f", nil},
+		{"foo bar<C-w><C-a><C-y> <Enter>", "bar foo ", nil},
+		{"foo bar<C-a><A-d><C-e><C-y><Enter>", " barfoo", nil},
+		{"foo bar baz<C-Left><C-Left><A-f>!<Enter>", "foo bar! baz", nil},
+		{"ab<C-t><Enter>", "ba", nil},
+		{"abc<Left><C-t><Enter>", "acb", nil},
+		{"abc<Backspace><Enter>", "ab", nil},
+		{"abc<Home><Delete><Enter>", "bc", nil},
+		{"ab<Home><C-d><Enter>", "b", nil},
+		{"éx<Left><Backspace><Enter>", "x", nil},
+		{"<C-d>", "", io.EOF},
+		{"abc<C-c>", "abc", ErrInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			vc := typed(t, tt.script)
+
+			got, err := readLine(t, vc, nil)
+			if got != tt.want || err != tt.err {
+				t.Fatalf("ReadLine = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+			if line := vc.Line(0); line != strings.TrimRight("> "+got, " ") {
+				t.Errorf("line = %q, want the prompt and the line", line)
+			}
+
+			var scrbufinfo ScreenBufferInfo
+			vc.GetScreenBufferInfo(&scrbufinfo)
+			if scrbufinfo.CursorPosition != (Coord{Y: 1}) {
+				t.Errorf("cursor = %+v, want the start of the next row", scrbufinfo.CursorPosition)
+			}
+		})
+	}
+}
+
+func TestLineEditorRepeatCount(t *testing.T) {
+	vc := NewVirtualConsole(40, 5)
+	a := runeKeyEvents('a')[0]
+	a.RepeatCount = 3
+	back := keyNames["backspace"]
+	back.RepeatCount = 2
+	vc.VirtualInput().Push(a, back, keyNames["enter"])
+
+	if got, err := readLine(t, vc, nil); got != "a" || err != nil {
+		t.Errorf("ReadLine = %q, %v, want \"a\"", got, err)
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	tests := []struct {
+		script string
+		want   string
+	}{
+		{"<Up><Enter>", "go test"},
+		{"<Up><Up><Enter>", "git status"},
+		{"<Up><Up><Up><Up><Enter>", "git commit"},
+		{"<Up><Up><Down><Enter>", "go test"},
+		{"<C-p><C-p><C-n><Enter>", "go test"},
+		{"draft<Up><Down><Enter>", "draft"},
+		{"<Up> -v<Enter>", "go test -v"},
+		{"<C-r>git<Enter>", "git status"},
+		{"<C-r>git<C-r><Enter>", "git commit"},
+		{"<C-r>test<End>!<Enter>", "go test!"},
+		{"<C-r>gi<Backspace>o<Enter>", "go test"},
+		{"x<C-r>zz<Esc>y<Enter>", "xy"},
+		{"<C-r>commit<Up><Enter>", "git commit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			h := NewHistory(0)
+			for _, line := range []string{"git commit", "git status", "go test"} {
+				h.Add(line)
+			}
+
+			vc := typed(t, tt.script)
+			got, err := readLine(t, vc, func(le *LineEditor) { le.History = h })
+			if got != tt.want || err != nil {
+				t.Fatalf("ReadLine = %q, %v, want %q", got, err, tt.want)
+			}
+
+			entries := h.Entries()
+			if last := entries[len(entries)-1]; last != tt.want {
+				t.Errorf("newest entry = %q, want %q", last, tt.want)
+			}
+			if tt.want == "go test" && len(entries) != 3 {
+				t.Errorf("entries = %q, want the repeated line added once", entries)
+			}
+		})
+	}
+}
+
+func TestLineEditorCompletion(t *testing.T) {
+	completer := func(line string, pos int) (int, []string) {
+		start := strings.LastIndexByte(line[:pos], ' ') + 1
+		var candidates []string
+		for _, c := range []string{"status", "stash", "commit"} {
+			if strings.HasPrefix(c, line[start:pos]) {
+				candidates = append(candidates, c)
+			}
+		}
+		return start, candidates
+	}
+
+	tests := []struct {
+		script string
+		want   string
+		lines  []string
+	}{
+		{"git co<Tab> -a<Enter>", "git commit -a", []string{"> git commit -a"}},
+		{"git st<Tab><Enter>", "git sta", []string{"> git sta"}},
+		{"git st<Tab>t<Tab><Enter>", "git status", []string{"> git status"}},
+		{"git x<Tab><Enter>", "git x", []string{"> git x"}},
+		{"git sta<Tab><Tab><Enter>", "git sta", []string{"> git sta", "status  stash", "> git sta"}},
+		{"git <Tab><Tab>c<Tab><Enter>", "git commit", []string{"> git", "status  stash   commit", "> git commit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			vc := typed(t, tt.script)
+
+			got, err := readLine(t, vc, func(le *LineEditor) { le.Completer = completer })
+			if got != tt.want || err != nil {
+				t.Fatalf("ReadLine = %q, %v, want %q", got, err, tt.want)
+			}
+			for y, want := range tt.lines {
+				if line := vc.Line(int16(y)); line != want {
+					t.Errorf("line %d = %q, want %q", y, line, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLineEditorWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		lines []string
+	}{
+		{"narrow", strings.Repeat("a", 45), []string{"> " + strings.Repeat("a", 38), strings.Repeat("a", 7)}},
+		{"wide in the last column", strings.Repeat("a", 37) + "日本", []string{"> " + strings.Repeat("a", 37), "日本"}},
+		{"exactly one row", strings.Repeat("a", 38), []string{"> " + strings.Repeat("a", 38), ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := typed(t, tt.text+"<Home><End><Enter>")
+
+			got, err := readLine(t, vc, nil)
+			if got != tt.text || err != nil {
+				t.Fatalf("ReadLine = %q, %v, want %q", got, err, tt.text)
+			}
+			for y, want := range tt.lines {
+				if line := vc.Line(int16(y)); line != want {
+					t.Errorf("line %d = %q, want %q", y, line, want)
+				}
+			}
+		})
+	}
+
+	// A line past the last row of the buffer scrolls it up.
+	vc := NewVirtualConsole(10, 3)
+	vc.SetCursorPosition(Coord{Y: 2})
+	vc.VirtualInput().Type(strings.Repeat("b", 12) + "<Enter>")
+	if got, err := readLine(t, vc, nil); err != nil || got != strings.Repeat("b", 12) {
+		t.Fatalf("ReadLine = %q, %v", got, err)
+	}
+	for y, want := range []string{"> bbbbbbbb", "bbbb", ""} {
+		if line := vc.Line(int16(y)); line != want {
+			t.Errorf("scrolled line %d = %q, want %q", y, line, want)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(3)
+	for _, line := range []string{"a", "b", "b", "", "  ", "c\nd", "e"} {
+		if err := h.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := h.Entries(), []string{"b", "c d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+	if h.Len() != 3 {
+		t.Errorf("Len = %d, want 3", h.Len())
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	// A missing file is created by the first Add.
+	h, err := LoadHistory(path, 3)
+	if err != nil || h.Len() != 0 {
+		t.Fatalf("LoadHistory of a missing file = %v, %v", h, err)
+	}
+	if err := h.Add("first"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "first\n" {
+		t.Errorf("file = %q, want the line added", data)
+	}
+
+	if err := os.WriteFile(path, []byte("one\r\ntwo\n\nthree\nfour\nfive\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err = LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h.Entries(), []string{"three", "four", "five"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "three\nfour\nfive\n" {
+		t.Errorf("file = %q, want the newest entries", data)
+	}
+
+	if err := h.Add("six"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h.Entries(), []string{"four", "five", "six"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries after Add = %q, want %q", got, want)
+	}
+
+	h, err = LoadHistory(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h.Entries(), []string{"three", "four", "five", "six"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries without limit = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,440 @@
+package cons
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Span is a run of text sharing one style, as produced by ParseMarkup.
+type Span struct {
+	Text  string
+	Style Style
+}
+
+// ParseMarkup splits text written in the markup of StyledWriter into styled spans.
+//
+// A tag in square brackets applies a style to the text up to the matching closing tag, [/],
+// and tags nest, each one adding to the style of the enclosing ones:
+//
+//	[bold red]error:[/] file [underline]main.go[/] not found
+//	[white on darkblue] status [bold]ok[/] [/]
+//
+// A tag is a space separated list of the attributes bold, italic, underline and reverse, a foreground color,
+// and a background color following the word "on". A color is the name of one of the 16 console colors such as
+// red or darkred, default, a hexadecimal #rrggbb, rgb(r,g,b) or color(n) for an entry of the xterm palette.
+// [/name] closes the innermost tag like [/] does. Brackets that do not hold a valid tag, such as [b] or
+// [red on], are kept as text, and \[ is a literal bracket.
+//
+// Parameters:
+//
+//	markup: The text with markup.
+//
+// Returns:
+//
+//	[]Span: The styled spans, in order; adjacent text with the same style is merged.
+func ParseMarkup(markup string) []Span {
+	var (
+		spans []Span
+		stack = []Style{{}}
+		text  strings.Builder
+	)
+
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+
+		style := stack[len(stack)-1]
+		if n := len(spans); n > 0 && spans[n-1].Style == style {
+			spans[n-1].Text += text.String()
+		} else {
+			spans = append(spans, Span{Text: text.String(), Style: style})
+		}
+		text.Reset()
+	}
+
+	for len(markup) > 0 {
+		switch {
+		case strings.HasPrefix(markup, `\[`):
+			text.WriteByte('[')
+			markup = markup[2:]
+			continue
+		case markup[0] != '[':
+			i := strings.IndexAny(markup, `[\`)
+			if i < 0 {
+				i = len(markup)
+			} else if i == 0 {
+				i = 1
+			}
+			text.WriteString(markup[:i])
+			markup = markup[i:]
+			continue
+		}
+
+		end := strings.IndexByte(markup, ']')
+		if end < 0 {
+			text.WriteString(markup)
+			break
+		}
+
+		tag := markup[1:end]
+		switch {
+		case tag == "/" || strings.HasPrefix(tag, "/") && isTag(tag[1:]):
+			flush()
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			style, ok := parseTag(stack[len(stack)-1], tag)
+			if !ok {
+				text.WriteByte('[')
+				markup = markup[1:]
+				continue
+			}
+
+			flush()
+			stack = append(stack, style)
+		}
+
+		markup = markup[end+1:]
+	}
+
+	flush()
+	return spans
+}
+
+// EscapeMarkup escapes the brackets of text, so it is printed as is by a StyledWriter.
+func EscapeMarkup(text string) string {
+	return strings.ReplaceAll(text, "[", `\[`)
+}
+
+// StripMarkup returns the text of markup without its tags.
+func StripMarkup(markup string) string {
+	var sb strings.Builder
+	for _, span := range ParseMarkup(markup) {
+		sb.WriteString(span.Text)
+	}
+
+	return sb.String()
+}
+
+// isTag reports whether tag is a valid tag, for the name of a closing tag.
+func isTag(tag string) bool {
+	_, ok := parseTag(Style{}, tag)
+	return ok
+}
+
+// parseTag applies the words of a tag to the style of the enclosing text.
+func parseTag(style Style, tag string) (Style, bool) {
+	words := strings.Fields(tag)
+	if len(words) == 0 {
+		return style, false
+	}
+
+	// background is set by "on", and color once the color following it is parsed.
+	background, color := false, false
+	for _, word := range words {
+		word = strings.ToLower(word)
+		switch word {
+		case "bold":
+			style.Bold = true
+		case "italic":
+			style.Italic = true
+		case "underline":
+			style.Underline = true
+		case "reverse":
+			style.Reverse = true
+		case "on":
+			if background {
+				return style, false
+			}
+			background = true
+		default:
+			c, ok := parseColor(word)
+			if !ok {
+				return style, false
+			}
+
+			if background {
+				style.Background, color = c, true
+			} else {
+				style.Foreground = c
+			}
+		}
+	}
+
+	return style, background == color
+}
+
+// markupColors are the color names of the markup.
+var markupColors = map[string]Color{
+	"default": {}, "black": Black, "darkblue": DarkBlue, "darkgreen": DarkGreen, "darkcyan": DarkCyan,
+	"darkred": DarkRed, "darkmagenta": DarkMagenta, "darkyellow": DarkYellow, "gray": Gray, "grey": Gray,
+	"darkgray": DarkGray, "darkgrey": DarkGray, "blue": Blue, "green": Green, "cyan": Cyan, "red": Red,
+	"magenta": Magenta, "yellow": Yellow, "white": White,
+}
+
+// parseColor parses a color of the markup.
+func parseColor(word string) (Color, bool) {
+	if color, ok := markupColors[strings.ReplaceAll(word, "_", "")]; ok {
+		return color, true
+	}
+
+	if hex, ok := strings.CutPrefix(word, "#"); ok && len(hex) == 6 {
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return Color{}, false
+		}
+		return RGBColor(uint8(v>>16), uint8(v>>8), uint8(v)), true
+	}
+
+	if args, ok := strings.CutPrefix(word, "color("); ok {
+		n, err := strconv.ParseUint(strings.TrimSuffix(args, ")"), 10, 8)
+		if err != nil || !strings.HasSuffix(args, ")") {
+			return Color{}, false
+		}
+		return PaletteColor(uint8(n)), true
+	}
+
+	if args, ok := strings.CutPrefix(word, "rgb("); ok && strings.HasSuffix(args, ")") {
+		parts := strings.Split(strings.TrimSuffix(args, ")"), ",")
+		if len(parts) != 3 {
+			return Color{}, false
+		}
+
+		var rgb [3]uint8
+		for i, part := range parts {
+			v, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+			if err != nil {
+				return Color{}, false
+			}
+			rgb[i] = uint8(v)
+		}
+		return RGBColor(rgb[0], rgb[1], rgb[2]), true
+	}
+
+	return Color{}, false
+}
+
+// StyledWriter renders the markup described by ParseMarkup. Depending on how it was created,
+// it renders the styles as SGR sequences, as cell attributes of a console, or not at all.
+type StyledWriter struct {
+	w       io.Writer    // The writer of SGR sequences and plain text.
+	out     OutputTarget // The console written to with WriteCharacter and WriteAttribute, if any.
+	profile ColorProfile // The colors w can display; ProfileNoColor writes plain text.
+}
+
+// NewStyledWriter creates a writer rendering markup as SGR sequences, downsampling colors to the profile.
+// With ProfileNoColor, the tags are dropped and plain text is written.
+//
+// Parameters:
+//
+//	w: The destination, such as os.Stdout on a VT terminal.
+//	profile: The colors the destination can display.
+//
+// Returns:
+//
+//	*StyledWriter: The new writer.
+func NewStyledWriter(w io.Writer, profile ColorProfile) *StyledWriter {
+	return &StyledWriter{w: w, profile: profile}
+}
+
+// NewConsoleStyledWriter creates a writer rendering markup directly into the cells of a console at its cursor,
+// with WriteCharacter and WriteAttribute, for consoles that do not process VT sequences. The text wraps at the
+// edge of the screen buffer, scrolls it at the bottom, and moves the cursor like written text would.
+// The default colors are the current text attributes of the console.
+//
+// Parameters:
+//
+//	out: The output side of the console.
+//
+// Returns:
+//
+//	*StyledWriter: The new writer.
+func NewConsoleStyledWriter(out OutputTarget) *StyledWriter {
+	return &StyledWriter{out: out}
+}
+
+// EnvColorProfile guesses the color profile of the terminal from the environment: NO_COLOR disables colors,
+// COLORTERM=truecolor and Windows Terminal select 24-bit colors, and a TERM ending in 256color selects the
+// xterm palette. Otherwise the 16 colors are assumed, or none with TERM=dumb.
+//
+// Returns:
+//
+//	ColorProfile: The guessed profile.
+func EnvColorProfile() ColorProfile {
+	term := os.Getenv("TERM")
+	switch colorterm := os.Getenv("COLORTERM"); {
+	case os.Getenv("NO_COLOR") != "", term == "dumb":
+		return ProfileNoColor
+	case colorterm == "truecolor" || colorterm == "24bit", os.Getenv("WT_SESSION") != "":
+		return ProfileTrueColor
+	case strings.HasSuffix(term, "256color"):
+		return ProfileANSI256
+	}
+
+	return ProfileANSI16
+}
+
+// Write renders p as markup. Markup split across calls is not supported: every call must hold whole tags.
+func (sw *StyledWriter) Write(p []byte) (int, error) {
+	if err := sw.Print(string(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Print renders markup.
+//
+// Parameters:
+//
+//	markup: The text with markup.
+//
+// Returns:
+//
+//	error: If the function successfully writes the text, it returns nil. Otherwise, it returns an error.
+func (sw *StyledWriter) Print(markup string) error {
+	spans := ParseMarkup(markup)
+	if sw.out != nil {
+		return sw.printConsole(spans)
+	}
+
+	var sb strings.Builder
+	styled := false
+	for _, span := range spans {
+		if sw.profile != ProfileNoColor && (span.Style != Style{} || styled) {
+			sb.WriteString(span.Style.SGR(sw.profile))
+			styled = span.Style != Style{}
+		}
+		sb.WriteString(span.Text)
+	}
+
+	if styled {
+		sb.WriteString("\x1b[0m")
+	}
+
+	_, err := io.WriteString(sw.w, sb.String())
+	return err
+}
+
+// Printf formats according to a format specifier and renders the result as markup.
+// Arguments holding untrusted text should be escaped with EscapeMarkup.
+func (sw *StyledWriter) Printf(format string, a ...any) error {
+	return sw.Print(fmt.Sprintf(format, a...))
+}
+
+// printConsole writes spans into the cells of the console, starting at its cursor.
+func (sw *StyledWriter) printConsole(spans []Span) error {
+	var scrbufinfo ScreenBufferInfo
+	if err := sw.out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return err
+	}
+
+	var (
+		size = scrbufinfo.Size
+		pos  = scrbufinfo.CursorPosition
+		base = scrbufinfo.Attributes
+		row  []CharInfo // The cells written on the current row since start.
+		from = pos      // The position of the first cell of row.
+	)
+
+	flush := func() error {
+		if err := writeCells(sw.out, row, from); err != nil {
+			return err
+		}
+
+		row, from = row[:0], pos
+		return nil
+	}
+
+	newline := func() error {
+		if err := flush(); err != nil {
+			return err
+		}
+
+		pos.X = 0
+		if pos.Y < size.Y-1 {
+			pos.Y++
+		} else {
+			scroll := SmallRect{Top: 1, Right: size.X - 1, Bottom: size.Y - 1}
+			if err := sw.out.ScrollScreenBuffer(&scroll, nil, Coord{}, CharInfo{UnicodeChar: ' ', Attributes: base}); err != nil {
+				return err
+			}
+		}
+
+		from = pos
+		return nil
+	}
+
+	for _, span := range spans {
+		attr := consoleAttributes(span.Style, base)
+		for rest := span.Text; rest != ""; {
+			var (
+				cluster string
+				width   int
+				err     error
+			)
+
+			cluster, rest, width = NextGrapheme(rest)
+			switch cluster {
+			case "\n", "\r\n":
+				err = newline()
+			case "\r":
+				if err = flush(); err == nil {
+					pos.X, from = 0, Coord{Y: pos.Y}
+				}
+			case "\t":
+				for n := 8 - pos.X%8; n > 0 && pos.X < size.X; n-- {
+					row = append(row, CharInfo{UnicodeChar: ' ', Attributes: attr})
+					pos.X++
+				}
+			default:
+				// A wide character does not fit in the last column and moves to the next row.
+				if width == 2 && pos.X == size.X-1 {
+					row = append(row, CharInfo{UnicodeChar: ' ', Attributes: attr})
+					if err = newline(); err != nil {
+						return err
+					}
+				}
+
+				r, _ := utf8.DecodeRuneInString(cluster)
+				cells := layoutRune(r, width, attr)
+				row = append(row, cells...)
+				pos.X += int16(len(cells))
+			}
+
+			if err == nil && pos.X >= size.X {
+				err = newline()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return sw.out.SetCursorPosition(pos)
+}
+
+// consoleAttributes converts a style to console attributes, taking the default colors from base.
+func consoleAttributes(style Style, base uint16) uint16 {
+	fg, bg := base&0x0f, base>>4&0x0f
+	if !style.Foreground.IsDefault() {
+		fg = style.Foreground.Nibble()
+	}
+
+	if !style.Background.IsDefault() {
+		bg = style.Background.Nibble()
+	}
+
+	return Style{Foreground: NibbleColor(fg), Background: NibbleColor(bg), Bold: style.Bold,
+		Underline: style.Underline, Reverse: style.Reverse}.Attributes()
+}
@@ -0,0 +1,65 @@
+package cons
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+	tests := []struct {
+		markup string
+		want   []Span
+	}{
+		{"plain", []Span{{Text: "plain"}}},
+		{"[bold red]error:[/] done", []Span{{Text: "error:", Style: Style{Foreground: Red, Bold: true}}, {Text: " done"}}},
+		{"[white on darkblue] a [bold]b[/] [/]", []Span{
+			{Text: " a ", Style: Style{Foreground: White, Background: DarkBlue}},
+			{Text: "b", Style: Style{Foreground: White, Background: DarkBlue, Bold: true}},
+			{Text: " ", Style: Style{Foreground: White, Background: DarkBlue}},
+		}},
+		{"[italic underline reverse]x[/italic]y", []Span{{Text: "x", Style: Style{Italic: true, Underline: true, Reverse: true}}, {Text: "y"}}},
+		{"[on red]x", []Span{{Text: "x", Style: Style{Background: Red}}}},
+		{"[RED]x", []Span{{Text: "x", Style: Style{Foreground: Red}}}},
+		{"[dark_red]x", []Span{{Text: "x", Style: Style{Foreground: DarkRed}}}},
+		{"[#ff8000]x", []Span{{Text: "x", Style: Style{Foreground: RGBColor(0xff, 0x80, 0)}}}},
+		{"[rgb(1,2,3)]x", []Span{{Text: "x", Style: Style{Foreground: RGBColor(1, 2, 3)}}}},
+		{"[color(208)]x", []Span{{Text: "x", Style: Style{Foreground: PaletteColor(208)}}}},
+		{"[red][default]x", []Span{{Text: "x"}}},
+		{"[red]a[/][red]b", []Span{{Text: "ab", Style: Style{Foreground: Red}}}},
+
+		// Brackets that do not hold a tag are text.
+		{"a[i]b", []Span{{Text: "a[i]b"}}},
+		{"[b]x[/b]", []Span{{Text: "[b]x[/b]"}}},
+		{"[red on]x", []Span{{Text: "[red on]x"}}},
+		{"[on on red]x", []Span{{Text: "[on on red]x"}}},
+		{"[]x", []Span{{Text: "[]x"}}},
+		{"[#12345]x", []Span{{Text: "[#12345]x"}}},
+		{"[color(256)]x", []Span{{Text: "[color(256)]x"}}},
+		{"[rgb(1,2)]x", []Span{{Text: "[rgb(1,2)]x"}}},
+		{"[[red]x", []Span{{Text: "["}, {Text: "x", Style: Style{Foreground: Red}}}},
+		{"a[red", []Span{{Text: "a[red"}}},
+		{`\[red]x`, []Span{{Text: "[red]x"}}},
+		{`a\b`, []Span{{Text: `a\b`}}},
+		{"[/]x[/]", []Span{{Text: "x"}}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.markup, func(t *testing.T) {
+			if got := ParseMarkup(tt.markup); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMarkup(%q) = %+v, want %+v", tt.markup, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeMarkup(t *testing.T) {
+	text := `[red]a[b] \[`
+	if got := StripMarkup(EscapeMarkup(text)); got != text {
+		t.Errorf("StripMarkup(EscapeMarkup(%q)) = %q", text, got)
+	}
+
+	if got := StripMarkup("[bold]a[/] [i]b"); got != "a [i]b" {
+		t.Errorf("StripMarkup() = %q, want %q", got, "a [i]b")
+	}
+}
@@ -0,0 +1,227 @@
+package cons
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDoubleClickTime is the longest interval between two presses of a button that makes a double click,
+// the default of the Windows double-click time.
+const DefaultDoubleClickTime = 500 * time.Millisecond
+
+// MouseTracking selects which mouse activity a terminal reports.
+type MouseTracking int
+
+const (
+	MouseTrackingOff    MouseTracking = 0    // No mouse reports.
+	MouseTrackingClick  MouseTracking = 1000 // Button presses, releases and the wheel.
+	MouseTrackingDrag   MouseTracking = 1002 // As MouseTrackingClick, plus motion while a button is held.
+	MouseTrackingMotion MouseTracking = 1003 // As MouseTrackingClick, plus all motion.
+)
+
+// EnableMouseTracking turns on mouse input. On the input side, EnableMouseInput is set and the quick edit mode,
+// which would turn a click into a selection, is cleared. On the output side, the VT sequences asking a terminal
+// to report the mouse are written, preferring the SGR 1006 encoding and falling back to urxvt 1015 and X10.
+//
+// Parameters:
+//
+//	in: The input side of the console, or nil to leave its mode unchanged.
+//	out: The terminal output, such as os.Stdout, or nil to write no sequence, as on a Windows console without
+//	     EnableVirtualTerminalInput.
+//	tracking: The mouse activity to report.
+//
+// Returns:
+//
+//	error: If the function successfully enables the mouse, it returns nil. Otherwise, it returns an error.
+func EnableMouseTracking(in ModeController, out io.Writer, tracking MouseTracking) error {
+	if tracking == MouseTrackingOff {
+		return DisableMouseTracking(in, out)
+	}
+
+	switch tracking {
+	case MouseTrackingClick, MouseTrackingDrag, MouseTrackingMotion:
+	default:
+		return ErrInvalidParameter
+	}
+
+	if in != nil {
+		mode, err := in.GetMode()
+		if err != nil {
+			return err
+		}
+
+		mode = (mode | EnableMouseInput | EnableExtendedFlags) &^ EnableQuickEditMode
+		if err := in.SetMode(mode); err != nil {
+			return err
+		}
+	}
+
+	if out != nil {
+		if _, err := fmt.Fprintf(out, "\x1b[?%dh\x1b[?1015h\x1b[?1006h", tracking); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DisableMouseTracking turns off mouse input, clearing EnableMouseInput on the input side and
+// asking the terminal to stop reporting the mouse on the output side.
+//
+// Parameters:
+//
+//	in: The input side of the console, or nil to leave its mode unchanged.
+//	out: The terminal output, or nil to write no sequence.
+//
+// Returns:
+//
+//	error: If the function successfully disables the mouse, it returns nil. Otherwise, it returns an error.
+func DisableMouseTracking(in ModeController, out io.Writer) error {
+	if in != nil {
+		mode, err := in.GetMode()
+		if err != nil {
+			return err
+		}
+
+		if err := in.SetMode(mode &^ EnableMouseInput); err != nil {
+			return err
+		}
+	}
+
+	if out != nil {
+		if _, err := io.WriteString(out, "\x1b[?1006l\x1b[?1015l\x1b[?1003l\x1b[?1002l\x1b[?1000l"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MouseDecoder converts the mouse reports of a terminal to the MouseEventRecord values a console reports.
+// It remembers the buttons held down, which the reports only partially carry, and synthesizes
+// the DoubleClick flag from the time and position of consecutive presses.
+//
+// The zero value is ready to use.
+type MouseDecoder struct {
+	// DoubleClickTime is the longest interval between two presses making a double click; zero means DefaultDoubleClickTime.
+	DoubleClickTime time.Duration
+
+	buttons   uint32    // The buttons currently held down.
+	lastPress uint32    // The button of the last press, for double click detection.
+	lastPos   Coord     // The position of the last press.
+	lastTime  time.Time // The time of the last press.
+}
+
+// Decode converts a complete mouse report in one of the following encodings:
+//
+//	SGR 1006:   ESC [ < button ; x ; y M  (press or motion), ESC [ < button ; x ; y m  (release)
+//	urxvt 1015: ESC [ button+32 ; x ; y M
+//	X10:        ESC [ M button+32 x+32 y+32, with raw bytes
+//
+// Parameters:
+//
+//	b: The bytes of the report.
+//
+// Returns:
+//
+//	MouseEventRecord: The decoded event, with a zero-based position.
+//	bool: True if b is a well-formed mouse report.
+func (m *MouseDecoder) Decode(b []byte) (MouseEventRecord, bool) {
+	if len(b) < 6 || b[0] != 0x1b || b[1] != '[' {
+		return MouseEventRecord{}, false
+	}
+
+	if b[2] == 'M' {
+		if len(b) != 6 {
+			return MouseEventRecord{}, false
+		}
+		return m.report(int(b[3])-32, int(b[4])-32, int(b[5])-32, false), true
+	}
+
+	final := b[len(b)-1]
+	body, sgr := strings.CutPrefix(string(b[2:len(b)-1]), "<")
+	fields := strings.Split(body, ";")
+	if len(fields) != 3 || (final != 'M' && (final != 'm' || !sgr)) {
+		return MouseEventRecord{}, false
+	}
+
+	var v [3]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return MouseEventRecord{}, false
+		}
+		v[i] = n
+	}
+
+	if !sgr {
+		v[0] -= 32
+	}
+	return m.report(v[0], v[1], v[2], final == 'm'), true
+}
+
+// report decodes the button code of a report and one-based coordinates. release is true for an SGR release,
+// which names the button released, unlike the button code 3 of the other encodings that releases them all.
+func (m *MouseDecoder) report(code, x, y int, release bool) MouseEventRecord {
+	ev := MouseEventRecord{MousePosition: Coord{X: int16(max(x-1, 0)), Y: int16(max(y-1, 0))}}
+
+	if code&4 != 0 {
+		ev.ControlKeyState |= ShiftPressed
+	}
+	if code&8 != 0 {
+		ev.ControlKeyState |= LeftAltPressed
+	}
+	if code&16 != 0 {
+		ev.ControlKeyState |= LeftCtrlPressed
+	}
+
+	if code&64 != 0 && code&128 == 0 {
+		// The wheel has no release; its direction goes in the high word of the button state.
+		delta := map[int]int16{0: 120, 1: -120, 2: -120, 3: 120}[code&3]
+		ev.EventFlags = MouseWheeled
+		if code&3 >= 2 {
+			ev.EventFlags = MouseHwheeled
+		}
+		ev.ButtonState = uint32(uint16(delta))<<16 | m.buttons
+		return ev
+	}
+
+	var button uint32
+	switch {
+	case code&128 != 0:
+		button = map[int]uint32{0: FromLeft3rdButtonPressed, 1: FromLeft4thButtonPressed}[code&3]
+	case code&3 != 3:
+		button = map[int]uint32{0: FromLeft1stButtonPressed, 1: FromLeft2ndButtonPressed, 2: RightmostButtonPressed}[code&3]
+	}
+
+	switch {
+	case code&32 != 0:
+		ev.EventFlags = MouseMoved
+		m.buttons |= button
+	case release:
+		m.buttons &^= button
+	case button == 0:
+		m.buttons = 0
+	default:
+		m.buttons |= button
+
+		now := time.Now()
+		limit := m.DoubleClickTime
+		if limit == 0 {
+			limit = DefaultDoubleClickTime
+		}
+
+		if button == m.lastPress && ev.MousePosition == m.lastPos && now.Sub(m.lastTime) <= limit {
+			ev.EventFlags = DoubleClick
+			m.lastPress = 0
+		} else {
+			m.lastPress, m.lastPos, m.lastTime = button, ev.MousePosition, now
+		}
+	}
+
+	ev.ButtonState = m.buttons
+	return ev
+}
@@ -0,0 +1,130 @@
+package cons
+
+import "syscall"
+
+const (
+	StdInputHandle  = ^uint32(10) + 1
+	StdOutputHandle = ^uint32(11) + 1
+	StdErrorHandle  = ^uint32(12) + 1
+)
+
+const (
+	errorSuccess = syscall.Errno(0)
+)
+
+const (
+	EnableProcessedInput            = 0x0001
+	EnableLineInput                 = 0x0002
+	EnableEchoInput                 = 0x0004
+	EnableWindowInput               = 0x0008
+	EnableMouseInput                = 0x0010
+	EnableInsertMode                = 0x0020
+	EnableQuickEditMode             = 0x0040
+	EnableExtendedFlags             = 0x0080
+	EnableVirtualTerminalInput      = 0x0200
+	EnableProcessedOutput           = 0x0001
+	EnableWrapAtEolOutput           = 0x0002
+	EnableVirtualTerminalProcessing = 0x0004
+	DisableNewlineAutoReturn        = 0x0008
+	EnableLvbGridWorldwide          = 0x0010
+)
+
+const (
+	ForegroundBlue      = 0x0001
+	ForegroundGreen     = 0x0002
+	ForegroundRed       = 0x0004
+	ForegroundIntensity = 0x0008
+	BackgroundBlue      = 0x0010
+	BackgroundGreen     = 0x0020
+	BackgroundRed       = 0x0040
+	BackgroundIntensity = 0x0080
+)
+
+const (
+	CommonLvbLeadingByte    = 0x0100
+	CommonLvbTrailingByte   = 0x0200
+	CommonLvbGridHorizontal = 0x0400
+	CommonLvbGridLvertical  = 0x0800
+	CommonLvbGridRvertical  = 0x1000
+	CommonLvbReverseVideo   = 0x4000
+	CommonLvbUnderscore     = 0x8000
+)
+
+const (
+	Acp       = 0
+	Oemcp     = 1
+	Maccp     = 2
+	ThreadAcp = 3
+	Symbol    = 42
+	Utf7      = 65000
+	Utf8      = 65001
+)
+
+const (
+	FromLeft1stButtonPressed = 0x0001
+	RightmostButtonPressed   = 0x0002
+	FromLeft2ndButtonPressed = 0x0004
+	FromLeft3rdButtonPressed = 0x0008
+	FromLeft4thButtonPressed = 0x0010
+)
+
+const (
+	MouseMoved    = 0x0001
+	DoubleClick   = 0x0002
+	MouseWheeled  = 0x0004
+	MouseHwheeled = 0x0008
+)
+
+const (
+	KeyEvent              = 0x0001
+	MouseEvent            = 0x0002
+	WindowBufferSizeEvent = 0x0004
+	MenuEvent             = 0x0008
+	FocusEvent            = 0x0010
+)
+
+const (
+	RightAltPressed  = 0x0001
+	LeftAltPressed   = 0x0002
+	RightCtrlPressed = 0x0004
+	LeftCtrlPressed  = 0x0008
+	ShiftPressed     = 0x0010
+	NumLockOn        = 0x0020
+	ScrollLockOn     = 0x0040
+	CapsLockOn       = 0x0080
+	EnhancedKey      = 0x0100
+)
+
+const (
+	VkBack    = 0x08
+	VkTab     = 0x09
+	VkReturn  = 0x0D
+	VkShift   = 0x10
+	VkControl = 0x11
+	VkMenu    = 0x12
+	VkPause   = 0x13
+	VkEscape  = 0x1B
+	VkSpace   = 0x20
+	VkPrior   = 0x21
+	VkNext    = 0x22
+	VkEnd     = 0x23
+	VkHome    = 0x24
+	VkLeft    = 0x25
+	VkUp      = 0x26
+	VkRight   = 0x27
+	VkDown    = 0x28
+	VkInsert  = 0x2D
+	VkDelete  = 0x2E
+	VkF1      = 0x70
+	VkF2      = 0x71
+	VkF3      = 0x72
+	VkF4      = 0x73
+	VkF5      = 0x74
+	VkF6      = 0x75
+	VkF7      = 0x76
+	VkF8      = 0x77
+	VkF9      = 0x78
+	VkF10     = 0x79
+	VkF11     = 0x7A
+	VkF12     = 0x7B
+)
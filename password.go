@@ -0,0 +1,104 @@
+package cons
+
+import (
+	"io"
+	"strings"
+)
+
+// ReadPassword reads a secret typed on the console without displaying it. See ReadMasked.
+//
+// Parameters:
+//
+//	in: The input side of the console.
+//
+// Returns:
+//
+//	string: The secret, without line break.
+//	error: If the function successfully reads the secret, it returns nil. Otherwise, it returns ErrInterrupted
+//	for Ctrl+C, io.EOF for Ctrl+D on an empty secret, or the error reading the keys.
+func ReadPassword(in InputSource) (string, error) {
+	return ReadMasked(in, nil, 0)
+}
+
+// ReadMasked reads a secret typed on the console, displaying a mask character in place of each character typed.
+// Echo, line input and processed input are disabled while the secret is read, so Ctrl+C arrives as a key
+// rather than as a signal, and the previous input mode is restored before returning in every case.
+// Backspace deletes the last character and Ctrl+U the whole secret, Enter ends it.
+//
+// Parameters:
+//
+//	in: The input side of the console.
+//	out: The output side of the console where the mask is drawn at the cursor, or nil to draw nothing.
+//	mask: The character drawn for each character typed, such as '*', or 0 to draw nothing.
+//
+// Returns:
+//
+//	string: The secret, without line break.
+//	error: If the function successfully reads the secret, it returns nil. Otherwise, it returns ErrInterrupted
+//	for Ctrl+C, io.EOF for Ctrl+D on an empty secret, or the error reading the keys.
+func ReadMasked(in InputSource, out OutputTarget, mask rune) (string, error) {
+	restore, err := rawKeyMode(in)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	// The mask is drawn as the line of a line editor without prompt, which wraps and scrolls it.
+	var st *lineState
+	if out != nil && mask != 0 {
+		var scrbufinfo ScreenBufferInfo
+		if err := out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+			return "", err
+		}
+
+		st = &lineState{le: &LineEditor{out: out}, origin: scrbufinfo.CursorPosition, attr: scrbufinfo.Attributes}
+	}
+
+	var (
+		keys   = NewRuneReader(in)
+		secret []rune
+	)
+
+	for {
+		key, r, err := keys.ReadKey()
+		if err != nil {
+			return "", finishMask(st, err)
+		}
+
+		switch {
+		case key.VirtualKeyCode == VkReturn || r == '\r' || r == '\n':
+			return string(secret), finishMask(st, nil)
+		case r == 0x03:
+			return "", finishMask(st, ErrInterrupted)
+		case r == 0x04 && len(secret) == 0:
+			return "", finishMask(st, io.EOF)
+		case r == 0x08 || r == 0x7f:
+			if len(secret) > 0 {
+				secret = secret[:len(secret)-1]
+			}
+		case r == 0x15:
+			secret = secret[:0]
+		case r >= ' ':
+			secret = append(secret, r)
+		default:
+			continue
+		}
+
+		if st != nil {
+			st.line = []rune(strings.Repeat(string(mask), len(secret)))
+			st.pos = len(st.line)
+			if err := st.draw(); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// finishMask moves the cursor below the mask, if one is drawn, and returns err.
+func finishMask(st *lineState, err error) error {
+	if st == nil {
+		return err
+	}
+
+	return st.abort(err)
+}
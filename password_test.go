@@ -0,0 +1,111 @@
+package cons
+
+import (
+	"io"
+	"testing"
+	"unsafe"
+)
+
+// modeInput is a VirtualInput keeping the input mode set at each read.
+type modeInput struct {
+	*VirtualInput
+	modes []DWord
+}
+
+func (mi *modeInput) ReadInput(buffer unsafe.Pointer, length uint32, counter *uint32) error {
+	mode, _ := mi.GetMode()
+	mi.modes = append(mi.modes, mode)
+	return mi.VirtualInput.ReadInput(buffer, length, counter)
+}
+
+func TestReadMasked(t *testing.T) {
+	const initial = EnableProcessedInput | EnableLineInput | EnableEchoInput | EnableWindowInput
+
+	tests := []struct {
+		name   string
+		script string
+		close  bool
+		want   string
+		err    error
+		line   string
+	}{
+		{"typed", "s3cret<Enter>", false, "s3cret", nil, "$ ******"},
+		{"backspace", "abcd<Backspace><Backspace>x<Enter>", false, "abx", nil, "$ ***"},
+		{"backspace on empty", "<Backspace>a<Enter>", false, "a", nil, "$ *"},
+		{"ctrl+u", "abc<C-u>de<Enter>", false, "de", nil, "$ **"},
+		{"arrow keys ignored", "a<Left>b<Enter>", false, "ab", nil, "$ **"},
+		{"ctrl+c", "abc<C-c>", false, "", ErrInterrupted, "$ ***"},
+		{"ctrl+d on empty", "<C-d>", false, "", io.EOF, "$"},
+		{"ctrl+d ignored", "a<C-d>b<Enter>", false, "ab", nil, "$ **"},
+		{"input closed", "abc", true, "", io.EOF, "$ ***"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := typed(t, tt.script)
+			if tt.close {
+				vc.VirtualInput().Close()
+			}
+			in := &modeInput{VirtualInput: vc.VirtualInput()}
+			in.SetMode(initial)
+
+			WriteString(vc, "$ ", vc.attributes, Coord{})
+			vc.SetCursorPosition(Coord{X: 2})
+
+			got, err := ReadMasked(in, vc, '*')
+			if got != tt.want || err != tt.err {
+				t.Fatalf("ReadMasked = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+			if line := vc.Line(0); line != tt.line {
+				t.Errorf("mask row = %q, want %q", line, tt.line)
+			}
+
+			var scrbufinfo ScreenBufferInfo
+			vc.GetScreenBufferInfo(&scrbufinfo)
+			if scrbufinfo.CursorPosition != (Coord{Y: 1}) {
+				t.Errorf("cursor = %+v, want the start of the row below the mask", scrbufinfo.CursorPosition)
+			}
+
+			if mode, _ := in.GetMode(); mode != initial {
+				t.Errorf("mode after ReadMasked = %#x, want %#x", mode, initial)
+			}
+			for _, mode := range in.modes {
+				if mode&(EnableLineInput|EnableEchoInput|EnableProcessedInput) != 0 || mode&EnableWindowInput == 0 {
+					t.Errorf("mode while reading = %#x, want %#x without line input, echo and processed input", mode, initial)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPassword(t *testing.T) {
+	tests := []struct {
+		script string
+		want   string
+		err    error
+	}{
+		{"hunter2<Enter>", "hunter2", nil},
+		{"pass<C-c>", "", ErrInterrupted},
+		{"<C-d>", "", io.EOF},
+		{"😀é<Backspace><Enter>", "😀", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			vc := typed(t, tt.script)
+			in := &modeInput{VirtualInput: vc.VirtualInput()}
+			mode, _ := in.GetMode()
+
+			got, err := ReadPassword(in)
+			if got != tt.want || err != tt.err {
+				t.Fatalf("ReadPassword = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+			if restored, _ := in.GetMode(); restored != mode {
+				t.Errorf("mode after ReadPassword = %#x, want %#x", restored, mode)
+			}
+			if line := vc.Line(0); line != "" {
+				t.Errorf("ReadPassword drew %q", line)
+			}
+		})
+	}
+}
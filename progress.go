@@ -0,0 +1,548 @@
+package cons
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// progressRefresh is the shortest interval between two redraws of the bars and spinners of a Progress.
+const progressRefresh = 100 * time.Millisecond
+
+// spinnerFrames are the frames of a running Spinner, each shown for progressRefresh.
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// ProgressUnit selects how the counts of a Bar are formatted.
+type ProgressUnit int
+
+const (
+	UnitCount ProgressUnit = iota // Plain counts, such as 120/300 and 15.0/s.
+	UnitBytes                     // Byte sizes, such as 1.5 MiB/3.0 MiB and 512.0 KiB/s.
+)
+
+// progressLine is a bar or a spinner, drawn on one row of the area of a Progress.
+type progressLine interface {
+	label() string
+	active() bool // Reports whether the line changes with time alone.
+	line(now time.Time, nameWidth, width int) string
+}
+
+// Progress draws progress bars and spinners on rows reserved at the bottom of the window, while the lines
+// written to it are printed above them, scrolling the text above the bars rather than the bars themselves.
+// The bars are redrawn at most every 100 milliseconds, on a goroutine running until Stop.
+//
+// While a Progress runs, the console must only be written through it, for example with
+// log.SetOutput(p) or fmt.Fprintln(p, ...).
+type Progress struct {
+	mu      sync.Mutex
+	out     OutputTarget
+	attr    uint16 // The attributes of the console when the progress started.
+	lines   []progressLine
+	log     Coord // The position of the next line written.
+	top     int16 // The first row of the area of the bars, as last drawn.
+	shown   int   // The number of bars drawn.
+	partial []byte
+	dirty   bool
+	err     error // The first error drawing from the goroutine.
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewProgress starts drawing progress on a console. The text written to the Progress starts on the row
+// of the cursor, or on the next row if the cursor is not at the start of a row.
+//
+// Parameters:
+//
+//	out: The output side of the console.
+//
+// Returns:
+//
+//	*Progress: The running progress, without bars.
+//	error: If the function successfully reads the screen buffer information, it returns nil. Otherwise, it returns an error.
+func NewProgress(out OutputTarget) (*Progress, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return nil, err
+	}
+
+	p := &Progress{
+		out:  out,
+		attr: scrbufinfo.Attributes,
+		log:  Coord{Y: scrbufinfo.CursorPosition.Y},
+		top:  scrbufinfo.Window.Bottom + 1,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if scrbufinfo.CursorPosition.X > 0 {
+		p.log.Y++
+	}
+
+	go p.run()
+	return p, nil
+}
+
+// AddBar adds a progress bar below the bars and spinners already shown.
+//
+// Parameters:
+//
+//	name: The name shown before the bar.
+//	total: The count reached when the work is done, or 0 if it is unknown, in which case no bar is drawn.
+//	unit: How the counts are formatted.
+//
+// Returns:
+//
+//	*Bar: The new bar, at 0.
+func (p *Progress) AddBar(name string, total int64, unit ProgressUnit) *Bar {
+	b := &Bar{p: p, name: name, total: total, unit: unit, start: time.Now()}
+	p.add(b)
+	return b
+}
+
+// AddSpinner adds a spinner below the bars and spinners already shown, for work whose length is unknown.
+//
+// Parameters:
+//
+//	name: The name shown before the spinner.
+//
+// Returns:
+//
+//	*Spinner: The new, running spinner.
+func (p *Progress) AddSpinner(name string) *Spinner {
+	s := &Spinner{p: p, name: name, start: time.Now()}
+	p.add(s)
+	return s
+}
+
+func (p *Progress) add(line progressLine) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lines = append(p.lines, line)
+	p.dirty = true
+}
+
+// Write prints the complete lines of b above the bars, keeping the text after the last line break
+// until the rest of its line is written.
+//
+// Parameters:
+//
+//	b: The text to print.
+//
+// Returns:
+//
+//	int: The number of bytes of b, or 0 on error.
+//	error: If the function successfully prints the lines, it returns nil. Otherwise, it returns an error.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.partial = append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := string(p.partial[:i])
+		p.partial = p.partial[i+1:]
+		if err := p.print(line); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), p.out.SetCursorPosition(p.log)
+}
+
+// Stop stops drawing progress. The last state of the bars and spinners is printed as lines below the text
+// written, and the cursor is left on the row after them.
+//
+// Returns:
+//
+//	error: If the progress was successfully drawn, it returns nil. Otherwise, it returns the first error.
+func (p *Progress) Stop() error {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lines, now := p.lines, time.Now()
+	p.lines = nil
+
+	err := p.err
+	if len(p.partial) > 0 {
+		err = firstError(err, p.print(string(p.partial)))
+		p.partial = nil
+	}
+
+	// Blank the area, then print the bars as text, where they stay.
+	width, err := p.layout(err)
+	nameWidth := labelWidth(lines)
+	for _, line := range lines {
+		err = firstError(err, p.print(line.line(now, nameWidth, int(width))))
+	}
+
+	return firstError(err, p.out.SetCursorPosition(p.log))
+}
+
+func (p *Progress) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(progressRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		if p.dirty || p.active() {
+			p.err = firstError(p.err, p.draw())
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *Progress) active() bool {
+	for _, line := range p.lines {
+		if line.active() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// draw writes the bars on the rows reserved for them.
+func (p *Progress) draw() error {
+	width, err := p.layout(nil)
+	if err != nil {
+		return err
+	}
+
+	now, nameWidth := time.Now(), labelWidth(p.lines)
+	for i, line := range p.lines[:p.shown] {
+		cells := padCells(fitCells(line.line(now, nameWidth, int(width)), p.attr, int(width)), int(width), p.attr)
+		if err := writeCells(p.out, cells, Coord{Y: p.top + int16(i)}); err != nil {
+			return err
+		}
+	}
+	p.dirty = false
+
+	return p.out.SetCursorPosition(p.log)
+}
+
+// layout reserves the rows of the bars at the bottom of the window, keeping one row for the text at least,
+// blanks the rows of the previous area left out of the new one and scrolls the text of the window up if it
+// reaches the area.
+// It returns the width of the screen buffer, and err unless the layout fails.
+func (p *Progress) layout(err error) (int16, error) {
+	var scrbufinfo ScreenBufferInfo
+	if gerr := p.out.GetScreenBufferInfo(&scrbufinfo); gerr != nil {
+		return 0, firstError(err, gerr)
+	}
+
+	window, width := scrbufinfo.Window, scrbufinfo.Size.X
+	p.shown = min(len(p.lines), int(window.Bottom-window.Top))
+	top := window.Bottom - int16(p.shown) + 1
+
+	blank := padCells(nil, int(width), p.attr)
+	for y := max(p.top, p.log.Y); y < min(top, scrbufinfo.Size.Y); y++ {
+		if werr := writeCells(p.out, blank, Coord{Y: y}); werr != nil {
+			return width, firstError(err, werr)
+		}
+	}
+	p.top = top
+
+	// Only the rows of the window scroll: the rows above it, the history of a tall screen buffer, stay
+	// where they are, and a line costs a scroll of the window rather than of the whole buffer.
+	if p.log.Y >= top {
+		n := p.log.Y - top + 1
+		if window.Top+n < p.log.Y {
+			scroll := SmallRect{Top: window.Top + n, Right: width - 1, Bottom: p.log.Y - 1}
+			if serr := p.out.ScrollScreenBuffer(&scroll, nil, Coord{Y: window.Top}, CharInfo{UnicodeChar: ' ', Attributes: p.attr}); serr != nil {
+				return width, firstError(err, serr)
+			}
+		}
+		p.log.Y -= n
+	}
+
+	return width, err
+}
+
+// print writes a line of text on the rows above the bars, wrapping it at the end of each row,
+// and scrolls so that the row after it is above the bars too.
+func (p *Progress) print(text string) error {
+	width, err := p.layout(nil)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range wrapCells(strings.TrimSuffix(text, "\r"), p.attr, int(width)) {
+		if err := writeCells(p.out, padCells(row, int(width), p.attr), p.log); err != nil {
+			return err
+		}
+
+		p.log.Y++
+		if _, err := p.layout(nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Bar is a progress bar of a Progress. Its methods are safe for concurrent use.
+type Bar struct {
+	p       *Progress
+	name    string
+	total   int64
+	current int64
+	unit    ProgressUnit
+	start   time.Time
+	end     time.Time // The time the work was done, zero while it runs.
+}
+
+// Add adds n to the count of the bar.
+func (b *Bar) Add(n int64) {
+	b.p.mu.Lock()
+	defer b.p.mu.Unlock()
+
+	b.current += n
+	b.p.dirty = true
+}
+
+// SetCurrent sets the count of the bar.
+func (b *Bar) SetCurrent(n int64) {
+	b.p.mu.Lock()
+	defer b.p.mu.Unlock()
+
+	b.current = n
+	b.p.dirty = true
+}
+
+// SetTotal sets the count reached when the work is done, or 0 if it is unknown.
+func (b *Bar) SetTotal(n int64) {
+	b.p.mu.Lock()
+	defer b.p.mu.Unlock()
+
+	b.total = n
+	b.p.dirty = true
+}
+
+// Done marks the work done, filling the bar and showing the time the work took instead of the time left.
+func (b *Bar) Done() {
+	b.p.mu.Lock()
+	defer b.p.mu.Unlock()
+
+	if b.total > 0 {
+		b.current = b.total
+	}
+	b.end = time.Now()
+	b.p.dirty = true
+}
+
+func (b *Bar) label() string { return b.name }
+
+func (b *Bar) active() bool { return b.end.IsZero() }
+
+// line formats the bar as name [=====>    ]  45%  1.5 MiB/3.0 MiB  512.0 KiB/s  ETA 00:03.
+func (b *Bar) line(now time.Time, nameWidth, width int) string {
+	elapsed := now.Sub(b.start)
+	if !b.end.IsZero() {
+		elapsed = b.end.Sub(b.start)
+	}
+
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(b.current) / elapsed.Seconds()
+	}
+
+	stats := b.format(b.current)
+	if b.total > 0 {
+		stats = fmt.Sprintf("%3d%%  %s/%s", min(b.current*100/b.total, 100), stats, b.format(b.total))
+	}
+
+	if b.unit == UnitBytes {
+		stats += "  " + FormatBytes(int64(rate)) + "/s"
+	} else {
+		stats += fmt.Sprintf("  %.1f/s", rate)
+	}
+
+	switch {
+	case !b.end.IsZero():
+		stats += "  in " + FormatDuration(elapsed)
+	case b.total > 0 && rate > 0:
+		stats += "  ETA " + FormatDuration(time.Duration(float64(max(b.total-b.current, 0))/rate*float64(time.Second)))
+	case b.total > 0:
+		stats += "  ETA --:--"
+	}
+
+	name := padName(b.name, nameWidth)
+	room := width - StringWidth(name) - len(stats) - 4
+	if b.total <= 0 || room < 10 {
+		return name + " " + stats
+	}
+
+	filled := int(min(b.current, b.total) * int64(room) / b.total)
+	bar := strings.Repeat("=", filled)
+	if filled < room {
+		bar += ">" + strings.Repeat(" ", room-filled-1)
+	}
+
+	return name + " [" + bar + "] " + stats
+}
+
+func (b *Bar) format(n int64) string {
+	if b.unit == UnitBytes {
+		return FormatBytes(n)
+	}
+
+	return fmt.Sprint(n)
+}
+
+// Spinner is a spinner of a Progress, for work whose length is unknown. Its methods are safe for concurrent use.
+type Spinner struct {
+	p      *Progress
+	name   string
+	status string
+	start  time.Time
+	end    time.Time // The time the work was done, zero while it runs.
+}
+
+// SetStatus sets the text shown after the spinner, such as the step running.
+func (s *Spinner) SetStatus(status string) {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	s.status = status
+	s.p.dirty = true
+}
+
+// Done stops the spinner, replacing its status.
+func (s *Spinner) Done(status string) {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	s.status, s.end = status, time.Now()
+	s.p.dirty = true
+}
+
+func (s *Spinner) label() string { return s.name }
+
+func (s *Spinner) active() bool { return s.end.IsZero() }
+
+// line formats the spinner as name | status  00:03.
+func (s *Spinner) line(now time.Time, nameWidth, width int) string {
+	frame, elapsed := "*", s.end.Sub(s.start)
+	if s.end.IsZero() {
+		elapsed = now.Sub(s.start)
+		frame = spinnerFrames[int(elapsed/progressRefresh)%len(spinnerFrames)]
+	}
+
+	text := padName(s.name, nameWidth) + " " + frame
+	if s.status != "" {
+		text += " " + s.status
+	}
+
+	return text + "  " + FormatDuration(elapsed)
+}
+
+// FormatBytes formats a byte size with binary prefixes, such as 512 B, 1.5 KiB or 3.0 GiB.
+//
+// Parameters:
+//
+//	n: The size, in bytes.
+//
+// Returns:
+//
+//	string: The size formatted.
+func FormatBytes(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 && n > -1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	size, i := float64(n)/1024, 0
+	for (size >= 1024 || size <= -1024) && i < len(units)-1 {
+		size /= 1024
+		i++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", size, units[i])
+}
+
+// FormatDuration formats a duration as minutes and seconds, with hours when it reaches an hour,
+// such as 00:42 or 1:05:09.
+//
+// Parameters:
+//
+//	d: The duration, rounded down to the second.
+//
+// Returns:
+//
+//	string: The duration formatted.
+func FormatDuration(d time.Duration) string {
+	s := int64(max(d, 0) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+// labelWidth returns the width of the longest name of the lines, to align the bars.
+func labelWidth(lines []progressLine) int {
+	width := 0
+	for _, line := range lines {
+		width = max(width, StringWidth(line.label()))
+	}
+
+	return width
+}
+
+func padName(name string, width int) string {
+	return name + strings.Repeat(" ", max(width-StringWidth(name), 0))
+}
+
+// wrapCells lays out a line of text on rows of width cells, a wide character that does not fit at the end
+// of a row moving to the next one, and tabs moving to the next multiple of 8 columns.
+func wrapCells(s string, attr uint16, width int) [][]CharInfo {
+	rows := [][]CharInfo{nil}
+	for s != "" {
+		cluster, rest, w := NextGrapheme(s)
+		s = rest
+
+		last := len(rows) - 1
+		if cluster == "\t" {
+			cluster, w = " ", 1
+			s = strings.Repeat(" ", 7-len(rows[last])%8) + s
+		}
+
+		if len(rows[last])+w > width && len(rows[last]) > 0 {
+			rows[last] = padCells(rows[last], width, attr)
+			rows, last = append(rows, nil), last+1
+		}
+
+		r, _ := utf8.DecodeRuneInString(cluster)
+		rows[last] = append(rows[last], layoutRune(r, w, attr)...)
+	}
+
+	return rows
+}
+
+// firstError returns err, or next if err is nil.
+func firstError(err, next error) error {
+	if err != nil {
+		return err
+	}
+
+	return next
+}
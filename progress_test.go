@@ -0,0 +1,171 @@
+package cons
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{3 << 20, "3.0 MiB"},
+		{5 << 30, "5.0 GiB"},
+		{1 << 62, "4.0 EiB"},
+		{-2048, "-2.0 KiB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "00:00"},
+		{-time.Second, "00:00"},
+		{42*time.Second + 900*time.Millisecond, "00:42"},
+		{59*time.Minute + 59*time.Second, "59:59"},
+		{time.Hour + 5*time.Minute + 9*time.Second, "1:05:09"},
+		{100 * time.Hour, "100:00:00"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestBarLine(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start.Add(2 * time.Second)
+
+	tests := []struct {
+		name  string
+		bar   Bar
+		width int
+		want  string
+	}{
+		{"count", Bar{name: "copy", total: 100, current: 50, start: start}, 51,
+			"copy   [=====>    ]  50%  50/100  25.0/s  ETA 00:02"},
+		{"bytes", Bar{name: "download", total: 4 << 20, current: 1 << 20, unit: UnitBytes, start: start}, 70,
+			"download [===>         ]  25%  1.0 MiB/4.0 MiB  512.0 KiB/s  ETA 00:06"},
+		{"done", Bar{name: "copy", total: 10, current: 10, start: start, end: start.Add(time.Second)}, 49,
+			"copy   [==========] 100%  10/10  10.0/s  in 00:01"},
+		{"unknown total", Bar{name: "copy", current: 7, start: start}, 50, "copy   7  3.5/s"},
+		{"no elapsed time", Bar{name: "copy", total: 10, start: now}, 48, "copy   [>         ]   0%  0/10  0.0/s  ETA --:--"},
+		{"too narrow", Bar{name: "copy", total: 100, current: 50, start: start}, 30, "copy    50%  50/100  25.0/s  ETA 00:02"},
+		{"over total", Bar{name: "copy", total: 10, current: 20, start: start}, 50, "copy   [==========] 100%  20/10  10.0/s  ETA 00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.bar.line(now, 6, tt.width); got != tt.want {
+				t.Errorf("line() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// lines returns the rows from..to-1 of a console.
+func lines(vc *VirtualConsole, from, to int16) []string {
+	var rows []string
+	for y := from; y < to; y++ {
+		rows = append(rows, vc.Line(y))
+	}
+
+	return rows
+}
+
+func TestProgress(t *testing.T) {
+	vc := NewVirtualConsole(20, 5)
+	p, err := NewProgress(vc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := p.AddSpinner("job")
+	for i := 1; i <= 6; i++ {
+		if _, err := fmt.Fprintf(p, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The text scrolls above the row of the spinner.
+	if got, want := lines(vc, 0, 4), []string{"line 4", "line 5", "line 6", ""}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+
+	if _, err := p.Write([]byte("partial")); err != nil {
+		t.Fatal(err)
+	}
+	s.Done("ok")
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"line 5", "line 6", "partial", "job * ok  00:00", ""}
+	if got := lines(vc, 0, 5); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("rows after Stop = %q, want %q", got, want)
+	}
+
+	var info ScreenBufferInfo
+	if err := vc.GetScreenBufferInfo(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.CursorPosition != (Coord{X: 0, Y: 4}) {
+		t.Errorf("cursor = %+v, want {0 4}", info.CursorPosition)
+	}
+}
+
+func TestProgressTallBuffer(t *testing.T) {
+	vc := NewVirtualConsole(20, 10)
+	for y := int16(0); y < 5; y++ {
+		if _, err := WriteString(vc, fmt.Sprintf("history %d", y), vc.attributes, Coord{Y: y}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := vc.SetWindowInfo(SmallRect{Top: 5, Right: 19, Bottom: 9}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vc.SetCursorPosition(Coord{Y: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProgress(vc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.AddSpinner("job").Done("ok")
+	for i := 1; i <= 6; i++ {
+		if _, err := fmt.Fprintf(p, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the window scrolls, leaving the rows above it alone.
+	want := []string{
+		"history 0", "history 1", "history 2", "history 3", "history 4",
+		"line 4", "line 5", "line 6", "job * ok  00:00", "",
+	}
+	if got := lines(vc, 0, 10); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,497 @@
+package cons
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// selectPageSize is the number of options a Select or MultiSelect list shows at once.
+const selectPageSize = 10
+
+// Confirm asks a yes or no question. The answer is typed as y or n, and Enter picks the default.
+// Like every prompt of the package, it reads key events and draws on the console itself, so it can be driven
+// by a VirtualInput and a VirtualConsole in tests, with VirtualInput.Type.
+//
+// Parameters:
+//
+//	in: The input side of the console.
+//	out: The output side of the console, where the question is drawn at the cursor.
+//	question: The question, such as "Overwrite the file?".
+//	def: The answer picked by Enter.
+//
+// Returns:
+//
+//	bool: The answer.
+//	error: If the function successfully reads an answer, it returns nil. Otherwise, it returns ErrInterrupted
+//	for Ctrl+C or the error reading the keys.
+func Confirm(in InputSource, out OutputTarget, question string, def bool) (bool, error) {
+	restore, err := rawKeyMode(in)
+	if err != nil {
+		return def, err
+	}
+	defer restore()
+
+	v, err := newPromptView(out)
+	if err != nil {
+		return def, err
+	}
+
+	hint := question + " [y/N] "
+	if def {
+		hint = question + " [Y/n] "
+	}
+
+	if err := v.draw([]promptRow{{hint, v.attr}}, Coord{X: int16(StringWidth(hint))}); err != nil {
+		return def, err
+	}
+
+	keys := NewRuneReader(in)
+	for {
+		key, r, err := keys.ReadKey()
+		if err != nil {
+			return def, err
+		}
+
+		answer := def
+		switch {
+		case r == 'y' || r == 'Y':
+			answer = true
+		case r == 'n' || r == 'N':
+			answer = false
+		case key.VirtualKeyCode == VkReturn || r == '\r':
+		case r == 0x03:
+			return def, v.abort([]promptRow{{question, v.attr}}, ErrInterrupted)
+		default:
+			continue
+		}
+
+		word := "No"
+		if answer {
+			word = "Yes"
+		}
+
+		return answer, v.done([]promptRow{{question + " " + word, v.attr}})
+	}
+}
+
+// Select asks to pick one option of a list. Up, Down, Page Up, Page Down, Home and End move through the list,
+// typing filters it to the options containing the text typed, Backspace and Esc edit the filter, and Enter picks
+// the option under the cursor.
+//
+// Parameters:
+//
+//	in: The input side of the console.
+//	out: The output side of the console, where the question and the list are drawn from the cursor.
+//	question: The question, such as "Pick a color:".
+//	options: The options.
+//	def: The index of the option under the cursor at first.
+//
+// Returns:
+//
+//	int: The index of the option picked.
+//	error: If the function successfully reads a choice, it returns nil. Otherwise, it returns ErrInvalidParameter
+//	without options, ErrInterrupted for Ctrl+C or the error reading the keys.
+func Select(in InputSource, out OutputTarget, question string, options []string, def int) (int, error) {
+	if len(options) == 0 {
+		return -1, ErrInvalidParameter
+	}
+
+	lp := &listPrompt{question: question, options: options}
+	if err := lp.run(in, out, def); err != nil {
+		return -1, err
+	}
+
+	return lp.matches[lp.cur], nil
+}
+
+// MultiSelect asks to pick any number of options of a list, shown with check boxes. It works as Select,
+// except that Space checks or unchecks the option under the cursor, Ctrl+A checks or unchecks every option
+// shown, and Enter accepts the options checked.
+//
+// Parameters:
+//
+//	in: The input side of the console.
+//	out: The output side of the console, where the question and the list are drawn from the cursor.
+//	question: The question, such as "Pick the features:".
+//	options: The options.
+//	checked: The indices of the options checked at first.
+//
+// Returns:
+//
+//	[]int: The indices of the options checked, in ascending order.
+//	error: If the function successfully reads a choice, it returns nil. Otherwise, it returns ErrInvalidParameter
+//	without options, ErrInterrupted for Ctrl+C or the error reading the keys.
+func MultiSelect(in InputSource, out OutputTarget, question string, options []string, checked []int) ([]int, error) {
+	if len(options) == 0 {
+		return nil, ErrInvalidParameter
+	}
+
+	lp := &listPrompt{question: question, options: options, checked: make([]bool, len(options))}
+	for _, i := range checked {
+		if i >= 0 && i < len(options) {
+			lp.checked[i] = true
+		}
+	}
+
+	if err := lp.run(in, out, 0); err != nil {
+		return nil, err
+	}
+
+	return lp.picked(), nil
+}
+
+// ReadInt asks for a whole number, editing it with a LineEditor and asking again, after a message,
+// until the number is valid.
+//
+// Parameters:
+//
+//	in: The input side of the console.
+//	out: The output side of the console, where the question is drawn at the cursor.
+//	question: The question, such as "Port:".
+//	min: The smallest number accepted.
+//	max: The largest number accepted.
+//
+// Returns:
+//
+//	int: The number.
+//	error: If the function successfully reads a number, it returns nil. Otherwise, it returns ErrInterrupted
+//	for Ctrl+C, io.EOF for Ctrl+D or the error reading the keys.
+func ReadInt(in InputSource, out OutputTarget, question string, min, max int) (int, error) {
+	le := NewLineEditor(in, out)
+	le.Prompt = question + " "
+
+	for {
+		line, err := le.ReadLine()
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(line))
+		msg := ""
+		switch {
+		case err != nil:
+			msg = "Please enter a whole number."
+		case n < min || n > max:
+			msg = fmt.Sprintf("Please enter a number from %d to %d.", min, max)
+		default:
+			return n, nil
+		}
+
+		v, err := newPromptView(out)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := v.done([]promptRow{{msg, v.attr&0xf0 | ForegroundRed | ForegroundIntensity}}); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// rawKeyMode disables line input, echo and processed input, so every key, Ctrl+C included, arrives as
+// a key event, and returns the function restoring the previous mode.
+func rawKeyMode(in InputSource) (func() error, error) {
+	mode, err := in.GetMode()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := in.SetMode(mode &^ (EnableLineInput | EnableEchoInput | EnableProcessedInput)); err != nil {
+		return nil, err
+	}
+
+	return func() error { return in.SetMode(mode) }, nil
+}
+
+// listPrompt is the state of a Select or MultiSelect prompt.
+type listPrompt struct {
+	question string
+	options  []string
+	checked  []bool // The options checked, nil for a Select prompt.
+	filter   []rune
+	matches  []int // The indices of the options containing the filter.
+	cur      int   // The index in matches of the option under the cursor.
+	top      int   // The index in matches of the first option shown.
+}
+
+func (lp *listPrompt) run(in InputSource, out OutputTarget, def int) error {
+	restore, err := rawKeyMode(in)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	v, err := newPromptView(out)
+	if err != nil {
+		return err
+	}
+
+	lp.refilter(def)
+	keys := NewRuneReader(in)
+	for {
+		if err := lp.draw(v); err != nil {
+			return err
+		}
+
+		key, r, err := keys.ReadKey()
+		if err != nil {
+			return err
+		}
+
+		ctrl := key.ControlKeyState&(LeftCtrlPressed|RightCtrlPressed) != 0
+		current := -1
+		if len(lp.matches) > 0 {
+			current = lp.matches[lp.cur]
+		}
+
+		switch vk := key.VirtualKeyCode; {
+		case vk == VkReturn || r == '\r':
+			if lp.checked == nil && current < 0 {
+				continue
+			}
+			return v.done([]promptRow{{lp.question + " " + lp.answer(), v.attr}})
+		case r == 0x03:
+			return v.abort([]promptRow{{lp.question, v.attr}}, ErrInterrupted)
+		case vk == VkUp && r == 0 || r == 0x10:
+			lp.move(-1)
+		case vk == VkDown && r == 0 || r == 0x0e || r == '\t':
+			lp.move(1)
+		case vk == VkPrior && r == 0:
+			lp.move(-selectPageSize)
+		case vk == VkNext && r == 0:
+			lp.move(selectPageSize)
+		case vk == VkHome && r == 0:
+			lp.move(-len(lp.matches))
+		case vk == VkEnd && r == 0:
+			lp.move(len(lp.matches))
+		case r == ' ' && lp.checked != nil:
+			if current >= 0 {
+				lp.checked[current] = !lp.checked[current]
+			}
+		case r == 0x01 && lp.checked != nil:
+			lp.toggleAll()
+		case r == 0x08 || r == 0x7f:
+			if len(lp.filter) > 0 {
+				lp.filter = lp.filter[:len(lp.filter)-1]
+				lp.refilter(current)
+			}
+		case r == 0x1b:
+			lp.filter = nil
+			lp.refilter(current)
+		case r >= ' ' && !ctrl:
+			lp.filter = append(lp.filter, r)
+			lp.refilter(current)
+		}
+	}
+}
+
+// refilter finds the options containing the filter, keeping the cursor on the option keep if it still matches.
+func (lp *listPrompt) refilter(keep int) {
+	filter := strings.ToLower(string(lp.filter))
+
+	lp.matches, lp.cur, lp.top = lp.matches[:0], 0, 0
+	for i, option := range lp.options {
+		if strings.Contains(strings.ToLower(option), filter) {
+			if i == keep {
+				lp.cur = len(lp.matches)
+			}
+			lp.matches = append(lp.matches, i)
+		}
+	}
+
+	lp.move(0)
+}
+
+// move moves the cursor by n options, wrapping around the ends of the list for a single step.
+func (lp *listPrompt) move(n int) {
+	count := len(lp.matches)
+	if count == 0 {
+		return
+	}
+
+	switch next := lp.cur + n; {
+	case n == 1 || n == -1:
+		lp.cur = (next + count) % count
+	default:
+		lp.cur = min(max(next, 0), count-1)
+	}
+
+	// Keep the cursor in the page shown.
+	if lp.cur < lp.top {
+		lp.top = lp.cur
+	} else if lp.cur >= lp.top+selectPageSize {
+		lp.top = lp.cur - selectPageSize + 1
+	}
+}
+
+// toggleAll checks every option shown, or unchecks them if they all are checked.
+func (lp *listPrompt) toggleAll() {
+	all := true
+	for _, i := range lp.matches {
+		all = all && lp.checked[i]
+	}
+
+	for _, i := range lp.matches {
+		lp.checked[i] = !all
+	}
+}
+
+func (lp *listPrompt) picked() []int {
+	picked := []int{}
+	for i, on := range lp.checked {
+		if on {
+			picked = append(picked, i)
+		}
+	}
+
+	sort.Ints(picked)
+	return picked
+}
+
+// answer returns the options picked, as shown once the prompt is done.
+func (lp *listPrompt) answer() string {
+	if lp.checked == nil {
+		return lp.options[lp.matches[lp.cur]]
+	}
+
+	var names []string
+	for _, i := range lp.picked() {
+		names = append(names, lp.options[i])
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (lp *listPrompt) draw(v *promptView) error {
+	var (
+		head      = lp.question + " " + string(lp.filter)
+		rows      = []promptRow{{head, v.attr}}
+		highlight = v.attr&0xf0 | ForegroundGreen | ForegroundBlue | ForegroundIntensity
+	)
+
+	for n, i := range lp.matches[lp.top:min(lp.top+selectPageSize, len(lp.matches))] {
+		row := promptRow{"  ", v.attr}
+		if lp.top+n == lp.cur {
+			row = promptRow{"> ", highlight}
+		}
+
+		if lp.checked != nil {
+			if lp.checked[i] {
+				row.text += "[x] "
+			} else {
+				row.text += "[ ] "
+			}
+		}
+
+		row.text += lp.options[i]
+		rows = append(rows, row)
+	}
+
+	if len(lp.matches) == 0 {
+		rows = append(rows, promptRow{"  no match", v.attr&0xf0 | ForegroundIntensity})
+	}
+
+	return v.draw(rows, Coord{X: int16(StringWidth(head))})
+}
+
+// promptRow is a row of text drawn by a prompt, in a single set of attributes.
+type promptRow struct {
+	text string
+	attr uint16
+}
+
+// promptView draws the rows of a prompt from the position of the cursor when the prompt starts,
+// redrawing them in place. Rows are clipped at the end of the screen buffer.
+type promptView struct {
+	out    OutputTarget
+	origin Coord  // The position of the first cell of the first row.
+	attr   uint16 // The attributes of the console when the prompt started.
+	drawn  int16  // The number of rows drawn.
+}
+
+func newPromptView(out OutputTarget) (*promptView, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return nil, err
+	}
+
+	return &promptView{out: out, origin: scrbufinfo.CursorPosition, attr: scrbufinfo.Attributes}, nil
+}
+
+// draw writes the rows, blanks the rows left over from the previous draw and places the cursor
+// at a position relative to the start of the first row.
+func (v *promptView) draw(rows []promptRow, cursor Coord) error {
+	var scrbufinfo ScreenBufferInfo
+	if err := v.out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return err
+	}
+
+	n, err := scrollToRow(v.out, v.origin.Y+int16(len(rows))-1, v.attr)
+	if err != nil {
+		return err
+	}
+	v.origin.Y -= n
+
+	for y := int16(0); y < max(int16(len(rows)), v.drawn); y++ {
+		left := int16(0)
+		if y == 0 {
+			left = v.origin.X
+		}
+
+		if v.origin.Y+y < 0 {
+			continue
+		}
+
+		var cells []CharInfo
+		if int(y) < len(rows) {
+			cells = fitCells(rows[y].text, rows[y].attr, int(scrbufinfo.Size.X-left))
+		}
+
+		cells = padCells(cells, int(scrbufinfo.Size.X-left), v.attr)
+		if err := writeCells(v.out, cells, Coord{X: left, Y: v.origin.Y + y}); err != nil {
+			return err
+		}
+	}
+	v.drawn = int16(len(rows))
+
+	if cursor.Y == 0 {
+		cursor.X += v.origin.X
+	}
+
+	return v.out.SetCursorPosition(Coord{X: min(cursor.X, scrbufinfo.Size.X-1), Y: v.origin.Y + cursor.Y})
+}
+
+// done draws the final rows and moves the cursor to the start of the row below them.
+func (v *promptView) done(rows []promptRow) error {
+	if err := v.draw(rows, Coord{}); err != nil {
+		return err
+	}
+
+	y := v.origin.Y + v.drawn
+	n, err := scrollToRow(v.out, y, v.attr)
+	if err != nil {
+		return err
+	}
+
+	return v.out.SetCursorPosition(Coord{Y: y - n})
+}
+
+// abort ends a prompt without an answer, returning err unless the final rows cannot be drawn.
+func (v *promptView) abort(rows []promptRow, err error) error {
+	if derr := v.done(rows); derr != nil {
+		return derr
+	}
+
+	return err
+}
+
+// padCells appends blanks in attr to cells up to n cells.
+func padCells(cells []CharInfo, n int, attr uint16) []CharInfo {
+	for len(cells) < n {
+		cells = append(cells, CharInfo{UnicodeChar: ' ', Attributes: attr})
+	}
+
+	return cells
+}
@@ -0,0 +1,183 @@
+package cons
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyScript(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []KeyEventRecord
+	}{
+		{"a", []KeyEventRecord{{KeyDown: 1, RepeatCount: 1, VirtualKeyCode: 'A', UnicodeChar: 'a'}}},
+		{"<Enter>", []KeyEventRecord{keyNames["enter"]}},
+		{"<down>", []KeyEventRecord{keyNames["down"]}},
+		{"<<", []KeyEventRecord{{KeyDown: 1, RepeatCount: 1, UnicodeChar: '<'}}},
+		{"<x", []KeyEventRecord{{KeyDown: 1, RepeatCount: 1, UnicodeChar: '<'}, {KeyDown: 1, RepeatCount: 1, VirtualKeyCode: 'X', UnicodeChar: 'x'}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			events := KeyScript(tt.script)
+			if len(events) != len(tt.want) {
+				t.Fatalf("KeyScript(%q) = %d events, want %d", tt.script, len(events), len(tt.want))
+			}
+
+			for i, ev := range events {
+				key := ev.(KeyEventRecord)
+				want := tt.want[i]
+				if key.KeyDown != want.KeyDown || key.VirtualKeyCode != want.VirtualKeyCode || key.UnicodeChar != want.UnicodeChar {
+					t.Errorf("event %d = %+v, want %+v", i, key, want)
+				}
+			}
+		})
+	}
+
+	ctrlC := KeyScript("<C-c>")[0].(KeyEventRecord)
+	if ctrlC.UnicodeChar != 0x03 || ctrlC.ControlKeyState&LeftCtrlPressed == 0 {
+		t.Errorf("<C-c> = %+v, want Ctrl+C", ctrlC)
+	}
+}
+
+// typed returns a console whose input holds the keys of a key script.
+func typed(t *testing.T, script string) *VirtualConsole {
+	t.Helper()
+
+	vc := NewVirtualConsole(40, 15)
+	if err := vc.VirtualInput().Type(script); err != nil {
+		t.Fatal(err)
+	}
+
+	return vc
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		script string
+		def    bool
+		want   bool
+		err    error
+		line   string
+	}{
+		{"y", false, true, nil, "Overwrite? Yes"},
+		{"N", true, false, nil, "Overwrite? No"},
+		{"<Enter>", true, true, nil, "Overwrite? Yes"},
+		{"<Enter>", false, false, nil, "Overwrite? No"},
+		{"xq<Down>y", false, true, nil, "Overwrite? Yes"},
+		{"<C-c>", true, true, ErrInterrupted, "Overwrite?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			vc := typed(t, tt.script)
+
+			got, err := Confirm(vc.Input(), vc.Output(), "Overwrite?", tt.def)
+			if got != tt.want || err != tt.err {
+				t.Fatalf("Confirm = %v, %v, want %v, %v", got, err, tt.want, tt.err)
+			}
+			if line := vc.Line(0); line != tt.line {
+				t.Errorf("line = %q, want %q", line, tt.line)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	options := []string{"red", "green", "blue"}
+
+	tests := []struct {
+		script string
+		def    int
+		want   int
+		err    error
+		line   string
+	}{
+		{"<Enter>", 0, 0, nil, "Color: red"},
+		{"<Enter>", 2, 2, nil, "Color: blue"},
+		{"<Down><Enter>", 0, 1, nil, "Color: green"},
+		{"<End><Enter>", 0, 2, nil, "Color: blue"},
+		{"<Down><Down><Home><Enter>", 0, 0, nil, "Color: red"},
+		{"bl<Enter>", 0, 2, nil, "Color: blue"},
+		{"gx<Backspace><Enter>", 0, 1, nil, "Color: green"},
+		{"<C-c>", 0, -1, ErrInterrupted, "Color:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			vc := typed(t, tt.script)
+
+			got, err := Select(vc.Input(), vc.Output(), "Color:", options, tt.def)
+			if got != tt.want || err != tt.err {
+				t.Fatalf("Select = %d, %v, want %d, %v", got, err, tt.want, tt.err)
+			}
+			if line := vc.Line(0); line != tt.line {
+				t.Errorf("line = %q, want %q", line, tt.line)
+			}
+			if line := vc.Line(1); line != "" {
+				t.Errorf("list left on screen: %q", line)
+			}
+		})
+	}
+
+	if _, err := Select(NewVirtualInput(), NewVirtualConsole(10, 2), "Color:", nil, 0); err != ErrInvalidParameter {
+		t.Errorf("Select without options = %v, want ErrInvalidParameter", err)
+	}
+}
+
+func TestMultiSelect(t *testing.T) {
+	options := []string{"cache", "logs", "metrics"}
+
+	tests := []struct {
+		script  string
+		checked []int
+		want    []int
+	}{
+		{"<Enter>", nil, nil},
+		{"<Enter>", []int{2, 0}, []int{0, 2}},
+		{"<Space><Down><Down><Space><Enter>", nil, []int{0, 2}},
+		{"<Space><Enter>", []int{0, 1}, []int{1}},
+		{"<C-a><Enter>", nil, []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			vc := typed(t, tt.script)
+
+			got, err := MultiSelect(vc.Input(), vc.Output(), "Features:", options, tt.checked)
+			if err != nil || len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("MultiSelect = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadInt(t *testing.T) {
+	tests := []struct {
+		script string
+		want   int
+		err    error
+		lines  []string
+	}{
+		{"42<Enter>", 42, nil, []string{"Port: 42"}},
+		{"abc<Enter>8080<Enter>", 8080, nil, []string{"Port: abc", "Please enter a whole number.", "Port: 8080"}},
+		{"0<Enter> 7 <Enter>", 7, nil, []string{"Port: 0", "Please enter a number from 1 to 65535.", "Port:  7"}},
+		{"1<C-c>", 0, ErrInterrupted, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			vc := typed(t, tt.script)
+
+			got, err := ReadInt(vc.Input(), vc.Output(), "Port:", 1, 65535)
+			if got != tt.want || err != tt.err {
+				t.Fatalf("ReadInt = %d, %v, want %d, %v", got, err, tt.want, tt.err)
+			}
+			for y, want := range tt.lines {
+				if line := vc.Line(int16(y)); line != want {
+					t.Errorf("line %d = %q, want %q", y, line, want)
+				}
+			}
+		})
+	}
+}
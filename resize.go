@@ -0,0 +1,97 @@
+package cons
+
+import (
+	"context"
+	"time"
+)
+
+// ResizeDebounce is how long an EventLoop watching the size of the console waits after the last
+// WindowBufferSizeRecord before reporting a ResizeEvent, as dragging the edge of a window reports many sizes.
+const ResizeDebounce = 100 * time.Millisecond
+
+// ResizeEvent reports the size of a console once it has settled after a change, see EventLoop.WatchResize.
+type ResizeEvent struct {
+	Size   Coord     // The size of the screen buffer.
+	Window SmallRect // The visible window, in screen buffer coordinates.
+}
+
+// EventType reports a resize as a window buffer size event, the record it replaces.
+func (ResizeEvent) EventType() uint16 { return WindowBufferSizeEvent }
+
+// WatchResize makes the loop deliver a ResizeEvent, with the size of the screen buffer and the window of out,
+// once the console has not been resized for ResizeDebounce. It enables EnableWindowInput on the input, restored
+// by Close, and the WindowBufferSizeRecords the console then reports are no longer delivered themselves.
+//
+// Consoles report a change of the size of the screen buffer, VirtualConsole.Resize queues one like them, and on
+// Unix every SIGWINCH is reported as the new size of the terminal. A ResizeEvent is delivered while the loop
+// waits for input, so only when the input source is an InputWaiter.
+//
+// Parameters:
+//
+//	out: The output side of the console whose size is reported.
+//
+// Returns:
+//
+//	error: If the function successfully enables window input, it returns nil. Otherwise, it returns an error.
+func (l *EventLoop) WatchResize(out OutputTarget) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	mode, err := l.in.GetMode()
+	if err != nil {
+		return err
+	}
+
+	if err := l.in.SetMode(mode | EnableWindowInput); err != nil {
+		return err
+	}
+
+	if !l.restore {
+		l.mode, l.restore = mode, true
+	}
+	l.resize = out
+
+	return nil
+}
+
+// output returns the output watched for resizes, nil before WatchResize.
+func (l *EventLoop) output() OutputTarget {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.resize
+}
+
+// filterResize drops the WindowBufferSizeRecords of a watched console, postponing the ResizeEvent instead.
+func (l *EventLoop) filterResize(events []Event) []Event {
+	if l.output() == nil {
+		return events
+	}
+
+	kept := events[:0]
+	for _, ev := range events {
+		if _, ok := ev.(WindowBufferSizeRecord); ok {
+			l.settle = time.Now().Add(ResizeDebounce)
+			continue
+		}
+
+		kept = append(kept, ev)
+	}
+
+	return kept
+}
+
+// settleResize sends the postponed ResizeEvent once it is due, reporting false if ctx is done first.
+func (l *EventLoop) settleResize(ctx context.Context) bool {
+	if l.settle.IsZero() || time.Now().Before(l.settle) {
+		return true
+	}
+	l.settle = time.Time{}
+
+	var scrbufinfo ScreenBufferInfo
+	if err := l.output().GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return true
+	}
+
+	return l.send(ctx, []Event{ResizeEvent{Size: scrbufinfo.Size, Window: scrbufinfo.Window}})
+}
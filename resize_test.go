@@ -0,0 +1,90 @@
+package cons
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchResize(t *testing.T) {
+	vc := NewVirtualConsole(20, 5)
+	l, err := NewEventLoop(context.Background(), vc.Input(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	saved, _ := vc.Input().GetMode()
+	if err := l.WatchResize(vc); err != nil {
+		t.Fatal(err)
+	}
+	if mode, _ := vc.Input().GetMode(); !mode.IsEnableMode(EnableWindowInput) {
+		t.Errorf("mode = %#x, want EnableWindowInput", mode)
+	}
+
+	// Resizes within ResizeDebounce of each other are reported once, with the last size.
+	for _, size := range []Coord{{X: 30, Y: 6}, {X: 35, Y: 7}, {X: 40, Y: 8}} {
+		if err := vc.Resize(size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := vc.VirtualInput().Type("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	var resizes []ResizeEvent
+	keys := 0
+	for deadline := time.Now().Add(2 * time.Second); len(resizes) == 0 || keys == 0; {
+		ev, err := l.NextTimeout(time.Until(deadline))
+		if err != nil {
+			t.Fatalf("Next() error = %v, got %d resizes and %d keys", err, len(resizes), keys)
+		}
+
+		switch ev := ev.(type) {
+		case ResizeEvent:
+			resizes = append(resizes, ev)
+		case KeyEventRecord:
+			keys++
+		default:
+			t.Errorf("event %#v delivered, want only resize and key events", ev)
+		}
+	}
+
+	if ev, err := l.NextTimeout(3 * ResizeDebounce); err == nil {
+		t.Errorf("event %#v delivered after the resize, want none", ev)
+	}
+
+	want := ResizeEvent{Size: Coord{X: 40, Y: 8}, Window: SmallRect{Right: 39, Bottom: 7}}
+	if len(resizes) != 1 || resizes[0] != want {
+		t.Errorf("resize events = %+v, want [%+v]", resizes, want)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if mode, _ := vc.Input().GetMode(); mode != saved {
+		t.Errorf("mode after Close = %#x, want %#x", mode, saved)
+	}
+}
+
+func TestEventLoopWindowInput(t *testing.T) {
+	vc := NewVirtualConsole(20, 5)
+	l, err := NewEventLoop(context.Background(), vc.Input(), EnableWindowInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Without WatchResize, the records of the console are delivered as they are.
+	if err := vc.Resize(Coord{X: 30, Y: 6}); err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := l.NextTimeout(2 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (WindowBufferSizeRecord{Size: Coord{X: 30, Y: 6}}); ev != want {
+		t.Errorf("event = %#v, want %#v", ev, want)
+	}
+}
@@ -0,0 +1,306 @@
+package cons
+
+import (
+	"io"
+	"strings"
+)
+
+// screenVTGap is the longest run of unchanged cells that Flush rewrites in VT mode
+// rather than moving the cursor over it, which costs about as many bytes.
+const screenVTGap = 4
+
+// blankCell is the cell a Screen starts with: a space in the default console colors.
+var blankCell = CharInfo{UnicodeChar: ' ', Attributes: ForegroundBlue | ForegroundGreen | ForegroundRed}
+
+// Screen is a double-buffered view of the console window. Drawing goes to a back buffer held in memory,
+// and Flush sends only the cells that differ from the front buffer, the cells last sent, so a frame can be
+// redrawn from scratch without the flicker of clearing the screen buffer first.
+//
+// A Screen is not safe for concurrent use.
+type Screen struct {
+	out    OutputTarget // The console updated by Flush, nil in VT mode.
+	vt     io.Writer    // The terminal updated by Flush in VT mode.
+	origin Coord        // The position of the top left cell of the screen in the screen buffer.
+	size   Coord
+	front  []CharInfo
+	back   []CharInfo
+	dirty  SmallRect // The rectangle holding every cell of the back buffer that differs from the front buffer.
+	marked bool      // Indicates whether the dirty rectangle holds cells to send.
+	stale  bool      // Indicates whether the front buffer is unknown, so every cell is sent on the next Flush.
+}
+
+// NewScreen creates a screen covering the current window of a console. Flush writes each run of changed cells
+// in one call, WriteConsoleOutputW on a Windows console, or as runs of WriteAttribute and WriteCharacter calls
+// on an output that cannot write cells directly.
+//
+// Parameters:
+//
+//	out: The output side of the console.
+//
+// Returns:
+//
+//	*Screen: The new screen, whose first Flush paints every cell.
+//	error: If the function successfully retrieves the window, it returns nil. Otherwise, it returns an error.
+func NewScreen(out OutputTarget) (*Screen, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return nil, err
+	}
+
+	window := scrbufinfo.Window
+	s := &Screen{out: out, origin: Coord{X: window.Left, Y: window.Top}}
+	s.Resize(Coord{X: window.Right - window.Left + 1, Y: window.Bottom - window.Top + 1})
+
+	return s, nil
+}
+
+// NewVTScreen creates a screen of the given size whose top left cell is the top left cell of a VT terminal.
+// Flush updates the terminal with cursor movements, SGR sequences and text.
+//
+// Parameters:
+//
+//	w: The terminal output, such as os.Stdout.
+//	width: The number of columns of the screen.
+//	height: The number of rows of the screen.
+//
+// Returns:
+//
+//	*Screen: The new screen, whose first Flush paints every cell.
+func NewVTScreen(w io.Writer, width, height int16) *Screen {
+	s := &Screen{vt: w}
+	s.Resize(Coord{X: width, Y: height})
+
+	return s
+}
+
+// Size returns the number of columns and rows of the screen.
+func (s *Screen) Size() Coord {
+	return s.size
+}
+
+// Resize changes the size of the screen. The cells that remain inside it are kept in the back buffer,
+// new cells are blank, and the next Flush paints every cell.
+//
+// Parameters:
+//
+//	size: The new number of columns and rows.
+func (s *Screen) Resize(size Coord) {
+	size.X, size.Y = max(size.X, 1), max(size.Y, 1)
+
+	back := make([]CharInfo, int(size.X)*int(size.Y))
+	for i := range back {
+		back[i] = blankCell
+	}
+
+	for y := int16(0); y < min(size.Y, s.size.Y); y++ {
+		copy(back[int(y)*int(size.X):int(y+1)*int(size.X)], s.back[int(y)*int(s.size.X):][:min(size.X, s.size.X)])
+	}
+
+	s.size, s.back, s.front = size, back, make([]CharInfo, len(back))
+	s.Invalidate()
+}
+
+// Invalidate discards what the screen knows about the display, so the next Flush paints every cell.
+// It is needed after something else has written to the console.
+func (s *Screen) Invalidate() {
+	s.stale = true
+	s.touch(SmallRect{Right: s.size.X - 1, Bottom: s.size.Y - 1})
+}
+
+// Cell returns the cell of the back buffer at the given position, or a zero CharInfo outside the screen.
+func (s *Screen) Cell(x, y int16) CharInfo {
+	if x < 0 || y < 0 || x >= s.size.X || y >= s.size.Y {
+		return CharInfo{}
+	}
+
+	return s.back[int(y)*int(s.size.X)+int(x)]
+}
+
+// SetCell draws a cell in the back buffer. Positions outside the screen are ignored.
+//
+// Parameters:
+//
+//	x: The column of the cell.
+//	y: The row of the cell.
+//	cell: The character and attributes to draw.
+func (s *Screen) SetCell(x, y int16, cell CharInfo) {
+	if x < 0 || y < 0 || x >= s.size.X || y >= s.size.Y {
+		return
+	}
+
+	i := int(y)*int(s.size.X) + int(x)
+	if s.back[i] == cell {
+		return
+	}
+
+	s.back[i] = cell
+	s.touch(SmallRect{Left: x, Top: y, Right: x, Bottom: y})
+}
+
+// SetString draws text on a row of the back buffer, laid out in cells as TextCells does and clipped to the screen.
+// A wide character that does not fit in the last column leaves it blank.
+//
+// Parameters:
+//
+//	x: The column of the first character.
+//	y: The row of the text.
+//	text: The text to draw.
+//	attr: The attributes of the cells.
+//
+// Returns:
+//
+//	int16: The column following the last cell drawn.
+func (s *Screen) SetString(x, y int16, text string, attr uint16) int16 {
+	for _, cell := range fitCells(text, attr, int(s.size.X)-int(x)) {
+		s.SetCell(x, y, cell)
+		x++
+	}
+
+	return x
+}
+
+// Fill draws a cell over every position of a rectangle of the back buffer, clipped to the screen.
+//
+// Parameters:
+//
+//	rect: The rectangle to fill, inclusive on all sides.
+//	cell: The character and attributes to draw.
+func (s *Screen) Fill(rect SmallRect, cell CharInfo) {
+	rect, ok := intersectRect(rect, SmallRect{Right: s.size.X - 1, Bottom: s.size.Y - 1})
+	if !ok {
+		return
+	}
+
+	for y := rect.Top; y <= rect.Bottom; y++ {
+		for x := rect.Left; x <= rect.Right; x++ {
+			s.SetCell(x, y, cell)
+		}
+	}
+}
+
+// Clear fills the whole back buffer with spaces in the given attributes.
+//
+// Parameters:
+//
+//	attr: The attributes of the cells.
+func (s *Screen) Clear(attr uint16) {
+	s.Fill(SmallRect{Right: s.size.X - 1, Bottom: s.size.Y - 1}, CharInfo{UnicodeChar: ' ', Attributes: attr})
+}
+
+// touch extends the dirty rectangle to cover rect.
+func (s *Screen) touch(rect SmallRect) {
+	if s.marked {
+		s.dirty = SmallRect{
+			Left:   min(s.dirty.Left, rect.Left),
+			Top:    min(s.dirty.Top, rect.Top),
+			Right:  max(s.dirty.Right, rect.Right),
+			Bottom: max(s.dirty.Bottom, rect.Bottom),
+		}
+		return
+	}
+
+	s.dirty, s.marked = rect, true
+}
+
+// Flush sends the cells of the back buffer that differ from the front buffer to the console or terminal,
+// then makes them the front buffer.
+//
+// Returns:
+//
+//	error: If the function successfully updates the display, it returns nil. Otherwise, it returns an error.
+func (s *Screen) Flush() error {
+	if !s.marked {
+		return nil
+	}
+
+	var err error
+	if s.out != nil {
+		err = s.flushConsole()
+	} else {
+		err = s.flushVT()
+	}
+	if err != nil {
+		return err
+	}
+
+	for y := s.dirty.Top; y <= s.dirty.Bottom; y++ {
+		row := int(y) * int(s.size.X)
+		copy(s.front[row+int(s.dirty.Left):row+int(s.dirty.Right)+1], s.back[row+int(s.dirty.Left):])
+	}
+
+	s.marked, s.stale = false, false
+	return nil
+}
+
+// changed reports whether the cell at index i must be sent.
+func (s *Screen) changed(i int) bool {
+	return s.stale || s.back[i] != s.front[i]
+}
+
+// flushConsole sends the dirty cells as one write per run of changed cells, widened so that it
+// does not split a wide character or a surrogate pair.
+func (s *Screen) flushConsole() error {
+	for y := s.dirty.Top; y <= s.dirty.Bottom; y++ {
+		row := s.back[int(y)*int(s.size.X):][:s.size.X]
+
+		for x := int(s.dirty.Left); x <= int(s.dirty.Right); {
+			if !s.changed(int(y)*int(s.size.X) + x) {
+				x++
+				continue
+			}
+
+			end := x
+			for end < int(s.dirty.Right) && s.changed(int(y)*int(s.size.X)+end+1) {
+				end++
+			}
+
+			from, to := cellSpan(row, x, end)
+			if err := writeCells(s.out, row[from:to+1], Coord{X: s.origin.X + int16(from), Y: s.origin.Y + y}); err != nil {
+				return err
+			}
+			x = to + 1
+		}
+	}
+
+	return nil
+}
+
+// flushVT sends the dirty cells as a single write of cursor movements, SGR sequences and text.
+// Runs of changed cells separated by at most screenVTGap unchanged cells are sent as one.
+func (s *Screen) flushVT() error {
+	var sb strings.Builder
+
+	for y := s.dirty.Top; y <= s.dirty.Bottom; y++ {
+		row := int(y) * int(s.size.X)
+
+		for x := s.dirty.Left; x <= s.dirty.Right; {
+			if !s.changed(row + int(x)) {
+				x++
+				continue
+			}
+
+			// Extend the run over short gaps of unchanged cells.
+			end, gap := x, 0
+			for next := x + 1; next <= s.dirty.Right && gap <= screenVTGap; next++ {
+				if s.changed(row + int(next)) {
+					end, gap = next, 0
+				} else {
+					gap++
+				}
+			}
+
+			from, to := cellSpan(s.back[row:row+int(s.size.X)], int(x), int(end))
+			sb.WriteString(cup(Coord{X: int16(from), Y: y}))
+			appendVTCells(&sb, s.back[row+from:row+to+1])
+			x = int16(to) + 1
+		}
+	}
+
+	if sb.Len() == 0 {
+		return nil
+	}
+
+	sb.WriteString("\x1b[0m")
+	_, err := io.WriteString(s.vt, sb.String())
+	return err
+}
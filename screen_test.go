@@ -0,0 +1,117 @@
+package cons
+
+import (
+	"io"
+	"testing"
+)
+
+// cellCounter is a VirtualConsole counting the cells written to it.
+type cellCounter struct {
+	*VirtualConsole
+	writes int
+	cells  int
+}
+
+func (c *cellCounter) writeCells(cells []CharInfo, wcoord Coord) error {
+	c.writes++
+	c.cells += len(cells)
+	return c.VirtualConsole.writeCells(cells, wcoord)
+}
+
+func TestScreenFlush(t *testing.T) {
+	out := &cellCounter{VirtualConsole: NewVirtualConsole(20, 5)}
+	s, err := NewScreen(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flush := func(name string, writes, cells int) {
+		t.Helper()
+
+		out.writes, out.cells = 0, 0
+		if err := s.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if out.writes != writes || out.cells != cells {
+			t.Errorf("%s: %d writes of %d cells, want %d of %d", name, out.writes, out.cells, writes, cells)
+		}
+		if s.marked {
+			t.Errorf("%s: dirty rectangle left marked", name)
+		}
+	}
+
+	s.SetString(0, 0, "hello", blankCell.Attributes)
+	flush("first", 5, 100)
+	if line := out.Line(0); line != "hello" {
+		t.Errorf("line = %q, want %q", line, "hello")
+	}
+
+	flush("unchanged", 0, 0)
+
+	s.SetCell(3, 2, CharInfo{UnicodeChar: 'X', Attributes: blankCell.Attributes})
+	if want := (SmallRect{Left: 3, Top: 2, Right: 3, Bottom: 2}); s.dirty != want {
+		t.Errorf("dirty = %+v, want %+v", s.dirty, want)
+	}
+	flush("one cell", 1, 1)
+	if cell := out.Cell(3, 2); cell.UnicodeChar != 'X' {
+		t.Errorf("cell = %+v, want X", cell)
+	}
+
+	// The dirty rectangle spans both cells, but the unchanged cells between them are not sent.
+	s.SetCell(1, 4, CharInfo{UnicodeChar: 'a', Attributes: blankCell.Attributes})
+	s.SetCell(18, 4, CharInfo{UnicodeChar: 'b', Attributes: blankCell.Attributes})
+	if want := (SmallRect{Left: 1, Top: 4, Right: 18, Bottom: 4}); s.dirty != want {
+		t.Errorf("dirty = %+v, want %+v", s.dirty, want)
+	}
+	flush("two cells", 2, 2)
+
+	// A cell drawn back to the value last sent is not sent again.
+	s.SetCell(0, 0, CharInfo{UnicodeChar: 'j', Attributes: blankCell.Attributes})
+	s.SetCell(0, 0, CharInfo{UnicodeChar: 'h', Attributes: blankCell.Attributes})
+	flush("restored", 0, 0)
+
+	s.SetCell(5, 1, CharInfo{UnicodeChar: 'Y', Attributes: blankCell.Attributes})
+	if want := (SmallRect{Left: 5, Top: 1, Right: 5, Bottom: 1}); s.dirty != want {
+		t.Errorf("dirty after Flush = %+v, want %+v", s.dirty, want)
+	}
+
+	s.Invalidate()
+	flush("invalidated", 5, 100)
+}
+
+func BenchmarkFlush(b *testing.B) {
+	targets := []struct {
+		name   string
+		screen func() *Screen
+	}{
+		{"console", func() *Screen {
+			s, _ := NewScreen(NewVirtualConsole(80, 25))
+			return s
+		}},
+		{"vt", func() *Screen { return NewVTScreen(io.Discard, 80, 25) }},
+	}
+
+	for _, target := range targets {
+		b.Run(target.name+"/full repaint", func(b *testing.B) {
+			s := target.screen()
+			s.Flush()
+
+			attrs := [2]uint16{ForegroundRed, ForegroundGreen}
+			for i := 0; i < b.N; i++ {
+				s.Clear(attrs[i%2])
+				s.Flush()
+			}
+		})
+
+		b.Run(target.name+"/one cell", func(b *testing.B) {
+			s := target.screen()
+			s.Flush()
+
+			chars := [2]uint16{'a', 'b'}
+			for i := 0; i < b.N; i++ {
+				s.SetCell(40, 12, CharInfo{UnicodeChar: chars[i%2], Attributes: blankCell.Attributes})
+				s.Flush()
+			}
+		})
+	}
+}
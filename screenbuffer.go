@@ -0,0 +1,119 @@
+//go:build windows || linux || darwin || freebsd || netbsd || openbsd
+
+package cons
+
+import "sync"
+
+// ScreenBuffer is a screen buffer created besides the one of the standard output, for full-screen programs
+// to draw off screen and leave the scrollback of the user as it was on exit. On Unix, the buffer is displayed
+// in the alternate screen of the terminal.
+//
+//	sb, err := cons.NewScreenBuffer()
+//	if err != nil {
+//		log.Fatalln(err)
+//	}
+//	defer sb.Close()
+//
+//	cons.ClearScreenBuffer(sb.Handle())
+//	sb.Activate()
+type ScreenBuffer struct {
+	h        Handle
+	original Handle // The screen buffer displayed again by Restore and Close.
+	mu       sync.Mutex
+	active   bool // Indicates whether the buffer was activated and not restored since.
+	closed   bool
+}
+
+// NewScreenBuffer creates a screen buffer, which stays off screen until Activate is called.
+//
+// Returns:
+//
+//	*ScreenBuffer: The new screen buffer.
+//	error: If the function successfully creates the screen buffer, it returns nil. Otherwise, it returns an error.
+func NewScreenBuffer() (*ScreenBuffer, error) {
+	original, err := GetStdHandle(StdOutputHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := CreateScreenBuffer()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScreenBuffer{h: h, original: original}, nil
+}
+
+// Handle returns the handle of the screen buffer, which is the OutputTarget to draw into it.
+func (sb *ScreenBuffer) Handle() Handle {
+	return sb.h
+}
+
+// Activate displays the screen buffer in place of the standard output.
+//
+// Returns:
+//
+//	error: If the function successfully activates the screen buffer, it returns nil. Otherwise, it returns an error.
+func (sb *ScreenBuffer) Activate() error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	if sb.closed {
+		return ErrInvalidParameter
+	}
+
+	if err := SetActiveScreenBuffer(sb.h); err != nil {
+		return err
+	}
+
+	sb.active = true
+	return nil
+}
+
+// Restore displays the standard output again, as it was before Activate.
+//
+// Returns:
+//
+//	error: If the function successfully activates the standard output, it returns nil. Otherwise, it returns an error.
+func (sb *ScreenBuffer) Restore() error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	return sb.restore()
+}
+
+func (sb *ScreenBuffer) restore() error {
+	if !sb.active {
+		return nil
+	}
+
+	if err := SetActiveScreenBuffer(sb.original); err != nil {
+		return err
+	}
+
+	sb.active = false
+	return nil
+}
+
+// Close restores the standard output if the screen buffer is displayed, and closes the screen buffer.
+// Calling Close again does nothing.
+//
+// Returns:
+//
+//	error: If the function successfully restores the standard output and closes the buffer, it returns nil.
+//	Otherwise, it returns an error.
+func (sb *ScreenBuffer) Close() error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	if sb.closed {
+		return nil
+	}
+
+	if err := sb.restore(); err != nil {
+		return err
+	}
+
+	sb.closed = true
+	return CloseHandle(sb.h)
+}
@@ -0,0 +1,23 @@
+package cons
+
+import "strconv"
+
+// attributesSGR converts a console Attributes word to the SGR escape sequence that selects the same
+// colors, underline and reverse video on a VT terminal. The sequence always starts with a reset.
+// Light gray on black, the default console colors, selects the default colors of the terminal.
+//
+// Parameters:
+//
+//	attr: The console attributes to convert.
+//
+// Returns:
+//
+//	string: The SGR escape sequence.
+func attributesSGR(attr uint16) string {
+	return StyleFromAttributes(attr).SGR(ProfileANSI16)
+}
+
+// cup returns the VT sequence moving the cursor to pos.
+func cup(pos Coord) string {
+	return "\x1b[" + strconv.Itoa(int(pos.Y)+1) + ";" + strconv.Itoa(int(pos.X)+1) + "H"
+}
@@ -0,0 +1,162 @@
+package cons
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// State is a snapshot of the settings of a console that programs commonly change: the input and output modes,
+// the code pages, the cursor, the text attributes and the window title. Restoring it undoes those changes,
+// which keeps the console usable after a program that hid the cursor or switched to raw input exits.
+type State struct {
+	console    Console
+	inputMode  DWord
+	outputMode DWord
+	inputCP    uint32
+	outputCP   uint32
+	curinfo    CursorInfo
+	attributes uint16
+	title      string
+	once       sync.Once
+	err        error // The result of the first Restore.
+}
+
+// SaveState captures the current settings of a console.
+//
+// Parameters:
+//
+//	console: The console whose settings are captured.
+//
+// Returns:
+//
+//	*State: The snapshot, to be restored with Restore.
+//	error: If the function successfully retrieves every setting, it returns nil. Otherwise, it returns an error.
+func SaveState(console Console) (*State, error) {
+	var (
+		s          = &State{console: console}
+		scrbufinfo ScreenBufferInfo
+		err        error
+	)
+
+	if s.inputMode, err = console.Input().GetMode(); err != nil {
+		return nil, err
+	}
+
+	if s.outputMode, err = console.Output().GetMode(); err != nil {
+		return nil, err
+	}
+
+	if s.inputCP, err = console.GetInputCodePage(); err != nil {
+		return nil, err
+	}
+
+	if s.outputCP, err = console.GetOutputCodePage(); err != nil {
+		return nil, err
+	}
+
+	if s.curinfo, err = console.Output().GetCursorInfo(); err != nil {
+		return nil, err
+	}
+
+	if err := console.Output().GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return nil, err
+	}
+	s.attributes = scrbufinfo.Attributes
+
+	if s.title, err = console.GetWindowTitle(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Restore puts the settings captured by SaveState back. Every setting is restored even if an earlier one fails.
+// Only the first call has an effect, so Restore can be deferred and also registered with RestoreOnSignal.
+//
+// Returns:
+//
+//	error: If the function successfully restores every setting, it returns nil. Otherwise, it returns the errors joined.
+func (s *State) Restore() error {
+	s.once.Do(func() {
+		in, out := s.console.Input(), s.console.Output()
+		curinfo := s.curinfo
+
+		s.err = errors.Join(
+			in.SetMode(s.inputMode),
+			out.SetMode(s.outputMode),
+			s.console.SetInputCodePage(s.inputCP),
+			s.console.SetOutputCodePage(s.outputCP),
+			out.SetCursorInfo(&curinfo),
+			out.SetTextAttribute(s.attributes),
+			s.restoreTitle(),
+		)
+	})
+
+	return s.err
+}
+
+// restoreTitle sets the title back only if it changed, as a terminal whose title was never set
+// through this package reports an empty title that must not replace the one it shows.
+func (s *State) restoreTitle() error {
+	if title, err := s.console.GetWindowTitle(); err == nil && title == s.title {
+		return nil
+	}
+
+	return s.console.SetWindowTitle(s.title)
+}
+
+// RestoreOnPanic restores the state if the program is panicking, then lets the panic continue.
+// It must be deferred directly:
+//
+//	defer cons.RestoreOnPanic(state)
+//
+// Parameters:
+//
+//	s: The state to restore.
+func RestoreOnPanic(s *State) {
+	if r := recover(); r != nil {
+		s.Restore()
+		panic(r)
+	}
+}
+
+// RestoreOnSignal restores the state and exits when the process receives one of the given signals, as the
+// default action of those signals would end the process without restoring anything. The exit status is
+// 128 plus the signal number, the convention of shells, or 1 on platforms whose signals are not numbered.
+//
+// Parameters:
+//
+//	s: The state to restore.
+//	sig: The signals to handle; os.Interrupt and, where the platform has it, syscall.SIGTERM if none is given.
+//
+// Returns:
+//
+//	func(): A function that stops handling the signals, to be called once the state has been restored normally.
+func RestoreOnSignal(s *State, sig ...os.Signal) func() {
+	if len(sig) == 0 {
+		sig = defaultSignals()
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sig...)
+
+	go func() {
+		select {
+		case received := <-ch:
+			s.Restore()
+			os.Exit(signalExitCode(received))
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build unix || windows
+
+package cons
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultSignals returns the signals RestoreOnSignal handles when none is given.
+func defaultSignals() []os.Signal {
+	return []os.Signal{os.Interrupt, syscall.SIGTERM}
+}
+
+// signalExitCode returns the exit status of a process ended by sig: 128 plus the signal number.
+func signalExitCode(sig os.Signal) int {
+	if num, ok := sig.(syscall.Signal); ok {
+		return 128 + int(num)
+	}
+
+	return 1
+}
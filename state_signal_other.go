@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package cons
+
+import "os"
+
+// defaultSignals returns the signals RestoreOnSignal handles when none is given,
+// only os.Interrupt as this platform has no SIGTERM.
+func defaultSignals() []os.Signal {
+	return []os.Signal{os.Interrupt}
+}
+
+// signalExitCode returns the exit status of a process ended by sig, 1 as signals are not numbered on this platform.
+func signalExitCode(sig os.Signal) int {
+	return 1
+}
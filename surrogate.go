@@ -0,0 +1,268 @@
+package cons
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// A console cell and a key event carry a single UTF-16 code unit, so a character outside the Basic
+// Multilingual Plane, such as most emoji, takes two cells or two key events: a high surrogate followed
+// by a low surrogate. The functions of this file convert between those units and runes.
+
+// RuneReader reads characters from the key events of an input source, pairing the surrogates that arrive
+// in two consecutive key down records into one rune. It implements io.RuneReader.
+//
+// A RuneReader reads one record at a time, so no event is lost when it is dropped.
+type RuneReader struct {
+	in    InputSource
+	pair  surrogatePair
+	runes []rune // Characters decoded but not yet returned, from a repeated key.
+}
+
+// NewRuneReader creates a reader of the characters typed on an input source.
+//
+// Parameters:
+//
+//	in: The input side of the console.
+//
+// Returns:
+//
+//	*RuneReader: The new reader.
+func NewRuneReader(in InputSource) *RuneReader {
+	return &RuneReader{in: in}
+}
+
+// ReadRune returns the next character typed. Key up records, keys without a character and other events are
+// skipped, a key repeated RepeatCount times returns its character as many times, and a surrogate without
+// its other half is reported as U+FFFD.
+//
+// Returns:
+//
+//	rune: The character.
+//	int: The number of bytes of the character in UTF-8.
+//	error: If the function successfully reads a character, it returns nil. Otherwise, it returns an error.
+func (rr *RuneReader) ReadRune() (rune, int, error) {
+	for len(rr.runes) == 0 {
+		key, err := rr.readKey()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if key.UnicodeChar == 0 {
+			continue
+		}
+
+		for n := max(key.RepeatCount, 1); n > 0; n-- {
+			rr.runes = rr.pair.feed(rr.runes, key.UnicodeChar)
+		}
+	}
+
+	r := rr.runes[0]
+	rr.runes = rr.runes[1:]
+	return r, utf8.RuneLen(r), nil
+}
+
+// ReadKey returns the next key down record with the character it completes. The record of a high surrogate
+// is held back, and the character of a surrogate pair is returned with the record of its low surrogate.
+// RepeatCount is left to the caller.
+//
+// Returns:
+//
+//	KeyEventRecord: The key event.
+//	rune: The character of the key, or 0 for a key without a character such as an arrow key.
+//	error: If the function successfully reads a key, it returns nil. Otherwise, it returns an error.
+func (rr *RuneReader) ReadKey() (KeyEventRecord, rune, error) {
+	for {
+		key, err := rr.readKey()
+		if err != nil {
+			return key, 0, err
+		}
+
+		if key.UnicodeChar == 0 {
+			return key, 0, nil
+		}
+
+		// A lone surrogate before the character is dropped, as it has no record of its own to return.
+		if runes := rr.pair.feed(nil, key.UnicodeChar); len(runes) > 0 {
+			return key, runes[len(runes)-1], nil
+		}
+	}
+}
+
+// readKey reads records until a key down record.
+func (rr *RuneReader) readKey() (KeyEventRecord, error) {
+	for {
+		events, err := ReadEvents(rr.in, 1)
+		if err != nil {
+			return KeyEventRecord{}, err
+		}
+
+		for _, ev := range events {
+			if key, ok := ev.(KeyEventRecord); ok && key.KeyDown != 0 {
+				return key, nil
+			}
+		}
+	}
+}
+
+// GetKeyRune waits for a key to be pressed and returns its character. Unlike GetKeyValue, a character outside
+// the Basic Multilingual Plane is returned whole, once the key event of its low surrogate has been read.
+//
+// Parameters:
+//
+//	hStdin: The input side of the console.
+//
+// Returns:
+//
+//	rune: The character of the key, or 0 for a key without a character such as an arrow key.
+//	error: If the function successfully reads a key, it returns nil. Otherwise, it returns an error.
+func GetKeyRune(hStdin InputSource) (rune, error) {
+	_, r, err := NewRuneReader(hStdin).ReadKey()
+	return r, err
+}
+
+// KeyRunes returns the characters typed in a sequence of events, such as the result of ReadEvents,
+// pairing the surrogates of consecutive key down records. Key up records and other events are skipped,
+// and a surrogate without its other half becomes U+FFFD.
+//
+// Parameters:
+//
+//	events: The events to decode.
+//
+// Returns:
+//
+//	[]rune: The characters, in order.
+func KeyRunes(events []Event) []rune {
+	var (
+		pair  surrogatePair
+		runes []rune
+	)
+
+	for _, ev := range events {
+		key, ok := ev.(KeyEventRecord)
+		if !ok || key.KeyDown == 0 || key.UnicodeChar == 0 {
+			continue
+		}
+
+		for n := max(key.RepeatCount, 1); n > 0; n-- {
+			runes = pair.feed(runes, key.UnicodeChar)
+		}
+	}
+
+	return pair.end(runes)
+}
+
+// RuneCells returns the cells holding a character: two cells carrying the surrogate pair for a character
+// outside the Basic Multilingual Plane, one cell otherwise.
+//
+// Parameters:
+//
+//	r: The character.
+//	attr: The attributes of the cells.
+//
+// Returns:
+//
+//	[]CharInfo: The cells.
+func RuneCells(r rune, attr uint16) []CharInfo {
+	units := runeUnits(r)
+	cells := make([]CharInfo, len(units))
+	for i, u := range units {
+		cells[i] = CharInfo{UnicodeChar: u, Attributes: attr}
+	}
+
+	return cells
+}
+
+// StringCells returns the cells holding a text, one per UTF-16 code unit.
+//
+// Parameters:
+//
+//	s: The text.
+//	attr: The attributes of the cells.
+//
+// Returns:
+//
+//	[]CharInfo: The cells.
+func StringCells(s string, attr uint16) []CharInfo {
+	cells := make([]CharInfo, 0, len(s))
+	for _, r := range s {
+		cells = append(cells, RuneCells(r, attr)...)
+	}
+
+	return cells
+}
+
+// WriteRune writes a character to the cells starting at wcoord, taking two cells for a surrogate pair.
+// The attributes of the cells are left unchanged.
+//
+// Parameters:
+//
+//	hStdout: The output side of the console.
+//	r: The character to write.
+//	wcoord: The position of the first cell.
+//
+// Returns:
+//
+//	uint16: The number of cells written.
+//	error: If the function successfully writes the character, it returns nil. Otherwise, it returns an error.
+func WriteRune(hStdout OutputTarget, r rune, wcoord Coord) (uint16, error) {
+	return hStdout.FillCharacter(r, len(runeUnits(r)), wcoord)
+}
+
+// runeUnits returns the UTF-16 code units of a character. A surrogate value is kept as the single unit
+// it is, so a cell read back from a console can be written again, and an invalid character becomes U+FFFD.
+func runeUnits(r rune) []uint16 {
+	switch r1, r2 := utf16.EncodeRune(r); {
+	case r1 != utf8.RuneError:
+		return []uint16{uint16(r1), uint16(r2)}
+	case r < 0 || r > 0xffff:
+		return []uint16{utf8.RuneError}
+	}
+
+	return []uint16{uint16(r)}
+}
+
+// fillUnits returns the units written by a fill of length cells with a character, repeating the surrogate
+// pair of a character outside the Basic Multilingual Plane. An odd length ends with a high surrogate.
+func fillUnits(char rune, length int) []uint16 {
+	units := runeUnits(char)
+	fill := make([]uint16, max(length, 0))
+	for i := range fill {
+		fill[i] = units[i%len(units)]
+	}
+
+	return fill
+}
+
+// surrogatePair pairs the UTF-16 code units of a stream of key events.
+type surrogatePair struct {
+	high uint16 // A high surrogate waiting for its low surrogate.
+}
+
+// feed appends the character completed by a code unit to dst.
+func (p *surrogatePair) feed(dst []rune, c uint16) []rune {
+	switch {
+	case utf16.IsSurrogate(rune(c)) && c < 0xdc00:
+		dst = p.end(dst)
+		p.high = c
+		return dst
+	case utf16.IsSurrogate(rune(c)) && p.high != 0:
+		dst = append(dst, utf16.DecodeRune(rune(p.high), rune(c)))
+		p.high = 0
+		return dst
+	case utf16.IsSurrogate(rune(c)):
+		return append(dst, utf8.RuneError)
+	}
+
+	return append(p.end(dst), rune(c))
+}
+
+// end appends U+FFFD for a high surrogate left without its low surrogate.
+func (p *surrogatePair) end(dst []rune) []rune {
+	if p.high != 0 {
+		dst = append(dst, utf8.RuneError)
+		p.high = 0
+	}
+
+	return dst
+}
@@ -0,0 +1,174 @@
+package cons
+
+import (
+	"io"
+	"testing"
+)
+
+// unitKey returns the key down record of a UTF-16 code unit.
+func unitKey(c uint16) KeyEventRecord {
+	return KeyEventRecord{KeyDown: 1, RepeatCount: 1, UnicodeChar: c}
+}
+
+// unitInput returns a closed virtual input holding the given events.
+func unitInput(t *testing.T, events ...Event) *VirtualInput {
+	t.Helper()
+
+	vi := NewVirtualInput()
+	if err := vi.Push(events...); err != nil {
+		t.Fatal(err)
+	}
+	vi.Close()
+
+	return vi
+}
+
+func TestRuneReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		want   []rune
+	}{
+		{"pair", []Event{unitKey(0xd83d), unitKey(0xde00)}, []rune{'😀'}},
+		{"pair around a key up", []Event{unitKey(0xd83d), KeyEventRecord{UnicodeChar: 'x'}, unitKey(0xde00)}, []rune{'😀'}},
+		{"repeated", []Event{unitKey('a'), KeyEventRecord{KeyDown: 1, RepeatCount: 2, UnicodeChar: 'b'}}, []rune{'a', 'b', 'b'}},
+		{"lone high", []Event{unitKey(0xd83d), unitKey('a')}, []rune{0xfffd, 'a'}},
+		{"lone low", []Event{unitKey(0xde00), unitKey('a')}, []rune{0xfffd, 'a'}},
+		{"two highs", []Event{unitKey(0xd83d), unitKey(0xd83d), unitKey(0xde00)}, []rune{0xfffd, '😀'}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := NewRuneReader(unitInput(t, tt.events...))
+
+			var got []rune
+			for {
+				r, size, err := rr.ReadRune()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := len(string(r)); size != want {
+					t.Errorf("size of %U = %d, want %d", r, size, want)
+				}
+				got = append(got, r)
+			}
+
+			if string(got) != string(tt.want) {
+				t.Errorf("runes = %q, want %q", got, tt.want)
+			}
+			if keyed := KeyRunes(tt.events); string(keyed) != string(tt.want) {
+				t.Errorf("KeyRunes = %q, want %q", keyed, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuneReaderReadKey(t *testing.T) {
+	arrow := KeyEventRecord{KeyDown: 1, RepeatCount: 1, VirtualKeyCode: VkLeft}
+	rr := NewRuneReader(unitInput(t,
+		unitKey(0xde00), // A lone low surrogate is its own record, reported as U+FFFD.
+		unitKey(0xd83d), // A high surrogate is held back until its low surrogate.
+		unitKey(0xde00),
+		arrow,
+		unitKey(0xd83d), // A high surrogate followed by another character is dropped.
+		unitKey('a'),
+	))
+
+	want := []struct {
+		unit uint16
+		r    rune
+	}{{0xde00, 0xfffd}, {0xde00, '😀'}, {0, 0}, {'a', 'a'}}
+
+	for i, w := range want {
+		key, r, err := rr.ReadKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.UnicodeChar != w.unit || r != w.r {
+			t.Errorf("key %d = %#x, %q, want %#x, %q", i, key.UnicodeChar, r, w.unit, w.r)
+		}
+	}
+
+	if _, _, err := rr.ReadKey(); err != io.EOF {
+		t.Errorf("ReadKey at the end = %v, want io.EOF", err)
+	}
+
+	r, err := GetKeyRune(unitInput(t, unitKey(0xd83e), unitKey(0xdd16)))
+	if err != nil || r != '🤖' {
+		t.Errorf("GetKeyRune = %q, %v, want '🤖'", r, err)
+	}
+}
+
+func TestRuneCells(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want []uint16
+	}{
+		{'a', []uint16{'a'}},
+		{'界', []uint16{0x754c}},
+		{'😀', []uint16{0xd83d, 0xde00}},
+		{0xdc00, []uint16{0xdc00}},
+		{-1, []uint16{0xfffd}},
+		{0x110000, []uint16{0xfffd}},
+	}
+
+	for _, tt := range tests {
+		cells := RuneCells(tt.r, ForegroundRed)
+		if len(cells) != len(tt.want) {
+			t.Errorf("RuneCells(%U) = %d cells, want %d", tt.r, len(cells), len(tt.want))
+			continue
+		}
+		for i, cell := range cells {
+			if cell.UnicodeChar != tt.want[i] || cell.Attributes != ForegroundRed {
+				t.Errorf("RuneCells(%U)[%d] = %+v, want %#x", tt.r, i, cell, tt.want[i])
+			}
+		}
+	}
+
+	if cells := StringCells("a😀b", 0); len(cells) != 4 || cells[1].UnicodeChar != 0xd83d || cells[2].UnicodeChar != 0xde00 {
+		t.Errorf("StringCells = %+v, want a, the surrogate pair and b", cells)
+	}
+}
+
+func TestFillCharacterSurrogates(t *testing.T) {
+	tests := []struct {
+		name   string
+		r      rune
+		length int
+		want   []uint16
+	}{
+		{"pairs", '😀', 4, []uint16{0xd83d, 0xde00, 0xd83d, 0xde00, ' ', ' '}},
+		{"odd length", '😀', 3, []uint16{0xd83d, 0xde00, 0xd83d, ' ', ' ', ' '}},
+		{"basic plane", 'x', 3, []uint16{'x', 'x', 'x', ' ', ' ', ' '}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := NewVirtualConsole(6, 1)
+
+			n, err := vc.FillCharacter(tt.r, tt.length, Coord{})
+			if err != nil || int(n) != tt.length {
+				t.Fatalf("FillCharacter = %d, %v, want %d", n, err, tt.length)
+			}
+			for x, want := range tt.want {
+				if got := vc.Cell(int16(x), 0).UnicodeChar; got != want {
+					t.Errorf("cell %d = %#x, want %#x", x, got, want)
+				}
+			}
+		})
+	}
+
+	vc := NewVirtualConsole(6, 1)
+	if n, err := WriteRune(vc, '😀', Coord{X: 1}); err != nil || n != 2 {
+		t.Fatalf("WriteRune = %d, %v, want 2 cells", n, err)
+	}
+	if a, b := vc.Cell(1, 0).UnicodeChar, vc.Cell(2, 0).UnicodeChar; a != 0xd83d || b != 0xde00 {
+		t.Errorf("WriteRune cells = %#x %#x, want the surrogate pair", a, b)
+	}
+	if line := vc.Line(0); line != " 😀" {
+		t.Errorf("line = %q, want %q", line, " 😀")
+	}
+}
@@ -2,6 +2,7 @@ package cons
 
 import (
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -216,7 +217,42 @@ func SetWindowTitle(title string) error {
 		return err
 	}
 
-	if _, _, err := procSetConsoleTitle.Call(touintptr(cstr)); err != nil {
+	if _, _, err := procSetConsoleTitle.Call(touintptr(cstr)); err != errorSuccess {
+		return err
+	}
+
+	return nil
+}
+
+// Retrieves the title of the console window in Windows.
+//
+// Returns:
+//
+//	string: The current title of the console window.
+//	error: If the function successfully retrieves the title, it returns nil. Otherwise, it returns an error.
+func GetWindowTitle() (string, error) {
+	// The title of a console window is limited to 64K bytes.
+	buf := make([]uint16, 32*1024)
+	n, _, err := procGetConsoleTitle.Call(touintptr(&buf[0]), uintptr(len(buf)))
+	if n == 0 && err != errorSuccess {
+		return "", err
+	}
+
+	return syscall.UTF16ToString(buf[:n]), nil
+}
+
+// Sets the attributes of the characters written to the console screen buffer in Windows.
+//
+// Parameters:
+//
+//	hStdout: The handle to the standard output stream whose text attributes are to be set.
+//	attribute: The foreground and background colors and other attributes of the characters.
+//
+// Returns:
+//
+//	error: If the function successfully sets the text attributes, it returns nil. Otherwise, it returns an error.
+func SetTextAttribute(hStdout Handle, attribute uint16) error {
+	if _, _, err := procSetConsoleTextAttribute.Call(uintptr(hStdout), uintptr(attribute)); err != errorSuccess {
 		return err
 	}
 
@@ -242,6 +278,7 @@ func SetCursorInfo(hStdout Handle, p *CursorInfo) error {
 }
 
 // Fills the specified number of character cells with a given character in the console window.
+// A character outside the Basic Multilingual Plane is repeated as its surrogate pair, two cells at a time.
 //
 // Parameters:
 //
@@ -257,6 +294,11 @@ func SetCursorInfo(hStdout Handle, p *CursorInfo) error {
 func FillCharacter(hStdout Handle, char rune, length int, wcoord Coord) (uint16, error) {
 	var counter uint16
 
+	// FillConsoleOutputCharacterW takes a single code unit, so a surrogate pair is written as units.
+	if char > 0xffff {
+		return writeCharacters(hStdout, fillUnits(char, length), wcoord)
+	}
+
 	if _, _, err := procFillConsoleOutputCharacter.Call(
 		uintptr(hStdout), uintptr(char),
 		uintptr(length), strutouintptr(&wcoord),
@@ -308,9 +350,18 @@ func FillAttribute(hStdout Handle, attribute uint16, length int, wcoord Coord) (
 //	error: If the function successfully writes the attribute to the character cells, it returns nil. Otherwise, it returns an error.
 func WriteAttribute(hStdout Handle, attribute uint16, length uint32, wcoord Coord) (uint16, error) {
 	var counter uint16
+	if length == 0 {
+		return 0, nil
+	}
+
+	// WriteConsoleOutputAttribute reads one attribute per cell.
+	attributes := make([]uint16, length)
+	for i := range attributes {
+		attributes[i] = attribute
+	}
 
 	if _, _, err := procWriteConsoleOutputAttribute.Call(
-		uintptr(hStdout), touintptr(&attribute),
+		uintptr(hStdout), touintptr(&attributes[0]),
 		uintptr(length), strutouintptr(&wcoord),
 		touintptr(&counter)); err != errorSuccess {
 		return counter, err
@@ -333,11 +384,158 @@ func WriteAttribute(hStdout Handle, attribute uint16, length uint32, wcoord Coor
 //	uint16: The number of character cells with the character successfully written.
 //	error: If the function successfully writes the character to the character cells, it returns nil. Otherwise, it returns an error.
 func WriteCharacter(hStdout Handle, char uint16, length uint32, wcoord Coord) (uint16, error) {
+	return writeCharacters(hStdout, fillUnits(rune(char), int(length)), wcoord)
+}
+
+// writeCharacters writes UTF-16 code units to consecutive cells starting at wcoord, one unit per cell.
+func writeCharacters(hStdout Handle, units []uint16, wcoord Coord) (uint16, error) {
 	var counter uint16
+	if len(units) == 0 {
+		return 0, nil
+	}
 
 	if _, _, err := procWriteConsoleOutputCharacter.Call(
-		uintptr(hStdout), uintptr(char),
-		uintptr(length), strutouintptr(&wcoord),
+		uintptr(hStdout), touintptr(&units[0]),
+		uintptr(len(units)), strutouintptr(&wcoord),
+		touintptr(&counter)); err != errorSuccess {
+		return counter, err
+	}
+
+	return counter, nil
+}
+
+// writeOutputCells writes a row of cells starting at wcoord with WriteConsoleOutputW, which keeps the
+// leading and trailing cells of wide characters as given.
+func writeOutputCells(hStdout Handle, cells []CharInfo, wcoord Coord) error {
+	if len(cells) == 0 {
+		return nil
+	}
+
+	var (
+		size   = Coord{X: int16(len(cells)), Y: 1}
+		origin Coord
+		region = SmallRect{Left: wcoord.X, Top: wcoord.Y, Right: wcoord.X + int16(len(cells)) - 1, Bottom: wcoord.Y}
+	)
+
+	if _, _, err := procWriteConsoleOutput.Call(
+		uintptr(hStdout), touintptr(&cells[0]),
+		strutouintptr(&size), strutouintptr(&origin),
+		touintptr(&region)); err != errorSuccess {
+		return err
+	}
+
+	return nil
+}
+
+// WriteOutput writes a rectangle of cells to the screen buffer in one call, with WriteConsoleOutputW.
+//
+// Parameters:
+//
+//	hStdout: The handle to the standard output stream where the cells will be written.
+//	buffer: The cells to write, bufsize.X cells per row.
+//	bufsize: The size of buffer, in columns and rows.
+//	bufcoord: The cell of buffer written to the top left corner of region.
+//	region: The rectangle of the screen buffer to write. On return, it holds the rectangle actually written,
+//	clipped to the screen buffer and to buffer; its Right is less than its Left when nothing was written.
+//
+// Returns:
+//
+//	error: If the function successfully writes the cells, it returns nil. Otherwise, it returns an error.
+func WriteOutput(hStdout Handle, buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error {
+	if !validOutputBuffer(len(buffer), bufsize, bufcoord) || region == nil {
+		return ErrInvalidParameter
+	}
+
+	if _, _, err := procWriteConsoleOutput.Call(
+		uintptr(hStdout), touintptr(&buffer[0]),
+		strutouintptr(&bufsize), strutouintptr(&bufcoord),
+		touintptr(region)); err != errorSuccess {
+		return err
+	}
+
+	return nil
+}
+
+// ReadOutput reads a rectangle of cells from the screen buffer in one call, with ReadConsoleOutputW.
+//
+// Parameters:
+//
+//	hStdout: The handle to the standard output stream from which the cells will be read.
+//	buffer: The cells read, bufsize.X cells per row.
+//	bufsize: The size of buffer, in columns and rows.
+//	bufcoord: The cell of buffer receiving the top left corner of region.
+//	region: The rectangle of the screen buffer to read. On return, it holds the rectangle actually read,
+//	clipped to the screen buffer and to buffer; its Right is less than its Left when nothing was read.
+//
+// Returns:
+//
+//	error: If the function successfully reads the cells, it returns nil. Otherwise, it returns an error.
+func ReadOutput(hStdout Handle, buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error {
+	if !validOutputBuffer(len(buffer), bufsize, bufcoord) || region == nil {
+		return ErrInvalidParameter
+	}
+
+	if _, _, err := procReadConsoleOutput.Call(
+		uintptr(hStdout), touintptr(&buffer[0]),
+		strutouintptr(&bufsize), strutouintptr(&bufcoord),
+		touintptr(region)); err != errorSuccess {
+		return err
+	}
+
+	return nil
+}
+
+// ReadOutputCharacter reads the characters of consecutive cells of the screen buffer starting at wcoord,
+// wrapping at the end of each row, with ReadConsoleOutputCharacterW.
+//
+// Parameters:
+//
+//	hStdout: The handle to the standard output stream from which the characters will be read.
+//	buffer: The UTF-16 code units read, one per cell; its length is the number of cells to read.
+//	wcoord: The starting coordinates for reading.
+//
+// Returns:
+//
+//	uint32: The number of cells read, fewer than len(buffer) when the end of the screen buffer is reached.
+//	error: If the function successfully reads the characters, it returns nil. Otherwise, it returns an error.
+func ReadOutputCharacter(hStdout Handle, buffer []uint16, wcoord Coord) (uint32, error) {
+	var counter uint32
+	if len(buffer) == 0 {
+		return 0, nil
+	}
+
+	if _, _, err := procReadConsoleOutputCharacter.Call(
+		uintptr(hStdout), touintptr(&buffer[0]),
+		uintptr(len(buffer)), strutouintptr(&wcoord),
+		touintptr(&counter)); err != errorSuccess {
+		return counter, err
+	}
+
+	return counter, nil
+}
+
+// ReadOutputAttribute reads the attributes of consecutive cells of the screen buffer starting at wcoord,
+// wrapping at the end of each row, with ReadConsoleOutputAttribute.
+//
+// Parameters:
+//
+//	hStdout: The handle to the standard output stream from which the attributes will be read.
+//	buffer: The attributes read, one per cell; its length is the number of cells to read.
+//	wcoord: The starting coordinates for reading.
+//
+// Returns:
+//
+//	uint32: The number of cells read, fewer than len(buffer) when the end of the screen buffer is reached.
+//	error: If the function successfully reads the attributes, it returns nil. Otherwise, it returns an error.
+func ReadOutputAttribute(hStdout Handle, buffer []uint16, wcoord Coord) (uint32, error) {
+	var counter uint32
+	if len(buffer) == 0 {
+		return 0, nil
+	}
+
+	if _, _, err := procReadConsoleOutputAttribute.Call(
+		uintptr(hStdout), touintptr(&buffer[0]),
+		uintptr(len(buffer)), strutouintptr(&wcoord),
 		touintptr(&counter)); err != errorSuccess {
 		return counter, err
 	}
@@ -361,10 +559,109 @@ func WriteCharacter(hStdout Handle, char uint16, length uint32, wcoord Coord) (u
 func ScrollScreenBuffer(hStdout Handle, scrollrect, cliprect *SmallRect, dest Coord, fill CharInfo) error {
 	if _, _, err := procScrollConsoleScreenBuffer.Call(
 		uintptr(hStdout), touintptr(scrollrect),
-		touintptr(cliprect), touintptr(&dest),
+		touintptr(cliprect), strutouintptr(&dest),
 		touintptr(&fill)); err != errorSuccess {
 		return err
 	}
 
 	return nil
 }
+
+// Waits until the console input buffer of the specified standard input handle has input records or the timeout elapses.
+//
+// Parameters:
+//
+//	hStdin: The handle to the standard input stream to wait on.
+//	timeout: The maximum time to wait; a negative timeout waits forever.
+//
+// Returns:
+//
+//	bool: True if input records are available, false if the timeout elapsed.
+//	error: If the function successfully waits, it returns nil. Otherwise, it returns an error.
+func WaitForInput(hStdin Handle, timeout time.Duration) (bool, error) {
+	const (
+		waitObject0 = 0x00000000
+		waitFailed  = 0xFFFFFFFF
+		infinite    = 0xFFFFFFFF
+	)
+
+	milliseconds := uintptr(infinite)
+	if timeout >= 0 {
+		milliseconds = uintptr(min(timeout.Milliseconds(), infinite-1))
+	}
+
+	event, _, err := procWaitForSingleObject.Call(uintptr(hStdin), milliseconds)
+	if event == waitFailed {
+		return false, err
+	}
+
+	return event == waitObject0, nil
+}
+
+// CreateScreenBuffer creates a console screen buffer besides the one of the standard output. What is written
+// to it is kept off screen until it is made the active screen buffer with SetActiveScreenBuffer.
+//
+// Returns:
+//
+//	Handle: The handle of the new screen buffer, to be closed with CloseHandle.
+//	error: If the function successfully creates the screen buffer, it returns nil. Otherwise, it returns an error.
+func CreateScreenBuffer() (Handle, error) {
+	const (
+		genericRead           = 0x80000000
+		genericWrite          = 0x40000000
+		fileShareRead         = 0x00000001
+		fileShareWrite        = 0x00000002
+		consoleTextmodeBuffer = 0x00000001
+		invalidHandleValue    = ^uintptr(0)
+	)
+
+	h, _, err := procCreateConsoleScreenBuffer.Call(genericRead|genericWrite, fileShareRead|fileShareWrite, 0, consoleTextmodeBuffer, 0)
+	if h == invalidHandleValue {
+		return invalidHandle, err
+	}
+
+	return Handle(h), nil
+}
+
+// SetActiveScreenBuffer displays a screen buffer in the console window.
+//
+// Parameters:
+//
+//	hConsoleOutput: The handle of the screen buffer, from CreateScreenBuffer or GetStdHandle.
+//
+// Returns:
+//
+//	error: If the function successfully activates the screen buffer, it returns nil. Otherwise, it returns an error.
+func SetActiveScreenBuffer(hConsoleOutput Handle) error {
+	if ok, _, err := procSetConsoleActiveBuffer.Call(uintptr(hConsoleOutput)); ok == 0 {
+		return err
+	}
+
+	return nil
+}
+
+// CloseHandle closes a handle, such as a screen buffer created with CreateScreenBuffer.
+//
+// Parameters:
+//
+//	hObject: The handle to close.
+//
+// Returns:
+//
+//	error: If the function successfully closes the handle, it returns nil. Otherwise, it returns an error.
+func CloseHandle(hObject Handle) error {
+	return syscall.CloseHandle(syscall.Handle(hObject))
+}
+
+// writeHandle writes p to the handle with WriteFile, as VT sequences on a console with EnableVirtualTerminalProcessing.
+func writeHandle(h Handle, p []byte) (int, error) {
+	return syscall.Write(syscall.Handle(h), p)
+}
+
+// systemCodePages returns the ANSI and OEM code pages of the system.
+func systemCodePages() (acp, oemcp uint32) {
+	a, _, _ := procGetACP.Call()
+	o, _, _ := procGetOEMCP.Call()
+
+	return uint32(a), uint32(o)
+}
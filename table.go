@@ -0,0 +1,356 @@
+package cons
+
+import (
+	"io"
+	"strings"
+)
+
+// Align is the alignment of the cells of a column of a Table.
+type Align uint8
+
+const (
+	AlignLeft   Align = iota // Text starts at the left of the cell.
+	AlignRight               // Text ends at the right of the cell, as for numbers.
+	AlignCenter              // Text is centered, with the extra blank on the right.
+)
+
+// TableBorder is the set of characters the borders of a Table are drawn with.
+type TableBorder struct {
+	Horizontal, Vertical                  rune
+	TopLeft, TopMiddle, TopRight          rune
+	MiddleLeft, Cross, MiddleRight        rune // The line between the header and the rows.
+	BottomLeft, BottomMiddle, BottomRight rune
+}
+
+var (
+	// BoxBorder draws the borders with the light box-drawing characters.
+	BoxBorder = TableBorder{'─', '│', '┌', '┬', '┐', '├', '┼', '┤', '└', '┴', '┘'}
+
+	// ASCIIBorder draws the borders with -, | and +, for code pages without box-drawing characters.
+	ASCIIBorder = TableBorder{'-', '|', '+', '+', '+', '+', '+', '+', '+', '+', '+'}
+)
+
+// Column is a column of a Table.
+type Column struct {
+	Header   string
+	Align    Align
+	MaxWidth int // The widest the column grows, in cells, or 0 for no limit.
+}
+
+// TableCell is a cell of a Table with its own attributes, such as a status in red.
+type TableCell struct {
+	Text string
+	Attr uint16 // The attributes of the text, or 0 for the attributes of the table.
+}
+
+// Table lays out rows of text in columns sized to their content, with borders, and draws them
+// on a console or writes them to a writer. Text too wide for its column is truncated with an ellipsis.
+type Table struct {
+	Columns []Column
+
+	// Border is the set of border characters, or nil for BoxBorder when the output code page can encode it
+	// and ASCIIBorder otherwise.
+	Border *TableBorder
+
+	// Attr is the attributes of the borders and of the cells without their own, or 0 for the current
+	// text attributes of the console, and the default colors of a writer.
+	Attr uint16
+
+	// HeaderAttr is the attributes of the header, or 0 for Attr.
+	HeaderAttr uint16
+
+	// MaxWidth is the widest the table grows, in cells, or 0 for the width of the screen buffer from the
+	// column the table is drawn at, and no limit for a writer.
+	MaxWidth int
+
+	rows [][]TableCell
+}
+
+// NewTable creates a table with a left-aligned column for each header.
+//
+// Parameters:
+//
+//	headers: The headers of the columns, or none for a table without header; columns are then added by the rows.
+//
+// Returns:
+//
+//	*Table: The new table, without rows.
+func NewTable(headers ...string) *Table {
+	t := &Table{}
+	for _, header := range headers {
+		t.Columns = append(t.Columns, Column{Header: header})
+	}
+
+	return t
+}
+
+// AddRow adds a row of text in the attributes of the table.
+func (t *Table) AddRow(texts ...string) {
+	cells := make([]TableCell, len(texts))
+	for i, text := range texts {
+		cells[i] = TableCell{Text: text}
+	}
+
+	t.rows = append(t.rows, cells)
+}
+
+// AddCells adds a row of cells with their own attributes.
+func (t *Table) AddCells(cells ...TableCell) {
+	t.rows = append(t.rows, append([]TableCell(nil), cells...))
+}
+
+// Draw writes the table into the screen buffer, scrolling it up when the table reaches the bottom.
+// The borders are box-drawing characters when the output code page of out can encode them.
+//
+// Parameters:
+//
+//	out: The output side of the console.
+//	wcoord: The position of the top left corner of the table.
+//
+// Returns:
+//
+//	Coord: The position below the bottom left corner of the table, where the text after it starts.
+//	error: If the function successfully writes the table, it returns nil. Otherwise, it returns an error.
+func (t *Table) Draw(out OutputTarget, wcoord Coord) (Coord, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return wcoord, err
+	}
+
+	if wcoord.X < 0 || wcoord.Y < 0 || wcoord.X >= scrbufinfo.Size.X || wcoord.Y >= scrbufinfo.Size.Y {
+		return wcoord, ErrInvalidParameter
+	}
+
+	attr, width := t.Attr, int(scrbufinfo.Size.X-wcoord.X)
+	if attr == 0 {
+		attr = scrbufinfo.Attributes
+	}
+	if t.MaxWidth > 0 {
+		width = min(width, t.MaxWidth)
+	}
+
+	cp := uint32(Utf8)
+	if cper, ok := out.(interface{ GetOutputCodePage() (uint32, error) }); ok {
+		if got, err := cper.GetOutputCodePage(); err == nil {
+			cp = got
+		}
+	}
+
+	for _, row := range t.render(width, cp, attr) {
+		n, err := scrollToRow(out, wcoord.Y, attr)
+		if err != nil {
+			return wcoord, err
+		}
+		wcoord.Y -= n
+
+		// A table too narrow to shrink further is clipped at the edge of the screen buffer.
+		row = row[:min(len(row), int(scrbufinfo.Size.X-wcoord.X))]
+		if err := writeCells(out, row, wcoord); err != nil {
+			return wcoord, err
+		}
+		wcoord.Y++
+	}
+
+	n, err := scrollToRow(out, wcoord.Y, attr)
+	return Coord{X: wcoord.X, Y: wcoord.Y - n}, err
+}
+
+// Fprint writes the table to a writer, one line per row, with SGR sequences for the attributes
+// downsampled to a color profile.
+//
+// Parameters:
+//
+//	w: The destination, such as os.Stdout on a VT terminal.
+//	profile: The colors w can display; ProfileNoColor writes plain text.
+//	cp: The code page the text is shown in, such as Utf8, picking the border when Border is nil.
+//
+// Returns:
+//
+//	error: If the function successfully writes the table, it returns nil. Otherwise, it returns an error.
+func (t *Table) Fprint(w io.Writer, profile ColorProfile, cp uint32) error {
+	attr := t.Attr
+	if attr == 0 {
+		attr = ForegroundRed | ForegroundGreen | ForegroundBlue
+	}
+
+	width := t.MaxWidth
+	if width <= 0 {
+		width = int(^uint(0) >> 1)
+	}
+
+	var sb strings.Builder
+	for _, row := range t.render(width, cp, attr) {
+		if profile == ProfileNoColor {
+			sb.WriteString(cellsText(row))
+			sb.WriteByte('\n')
+			continue
+		}
+
+		for _, run := range cellRuns(row) {
+			sb.WriteString(StyleFromAttributes(run[0].Attributes).SGR(profile))
+			sb.WriteString(cellsText(run))
+		}
+		sb.WriteString("\x1b[0m\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// tableStyle returns the border and the ellipsis of a table shown in a code page: BoxBorder and … if it
+// encodes them, ASCIIBorder and ... otherwise.
+func (t *Table) tableStyle(cp uint32) (TableBorder, string) {
+	enc, err := GetEncoding(cp)
+
+	ellipsis := "..."
+	if err == nil && enc.CanEncode('…') {
+		ellipsis = "…"
+	}
+
+	if t.Border != nil {
+		return *t.Border, ellipsis
+	}
+
+	border := BoxBorder
+	for _, r := range []rune{border.Horizontal, border.Vertical, border.TopLeft, border.TopMiddle, border.TopRight,
+		border.MiddleLeft, border.Cross, border.MiddleRight, border.BottomLeft, border.BottomMiddle, border.BottomRight} {
+		if err != nil || !enc.CanEncode(r) {
+			return ASCIIBorder, ellipsis
+		}
+	}
+
+	return border, ellipsis
+}
+
+// render lays out the rows of cells of the table, at most width cells wide.
+func (t *Table) render(width int, cp uint32, attr uint16) [][]CharInfo {
+	border, ellipsis := t.tableStyle(cp)
+
+	columns := append([]Column(nil), t.Columns...)
+	header := false
+	for _, column := range columns {
+		header = header || column.Header != ""
+	}
+	for _, row := range t.rows {
+		for len(columns) < len(row) {
+			columns = append(columns, Column{})
+		}
+	}
+
+	widths := t.columnWidths(columns, width)
+
+	line := func(left, middle, right rune) []CharInfo {
+		var sb strings.Builder
+		sb.WriteRune(left)
+		for i, w := range widths {
+			if i > 0 {
+				sb.WriteRune(middle)
+			}
+			sb.WriteString(strings.Repeat(string(border.Horizontal), w+2))
+		}
+		sb.WriteRune(right)
+		return TextCells(sb.String(), attr)
+	}
+
+	row := func(cells []TableCell, rowAttr uint16) []CharInfo {
+		vertical := TextCells(string(border.Vertical), attr)
+		out := append([]CharInfo(nil), vertical...)
+		for i, w := range widths {
+			cell := TableCell{Attr: rowAttr}
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			if cell.Attr == 0 {
+				cell.Attr = rowAttr
+			}
+
+			out = append(out, CharInfo{UnicodeChar: ' ', Attributes: attr})
+			out = append(out, alignCells(cell, w, columns[i].Align, ellipsis)...)
+			out = append(out, CharInfo{UnicodeChar: ' ', Attributes: attr})
+			out = append(out, vertical...)
+		}
+		return out
+	}
+
+	rows := [][]CharInfo{line(border.TopLeft, border.TopMiddle, border.TopRight)}
+	if header {
+		headerAttr := t.HeaderAttr
+		if headerAttr == 0 {
+			headerAttr = attr
+		}
+
+		cells := make([]TableCell, len(columns))
+		for i, column := range columns {
+			cells[i] = TableCell{Text: column.Header}
+		}
+
+		rows = append(rows, row(cells, headerAttr), line(border.MiddleLeft, border.Cross, border.MiddleRight))
+	}
+
+	for _, cells := range t.rows {
+		rows = append(rows, row(cells, attr))
+	}
+
+	return append(rows, line(border.BottomLeft, border.BottomMiddle, border.BottomRight))
+}
+
+// columnWidths sizes the columns to their widest text, within their MaxWidth, then narrows the widest columns
+// one cell at a time until the table fits in width cells, borders and padding included.
+func (t *Table) columnWidths(columns []Column, width int) []int {
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = StringWidth(cellText(column.Header))
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], StringWidth(cellText(cell.Text)))
+		}
+	}
+
+	total := 1
+	for i, column := range columns {
+		if column.MaxWidth > 0 {
+			widths[i] = min(widths[i], column.MaxWidth)
+		}
+		widths[i] = max(widths[i], 1)
+		total += widths[i] + 3
+	}
+
+	for total > width {
+		widest := 0
+		for i := range widths {
+			if widths[i] > widths[widest] {
+				widest = i
+			}
+		}
+
+		if widths[widest] <= 1 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+
+	return widths
+}
+
+// alignCells lays out the text of a cell in width cells, truncated with the ellipsis when it is too wide.
+func alignCells(cell TableCell, width int, align Align, ellipsis string) []CharInfo {
+	text := Truncate(cellText(cell.Text), width, ellipsis)
+
+	pad := width - StringWidth(text)
+	left := 0
+	switch align {
+	case AlignRight:
+		left = pad
+	case AlignCenter:
+		left = pad / 2
+	}
+
+	return TextCells(strings.Repeat(" ", left)+text+strings.Repeat(" ", pad-left), cell.Attr)
+}
+
+// cellText returns the text of a cell on a single line.
+func cellText(text string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(text)
+}
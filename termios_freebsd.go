@@ -0,0 +1,19 @@
+package cons
+
+import "syscall"
+
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
+
+// waitReadable waits until fd is readable or the timeout, when not nil, elapses.
+func waitReadable(fd int, timeout *syscall.Timeval) (bool, error) {
+	var set syscall.FdSet
+	fdSet(set.X__fds_bits[:], fd)
+	if err := syscall.Select(fd+1, &set, nil, nil, timeout); err != nil {
+		return false, err
+	}
+
+	return fdIsSet(set.X__fds_bits[:], fd), nil
+}
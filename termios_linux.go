@@ -0,0 +1,19 @@
+package cons
+
+import "syscall"
+
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
+
+// waitReadable waits until fd is readable or the timeout, when not nil, elapses.
+func waitReadable(fd int, timeout *syscall.Timeval) (bool, error) {
+	var set syscall.FdSet
+	fdSet(set.Bits[:], fd)
+	if _, err := syscall.Select(fd+1, &set, nil, nil, timeout); err != nil {
+		return false, err
+	}
+
+	return fdIsSet(set.Bits[:], fd), nil
+}
@@ -53,7 +53,7 @@ type WindowBufferSizeRecord struct {
 }
 
 type MenuEventRecord struct {
-	CommandId uint // Identifier of the menu command.
+	CommandId uint32 // Identifier of the menu command.
 }
 
 type FocusEventRecord struct {
@@ -0,0 +1,557 @@
+package cons
+
+import (
+	"strings"
+	"sync"
+)
+
+// VirtualConsole is an in-memory screen buffer of CharInfo cells implementing OutputTarget with the
+// semantics of the kernel32 screen buffer functions, so code using the helpers of this package can be
+// run and asserted on without a real console. Together with its VirtualInput, it also implements Console.
+type VirtualConsole struct {
+	mu         sync.Mutex
+	size       Coord      // The size of the screen buffer.
+	window     SmallRect  // The window area within the screen buffer.
+	cursor     Coord      // The current cursor position.
+	attributes uint16     // The attributes of the screen buffer.
+	curinfo    CursorInfo // The size and visibility of the cursor.
+	mode       DWord      // The output mode.
+	cells      []CharInfo // The cells of the screen buffer, row by row.
+	input      *VirtualInput
+	inputCP    uint32 // The input code page.
+	outputCP   uint32 // The output code page.
+	title      string // The window title.
+}
+
+// NewVirtualConsole creates a virtual screen buffer of the given size, filled with spaces and default attributes.
+//
+// Parameters:
+//
+//	width: The number of columns of the screen buffer.
+//	height: The number of rows of the screen buffer.
+//
+// Returns:
+//
+//	*VirtualConsole: The new virtual screen buffer, whose window covers the whole buffer.
+func NewVirtualConsole(width, height int16) *VirtualConsole {
+	vc := &VirtualConsole{
+		attributes: ForegroundBlue | ForegroundGreen | ForegroundRed,
+		curinfo:    CursorInfo{Size: 25, Visible: true},
+		mode:       EnableProcessedOutput | EnableWrapAtEolOutput,
+		input:      NewVirtualInput(),
+		inputCP:    Utf8,
+		outputCP:   Utf8,
+	}
+	vc.resize(Coord{X: width, Y: height})
+
+	return vc
+}
+
+// Resize changes the size of the screen buffer, keeping the cells that are still inside it.
+// The window is clipped to the new size and the cursor is clamped into the buffer. When the size changes
+// and the input is in EnableWindowInput mode, a WindowBufferSizeRecord is queued on the input, as a console does.
+//
+// Parameters:
+//
+//	size: The new size of the screen buffer.
+//
+// Returns:
+//
+//	error: If the size is valid, it returns nil. Otherwise, it returns ErrInvalidParameter.
+func (vc *VirtualConsole) Resize(size Coord) error {
+	if size.X <= 0 || size.Y <= 0 {
+		return ErrInvalidParameter
+	}
+
+	vc.mu.Lock()
+	changed := size != vc.size
+	vc.resize(size)
+	vc.mu.Unlock()
+
+	if mode, _ := vc.input.GetMode(); changed && mode.IsEnableMode(EnableWindowInput) {
+		return vc.input.Push(WindowBufferSizeRecord{Size: size})
+	}
+
+	return nil
+}
+
+func (vc *VirtualConsole) resize(size Coord) {
+	cells := make([]CharInfo, int(size.X)*int(size.Y))
+	for i := range cells {
+		cells[i] = CharInfo{UnicodeChar: ' ', Attributes: vc.attributes}
+	}
+
+	for y := int16(0); y < min(size.Y, vc.size.Y); y++ {
+		for x := int16(0); x < min(size.X, vc.size.X); x++ {
+			cells[int(y)*int(size.X)+int(x)] = vc.cells[vc.index(Coord{X: x, Y: y})]
+		}
+	}
+
+	grow := len(vc.cells) == 0 || vc.window.Right == vc.size.X-1 && vc.window.Bottom == vc.size.Y-1
+	vc.size, vc.cells = size, cells
+	if grow || vc.window.Right >= size.X || vc.window.Bottom >= size.Y {
+		vc.window = SmallRect{Left: 0, Top: 0, Right: size.X - 1, Bottom: size.Y - 1}
+	}
+
+	vc.cursor.X = min(vc.cursor.X, size.X-1)
+	vc.cursor.Y = min(vc.cursor.Y, size.Y-1)
+}
+
+// SetWindowInfo moves or resizes the window within the screen buffer.
+//
+// Parameters:
+//
+//	window: The new window rectangle, in absolute buffer coordinates.
+//
+// Returns:
+//
+//	error: If the window lies inside the screen buffer, it returns nil. Otherwise, it returns ErrInvalidParameter.
+func (vc *VirtualConsole) SetWindowInfo(window SmallRect) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if window.Left < 0 || window.Top < 0 || window.Left > window.Right || window.Top > window.Bottom ||
+		window.Right >= vc.size.X || window.Bottom >= vc.size.Y {
+		return ErrInvalidParameter
+	}
+
+	vc.window = window
+	return nil
+}
+
+// SetTextAttribute sets the attributes used by the screen buffer, the counterpart of SetConsoleTextAttribute.
+//
+// Parameters:
+//
+//	attribute: The new attributes of the screen buffer.
+//
+// Returns:
+//
+//	error: Always nil.
+func (vc *VirtualConsole) SetTextAttribute(attribute uint16) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.attributes = attribute
+	return nil
+}
+
+// Input returns the virtual input of the console, on which input records can be pushed.
+func (vc *VirtualConsole) Input() InputSource {
+	return vc.input
+}
+
+// VirtualInput returns the virtual input of the console with its concrete type.
+func (vc *VirtualConsole) VirtualInput() *VirtualInput {
+	return vc.input
+}
+
+// Output returns the console itself, which is its own output side.
+func (vc *VirtualConsole) Output() OutputTarget {
+	return vc
+}
+
+// GetInputCodePage retrieves the input code page of the console, Utf8 by default.
+func (vc *VirtualConsole) GetInputCodePage() (uint32, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	return vc.inputCP, nil
+}
+
+// SetInputCodePage sets the input code page of the console.
+func (vc *VirtualConsole) SetInputCodePage(cp uint32) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.inputCP = cp
+	return nil
+}
+
+// GetOutputCodePage retrieves the output code page of the console, Utf8 by default.
+func (vc *VirtualConsole) GetOutputCodePage() (uint32, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	return vc.outputCP, nil
+}
+
+// SetOutputCodePage sets the output code page of the console.
+func (vc *VirtualConsole) SetOutputCodePage(cp uint32) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.outputCP = cp
+	return nil
+}
+
+// GetWindowTitle retrieves the title of the console, empty by default.
+func (vc *VirtualConsole) GetWindowTitle() (string, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	return vc.title, nil
+}
+
+// SetWindowTitle sets the title of the console.
+func (vc *VirtualConsole) SetWindowTitle(title string) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.title = title
+	return nil
+}
+
+// Cell returns the cell at the given position, or a zero CharInfo if the position is outside the buffer.
+//
+// Parameters:
+//
+//	x: The column of the cell.
+//	y: The row of the cell.
+//
+// Returns:
+//
+//	CharInfo: The character and attributes stored in the cell.
+func (vc *VirtualConsole) Cell(x, y int16) CharInfo {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if !vc.contains(Coord{X: x, Y: y}) {
+		return CharInfo{}
+	}
+
+	return vc.cells[vc.index(Coord{X: x, Y: y})]
+}
+
+// Cells returns a copy of all the cells of the screen buffer, row by row.
+//
+// Returns:
+//
+//	[]CharInfo: The cells of the screen buffer, Size.X cells per row.
+func (vc *VirtualConsole) Cells() []CharInfo {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	return append([]CharInfo(nil), vc.cells...)
+}
+
+// Line returns the text of a row of the screen buffer without its trailing spaces.
+//
+// Parameters:
+//
+//	y: The row to read.
+//
+// Returns:
+//
+//	string: The characters of the row, or an empty string if the row is outside the buffer.
+func (vc *VirtualConsole) Line(y int16) string {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	return vc.line(y)
+}
+
+func (vc *VirtualConsole) line(y int16) string {
+	if y < 0 || y >= vc.size.Y {
+		return ""
+	}
+
+	row := vc.cells[vc.index(Coord{X: 0, Y: y}):vc.index(Coord{X: 0, Y: y + 1})]
+	return strings.TrimRight(cellsText(row), " ")
+}
+
+// String returns the text of every row of the screen buffer, trailing spaces removed, joined by newlines.
+func (vc *VirtualConsole) String() string {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	lines := make([]string, vc.size.Y)
+	for y := range lines {
+		lines[y] = vc.line(int16(y))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (vc *VirtualConsole) GetMode() (DWord, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	return vc.mode, nil
+}
+
+func (vc *VirtualConsole) SetMode(mode DWord) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.mode = mode
+	return nil
+}
+
+func (vc *VirtualConsole) GetCursorInfo() (CursorInfo, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	return vc.curinfo, nil
+}
+
+func (vc *VirtualConsole) SetCursorInfo(p *CursorInfo) error {
+	if p.Size < 1 || p.Size > 100 {
+		return ErrInvalidParameter
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.curinfo = *p
+	return nil
+}
+
+func (vc *VirtualConsole) GetScreenBufferInfo(p *ScreenBufferInfo) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	*p = ScreenBufferInfo{
+		Size:              vc.size,
+		CursorPosition:    vc.cursor,
+		Attributes:        vc.attributes,
+		Window:            vc.window,
+		MaximumWindowSize: vc.size,
+	}
+
+	return nil
+}
+
+// SetCursorPosition moves the cursor and, like the real console, scrolls the window just enough to keep the cursor visible.
+func (vc *VirtualConsole) SetCursorPosition(newpos Coord) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if !vc.contains(newpos) {
+		return ErrInvalidParameter
+	}
+
+	vc.cursor = newpos
+
+	if dx := newpos.X - vc.window.Right; dx > 0 {
+		vc.window.Left, vc.window.Right = vc.window.Left+dx, vc.window.Right+dx
+	} else if dx := vc.window.Left - newpos.X; dx > 0 {
+		vc.window.Left, vc.window.Right = vc.window.Left-dx, vc.window.Right-dx
+	}
+
+	if dy := newpos.Y - vc.window.Bottom; dy > 0 {
+		vc.window.Top, vc.window.Bottom = vc.window.Top+dy, vc.window.Bottom+dy
+	} else if dy := vc.window.Top - newpos.Y; dy > 0 {
+		vc.window.Top, vc.window.Bottom = vc.window.Top-dy, vc.window.Bottom-dy
+	}
+
+	return nil
+}
+
+func (vc *VirtualConsole) FillCharacter(char rune, length int, wcoord Coord) (uint16, error) {
+	units, i := runeUnits(char), 0
+	return vc.fill(length, wcoord, func(cell *CharInfo) {
+		cell.UnicodeChar = units[i%len(units)]
+		i++
+	})
+}
+
+func (vc *VirtualConsole) FillAttribute(attribute uint16, length int, wcoord Coord) (uint16, error) {
+	return vc.fill(length, wcoord, func(cell *CharInfo) { cell.Attributes = attribute })
+}
+
+func (vc *VirtualConsole) WriteAttribute(attribute uint16, length uint32, wcoord Coord) (uint16, error) {
+	return vc.fill(int(length), wcoord, func(cell *CharInfo) { cell.Attributes = attribute })
+}
+
+func (vc *VirtualConsole) WriteCharacter(char uint16, length uint32, wcoord Coord) (uint16, error) {
+	return vc.fill(int(length), wcoord, func(cell *CharInfo) { cell.UnicodeChar = char })
+}
+
+// fill applies set to length consecutive cells starting at wcoord, wrapping at the end of each row
+// and stopping at the end of the buffer, and returns the number of cells that were touched.
+func (vc *VirtualConsole) fill(length int, wcoord Coord, set func(cell *CharInfo)) (uint16, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if !vc.contains(wcoord) {
+		return 0, ErrInvalidParameter
+	}
+
+	start := vc.index(wcoord)
+	end := min(start+max(length, 0), len(vc.cells))
+	for i := start; i < end; i++ {
+		set(&vc.cells[i])
+	}
+
+	return uint16(end - start), nil
+}
+
+// ScrollScreenBuffer follows ScrollConsoleScreenBuffer: the source rectangle is clipped to the buffer,
+// the cells it leaves behind are filled with fill, and nothing outside cliprect (when not nil) changes.
+func (vc *VirtualConsole) ScrollScreenBuffer(scrollrect, cliprect *SmallRect, dest Coord, fill CharInfo) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if scrollrect == nil {
+		return ErrInvalidParameter
+	}
+
+	bounds := SmallRect{Left: 0, Top: 0, Right: vc.size.X - 1, Bottom: vc.size.Y - 1}
+	src, ok := intersectRect(*scrollrect, bounds)
+	if !ok {
+		return ErrInvalidParameter
+	}
+
+	clip := bounds
+	if cliprect != nil {
+		if clip, ok = intersectRect(*cliprect, bounds); !ok {
+			return nil
+		}
+	}
+
+	dest.X += src.Left - scrollrect.Left
+	dest.Y += src.Top - scrollrect.Top
+
+	width, height := src.Right-src.Left+1, src.Bottom-src.Top+1
+	saved := make([]CharInfo, 0, int(width)*int(height))
+	for y := src.Top; y <= src.Bottom; y++ {
+		for x := src.Left; x <= src.Right; x++ {
+			saved = append(saved, vc.cells[vc.index(Coord{X: x, Y: y})])
+		}
+	}
+
+	for y := src.Top; y <= src.Bottom; y++ {
+		for x := src.Left; x <= src.Right; x++ {
+			if pos := (Coord{X: x, Y: y}); clip.contains(pos) {
+				vc.cells[vc.index(pos)] = fill
+			}
+		}
+	}
+
+	for y := int16(0); y < height; y++ {
+		for x := int16(0); x < width; x++ {
+			if pos := (Coord{X: dest.X + x, Y: dest.Y + y}); clip.contains(pos) {
+				vc.cells[vc.index(pos)] = saved[int(y)*int(width)+int(x)]
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteOutput follows WriteConsoleOutput: region is clipped to the buffer and to the cells of buffer from
+// bufcoord, and holds the rectangle written on return.
+func (vc *VirtualConsole) WriteOutput(buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error {
+	return vc.copyRegion(buffer, bufsize, bufcoord, region, func(cell, buf *CharInfo) { *cell = *buf })
+}
+
+// ReadOutput follows ReadConsoleOutput: region is clipped to the buffer and to the cells of buffer from
+// bufcoord, and holds the rectangle read on return.
+func (vc *VirtualConsole) ReadOutput(buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect) error {
+	return vc.copyRegion(buffer, bufsize, bufcoord, region, func(cell, buf *CharInfo) { *buf = *cell })
+}
+
+// ReadOutputCharacter reads the characters of len(buffer) consecutive cells starting at wcoord,
+// wrapping at the end of each row and stopping at the end of the buffer.
+func (vc *VirtualConsole) ReadOutputCharacter(buffer []uint16, wcoord Coord) (uint32, error) {
+	return vc.read(len(buffer), wcoord, func(i int, cell CharInfo) { buffer[i] = cell.UnicodeChar })
+}
+
+// ReadOutputAttribute reads the attributes of len(buffer) consecutive cells starting at wcoord,
+// wrapping at the end of each row and stopping at the end of the buffer.
+func (vc *VirtualConsole) ReadOutputAttribute(buffer []uint16, wcoord Coord) (uint32, error) {
+	return vc.read(len(buffer), wcoord, func(i int, cell CharInfo) { buffer[i] = cell.Attributes })
+}
+
+// read passes length consecutive cells starting at wcoord to get, stopping at the end of the buffer,
+// and returns the number of cells read.
+func (vc *VirtualConsole) read(length int, wcoord Coord, get func(i int, cell CharInfo)) (uint32, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if !vc.contains(wcoord) {
+		return 0, ErrInvalidParameter
+	}
+
+	start := vc.index(wcoord)
+	end := min(start+length, len(vc.cells))
+	for i := start; i < end; i++ {
+		get(i-start, vc.cells[i])
+	}
+
+	return uint32(end - start), nil
+}
+
+// copyRegion clips region to the buffer and to the bufsize cells of buffer from bufcoord, stores the clipped
+// rectangle in region, and calls move with each of its cells and the matching cell of buffer.
+func (vc *VirtualConsole) copyRegion(buffer []CharInfo, bufsize, bufcoord Coord, region *SmallRect, move func(cell, buf *CharInfo)) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if !validOutputBuffer(len(buffer), bufsize, bufcoord) || region == nil {
+		return ErrInvalidParameter
+	}
+
+	origin := Coord{X: region.Left, Y: region.Top}
+	clipped := SmallRect{
+		Left:   max(region.Left, 0),
+		Top:    max(region.Top, 0),
+		Right:  int16(min(int(region.Right), int(origin.X)+int(bufsize.X-bufcoord.X)-1, int(vc.size.X)-1)),
+		Bottom: int16(min(int(region.Bottom), int(origin.Y)+int(bufsize.Y-bufcoord.Y)-1, int(vc.size.Y)-1)),
+	}
+	*region = clipped
+
+	for y := clipped.Top; y <= clipped.Bottom; y++ {
+		for x := clipped.Left; x <= clipped.Right; x++ {
+			bx, by := int(bufcoord.X)+int(x)-int(origin.X), int(bufcoord.Y)+int(y)-int(origin.Y)
+			move(&vc.cells[vc.index(Coord{X: x, Y: y})], &buffer[by*int(bufsize.X)+bx])
+		}
+	}
+
+	return nil
+}
+
+// validOutputBuffer reports whether a buffer of length cells holds bufsize cells, with bufcoord inside them.
+func validOutputBuffer(length int, bufsize, bufcoord Coord) bool {
+	return bufsize.X > 0 && bufsize.Y > 0 && length >= int(bufsize.X)*int(bufsize.Y) &&
+		bufcoord.X >= 0 && bufcoord.Y >= 0 && bufcoord.X < bufsize.X && bufcoord.Y < bufsize.Y
+}
+
+// writeCells copies cells to a row of the buffer starting at wcoord, clipped at the end of the row.
+func (vc *VirtualConsole) writeCells(cells []CharInfo, wcoord Coord) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if !vc.contains(wcoord) {
+		return ErrInvalidParameter
+	}
+
+	copy(vc.cells[vc.index(wcoord):vc.index(Coord{X: 0, Y: wcoord.Y + 1})], cells)
+	return nil
+}
+
+func (vc *VirtualConsole) contains(pos Coord) bool {
+	return pos.X >= 0 && pos.Y >= 0 && pos.X < vc.size.X && pos.Y < vc.size.Y
+}
+
+func (vc *VirtualConsole) index(pos Coord) int {
+	return int(pos.Y)*int(vc.size.X) + int(pos.X)
+}
+
+// contains checks if pos lies inside the inclusive rectangle.
+func (rect SmallRect) contains(pos Coord) bool {
+	return pos.X >= rect.Left && pos.X <= rect.Right && pos.Y >= rect.Top && pos.Y <= rect.Bottom
+}
+
+// intersectRect returns the intersection of two inclusive rectangles and whether it is non-empty.
+func intersectRect(a, b SmallRect) (SmallRect, bool) {
+	r := SmallRect{
+		Left:   max(a.Left, b.Left),
+		Top:    max(a.Top, b.Top),
+		Right:  min(a.Right, b.Right),
+		Bottom: min(a.Bottom, b.Bottom),
+	}
+
+	return r, r.Left <= r.Right && r.Top <= r.Bottom
+}
@@ -0,0 +1,305 @@
+package cons
+
+import (
+	"testing"
+	"unicode/utf16"
+)
+
+// newLetters returns a console whose rows hold the given text, one row per line.
+func newLetters(t *testing.T, rows ...string) *VirtualConsole {
+	t.Helper()
+
+	vc := NewVirtualConsole(int16(len(rows[0])), int16(len(rows)))
+	for y, row := range rows {
+		if err := writeCells(vc, TextCells(row, vc.attributes), Coord{X: 0, Y: int16(y)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return vc
+}
+
+func TestVirtualConsoleFill(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		at     Coord
+		want   uint16
+		err    error
+		screen string
+	}{
+		{"in row", 3, Coord{X: 1, Y: 0}, 3, nil, " xxx\n\n"},
+		{"wraps", 7, Coord{X: 3, Y: 0}, 7, nil, "   xx\nxxxxx\n"},
+		{"stops at end", 10, Coord{X: 3, Y: 2}, 2, nil, "\n\n   xx"},
+		{"zero", 0, Coord{X: 0, Y: 0}, 0, nil, "\n\n"},
+		{"outside", 1, Coord{X: 5, Y: 0}, 0, ErrInvalidParameter, "\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := NewVirtualConsole(5, 3)
+
+			n, err := vc.FillCharacter('x', tt.length, tt.at)
+			if n != tt.want || err != tt.err {
+				t.Fatalf("FillCharacter = %d, %v, want %d, %v", n, err, tt.want, tt.err)
+			}
+			if got := vc.String(); got != tt.screen {
+				t.Errorf("screen = %q, want %q", got, tt.screen)
+			}
+
+			n, err = vc.FillAttribute(0x1e, tt.length, tt.at)
+			if n != tt.want || err != tt.err {
+				t.Fatalf("FillAttribute = %d, %v, want %d, %v", n, err, tt.want, tt.err)
+			}
+			for i, cell := range vc.Cells() {
+				if want := cell.UnicodeChar == 'x'; (cell.Attributes == 0x1e) != want {
+					t.Errorf("cell %d attributes = %#x, filled = %v", i, cell.Attributes, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualConsoleScroll(t *testing.T) {
+	fill := CharInfo{UnicodeChar: '.', Attributes: 0x1f}
+
+	tests := []struct {
+		name   string
+		scroll SmallRect
+		clip   *SmallRect
+		dest   Coord
+		err    error
+		screen string
+	}{
+		{
+			name:   "up",
+			scroll: SmallRect{Left: 0, Top: 1, Right: 3, Bottom: 3},
+			dest:   Coord{X: 0, Y: 0},
+			screen: "efgh\nijkl\nmnop\n....",
+		},
+		{
+			name:   "clipped",
+			scroll: SmallRect{Left: 0, Top: 1, Right: 3, Bottom: 3},
+			clip:   &SmallRect{Left: 0, Top: 0, Right: 3, Bottom: 1},
+			dest:   Coord{X: 0, Y: 0},
+			screen: "efgh\nijkl\nijkl\nmnop",
+		},
+		{
+			name:   "source outside buffer",
+			scroll: SmallRect{Left: -1, Top: 0, Right: 1, Bottom: 0},
+			dest:   Coord{X: 2, Y: 1},
+			screen: "..cd\nefga\nijkl\nmnop",
+		},
+		{
+			name:   "clip outside buffer",
+			scroll: SmallRect{Left: 0, Top: 0, Right: 3, Bottom: 0},
+			clip:   &SmallRect{Left: 4, Top: 4, Right: 6, Bottom: 6},
+			dest:   Coord{X: 0, Y: 1},
+			screen: "abcd\nefgh\nijkl\nmnop",
+		},
+		{
+			name:   "source outside",
+			scroll: SmallRect{Left: 5, Top: 0, Right: 6, Bottom: 0},
+			err:    ErrInvalidParameter,
+			screen: "abcd\nefgh\nijkl\nmnop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := newLetters(t, "abcd", "efgh", "ijkl", "mnop")
+
+			scroll := tt.scroll
+			if err := vc.ScrollScreenBuffer(&scroll, tt.clip, tt.dest, fill); err != tt.err {
+				t.Fatalf("ScrollScreenBuffer = %v, want %v", err, tt.err)
+			}
+			if got := vc.String(); got != tt.screen {
+				t.Errorf("screen = %q, want %q", got, tt.screen)
+			}
+			for i, cell := range vc.Cells() {
+				if cell.UnicodeChar == '.' && cell.Attributes != fill.Attributes {
+					t.Errorf("filled cell %d attributes = %#x, want %#x", i, cell.Attributes, fill.Attributes)
+				}
+			}
+		})
+	}
+
+	if err := NewVirtualConsole(2, 2).ScrollScreenBuffer(nil, nil, Coord{}, fill); err != ErrInvalidParameter {
+		t.Errorf("ScrollScreenBuffer(nil) = %v, want ErrInvalidParameter", err)
+	}
+}
+
+func TestVirtualConsoleWriteOutput(t *testing.T) {
+	buffer := StringCells("123456789", ForegroundGreen)
+
+	tests := []struct {
+		name     string
+		bufsize  Coord
+		bufcoord Coord
+		region   SmallRect
+		err      error
+		clipped  SmallRect
+		screen   string
+	}{
+		{
+			name:    "inside",
+			bufsize: Coord{X: 3, Y: 3},
+			region:  SmallRect{Left: 1, Top: 1, Right: 2, Bottom: 2},
+			clipped: SmallRect{Left: 1, Top: 1, Right: 2, Bottom: 2},
+			screen:  "abcd\ne12h\ni45l\nmnop",
+		},
+		{
+			name:     "from buffer coordinate",
+			bufsize:  Coord{X: 3, Y: 3},
+			bufcoord: Coord{X: 1, Y: 1},
+			region:   SmallRect{Left: 0, Top: 0, Right: 3, Bottom: 3},
+			clipped:  SmallRect{Left: 0, Top: 0, Right: 1, Bottom: 1},
+			screen:   "56cd\n89gh\nijkl\nmnop",
+		},
+		{
+			name:    "past the buffer",
+			bufsize: Coord{X: 3, Y: 3},
+			region:  SmallRect{Left: 2, Top: 3, Right: 6, Bottom: 6},
+			clipped: SmallRect{Left: 2, Top: 3, Right: 3, Bottom: 3},
+			screen:  "abcd\nefgh\nijkl\nmn12",
+		},
+		{
+			name:    "negative corner",
+			bufsize: Coord{X: 3, Y: 3},
+			region:  SmallRect{Left: -1, Top: -2, Right: 1, Bottom: 0},
+			clipped: SmallRect{Left: 0, Top: 0, Right: 1, Bottom: 0},
+			screen:  "89cd\nefgh\nijkl\nmnop",
+		},
+		{
+			name:    "outside",
+			bufsize: Coord{X: 3, Y: 3},
+			region:  SmallRect{Left: 4, Top: 0, Right: 6, Bottom: 0},
+			clipped: SmallRect{Left: 4, Top: 0, Right: 3, Bottom: 0},
+			screen:  "abcd\nefgh\nijkl\nmnop",
+		},
+		{
+			name:    "buffer too short",
+			bufsize: Coord{X: 4, Y: 3},
+			region:  SmallRect{Left: 0, Top: 0, Right: 3, Bottom: 3},
+			err:     ErrInvalidParameter,
+			clipped: SmallRect{Left: 0, Top: 0, Right: 3, Bottom: 3},
+			screen:  "abcd\nefgh\nijkl\nmnop",
+		},
+		{
+			name:     "buffer coordinate outside",
+			bufsize:  Coord{X: 3, Y: 3},
+			bufcoord: Coord{X: 3, Y: 0},
+			region:   SmallRect{Left: 0, Top: 0, Right: 3, Bottom: 3},
+			err:      ErrInvalidParameter,
+			clipped:  SmallRect{Left: 0, Top: 0, Right: 3, Bottom: 3},
+			screen:   "abcd\nefgh\nijkl\nmnop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := newLetters(t, "abcd", "efgh", "ijkl", "mnop")
+
+			region := tt.region
+			if err := vc.WriteOutput(buffer, tt.bufsize, tt.bufcoord, &region); err != tt.err {
+				t.Fatalf("WriteOutput = %v, want %v", err, tt.err)
+			}
+			if region != tt.clipped {
+				t.Errorf("region = %+v, want %+v", region, tt.clipped)
+			}
+			if got := vc.String(); got != tt.screen {
+				t.Errorf("screen = %q, want %q", got, tt.screen)
+			}
+
+			// Reading the same region back gives the cells written, at the same place in the buffer.
+			if tt.err != nil {
+				return
+			}
+			read := make([]CharInfo, len(buffer))
+			region = tt.region
+			if err := vc.ReadOutput(read, tt.bufsize, tt.bufcoord, &region); err != nil || region != tt.clipped {
+				t.Fatalf("ReadOutput = %v, %+v, want nil, %+v", err, region, tt.clipped)
+			}
+			for y := region.Top; y <= region.Bottom; y++ {
+				for x := region.Left; x <= region.Right; x++ {
+					i := int(tt.bufcoord.Y+y-tt.region.Top)*int(tt.bufsize.X) + int(tt.bufcoord.X+x-tt.region.Left)
+					if read[i] != buffer[i] {
+						t.Errorf("read cell %d = %+v, want %+v", i, read[i], buffer[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualConsoleReadOutputRuns(t *testing.T) {
+	vc := newLetters(t, "abc", "def")
+	vc.FillAttribute(0x2f, 2, Coord{X: 2, Y: 0})
+
+	chars := make([]uint16, 5)
+	n, err := vc.ReadOutputCharacter(chars, Coord{X: 2, Y: 0})
+	if err != nil || n != 4 || string(utf16.Decode(chars[:n])) != "cdef" {
+		t.Errorf("ReadOutputCharacter = %d, %v, %q, want 4, nil, \"cdef\"", n, err, string(utf16.Decode(chars[:n])))
+	}
+
+	attrs := make([]uint16, 3)
+	n, err = vc.ReadOutputAttribute(attrs, Coord{X: 1, Y: 0})
+	if err != nil || n != 3 || attrs[0] == 0x2f || attrs[1] != 0x2f || attrs[2] != 0x2f {
+		t.Errorf("ReadOutputAttribute = %d, %v, %#x", n, err, attrs)
+	}
+
+	if _, err := vc.ReadOutputCharacter(chars, Coord{X: 0, Y: 2}); err != ErrInvalidParameter {
+		t.Errorf("ReadOutputCharacter outside = %v, want ErrInvalidParameter", err)
+	}
+}
+
+func TestVirtualConsoleResize(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   Coord
+		err    error
+		screen string
+		cursor Coord
+	}{
+		{"grow", Coord{X: 5, Y: 3}, nil, "abc\ndef\n", Coord{X: 2, Y: 1}},
+		{"shrink", Coord{X: 2, Y: 1}, nil, "ab", Coord{X: 1, Y: 0}},
+		{"same", Coord{X: 3, Y: 2}, nil, "abc\ndef", Coord{X: 2, Y: 1}},
+		{"invalid", Coord{X: 0, Y: 2}, ErrInvalidParameter, "abc\ndef", Coord{X: 2, Y: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := newLetters(t, "abc", "def")
+			vc.SetCursorPosition(Coord{X: 2, Y: 1})
+			vc.VirtualInput().SetMode(EnableWindowInput)
+
+			if err := vc.Resize(tt.size); err != tt.err {
+				t.Fatalf("Resize = %v, want %v", err, tt.err)
+			}
+			if got := vc.String(); got != tt.screen {
+				t.Errorf("screen = %q, want %q", got, tt.screen)
+			}
+
+			var info ScreenBufferInfo
+			vc.GetScreenBufferInfo(&info)
+			if info.CursorPosition != tt.cursor {
+				t.Errorf("cursor = %+v, want %+v", info.CursorPosition, tt.cursor)
+			}
+			if want := (SmallRect{Right: info.Size.X - 1, Bottom: info.Size.Y - 1}); info.Window != want {
+				t.Errorf("window = %+v, want %+v", info.Window, want)
+			}
+
+			// Only a change of size is reported to the input.
+			changed := tt.err == nil && tt.size != Coord{X: 3, Y: 2}
+			if got := vc.VirtualInput().Pending(); (got == 1) != changed {
+				t.Fatalf("pending events = %d, want a record: %v", got, changed)
+			}
+			if changed {
+				events, err := ReadEvents(vc.Input(), 1)
+				if err != nil || len(events) != 1 || events[0] != (WindowBufferSizeRecord{Size: tt.size}) {
+					t.Errorf("ReadEvents = %+v, %v, want the new size", events, err)
+				}
+			}
+		})
+	}
+}
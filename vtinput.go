@@ -0,0 +1,472 @@
+package cons
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// EscapeTimeout is how long a lone ESC is held back waiting for the rest of an escape sequence
+// before it is reported as the Escape key.
+const EscapeTimeout = 50 * time.Millisecond
+
+// vtInputMaxSequence bounds the length of an escape sequence, so a stray ESC [ cannot hold back input forever.
+const vtInputMaxSequence = 64
+
+var (
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+)
+
+// PasteEvent is text pasted into a terminal with bracketed paste enabled, see EnableBracketedPaste.
+// The text is delivered as is, without interpreting the control characters or escape sequences it contains.
+type PasteEvent struct {
+	Text string
+}
+
+// EventType reports a paste as a key event, since a console without bracketed paste delivers pasted text as keys.
+func (PasteEvent) EventType() uint16 { return KeyEvent }
+
+// KeyEvents returns the key down records a console without bracketed paste would report for the text.
+func (p PasteEvent) KeyEvents() []KeyEventRecord {
+	keys := make([]KeyEventRecord, 0, len(p.Text))
+	for _, r := range p.Text {
+		keys = append(keys, runeKeyEvents(r)...)
+	}
+
+	return keys
+}
+
+// EnableBracketedPaste asks the terminal to surround pasted text with ESC [ 200 ~ and ESC [ 201 ~,
+// which a VTInputDecoder reports as a PasteEvent.
+//
+// Parameters:
+//
+//	out: The terminal output, such as os.Stdout.
+//	enable: True to enable bracketed paste, false to disable it.
+//
+// Returns:
+//
+//	error: If the function successfully writes the sequence, it returns nil. Otherwise, it returns an error.
+func EnableBracketedPaste(out io.Writer, enable bool) error {
+	seq := "\x1b[?2004l"
+	if enable {
+		seq = "\x1b[?2004h"
+	}
+
+	_, err := io.WriteString(out, seq)
+	return err
+}
+
+// VTInputDecoder turns the byte stream a terminal sends for keyboard input, such as ESC [ 1 ; 5 C for
+// Ctrl+Right, into the key events a console reports for the same keys. It is the input counterpart of VTParser:
+// the stream may be split at any byte, and incomplete sequences are held back until the next Feed.
+//
+// A lone ESC cannot be told apart from the start of a sequence. The reader is expected to call Flush
+// when Pending reports held back bytes and no more input arrived within EscapeTimeout.
+type VTInputDecoder struct {
+	// Mouse decodes the mouse reports found in the stream, see EnableMouseTracking.
+	Mouse MouseDecoder
+
+	buf     []byte
+	paste   []byte
+	pasting bool
+	high    uint16 // The high surrogate of a key fed by FeedKey, waiting for its low surrogate.
+}
+
+// NewVTInputDecoder creates a decoder with nothing held back.
+//
+// Returns:
+//
+//	*VTInputDecoder: The new decoder.
+func NewVTInputDecoder() *VTInputDecoder {
+	return &VTInputDecoder{}
+}
+
+// Pending reports whether the decoder holds back bytes of an incomplete sequence or character, which Flush
+// decodes. The text of a bracketed paste is not reported: a paste may arrive slower than EscapeTimeout,
+// and it stays buffered until its end marker.
+func (d *VTInputDecoder) Pending() bool {
+	return len(d.buf) > 0 && !d.pasting
+}
+
+// Feed decodes input bytes.
+//
+// Parameters:
+//
+//	b: The bytes read from the terminal.
+//
+// Returns:
+//
+//	[]Event: The KeyEventRecord, MouseEventRecord, FocusEventRecord and PasteEvent values completed by b, in order.
+func (d *VTInputDecoder) Feed(b []byte) []Event {
+	d.buf = append(d.buf, b...)
+	return d.decode(false)
+}
+
+// FeedKey decodes a key event read from a console in EnableVirtualTerminalInput mode, where the
+// console reports every byte of a sequence as a key carrying it in UnicodeChar. Key up records
+// of such keys are dropped, and records without a character are returned unchanged.
+//
+// Parameters:
+//
+//	key: The key event read from the console.
+//
+// Returns:
+//
+//	[]Event: The events completed by key, in order.
+func (d *VTInputDecoder) FeedKey(key KeyEventRecord) []Event {
+	switch c := key.UnicodeChar; {
+	case c == 0:
+		return []Event{key}
+	case key.KeyDown == 0:
+		return nil
+	case utf16.IsSurrogate(rune(c)) && c < 0xdc00:
+		d.high = c
+		return nil
+	case utf16.IsSurrogate(rune(c)):
+		r := utf16.DecodeRune(rune(d.high), rune(c))
+		d.high = 0
+		return d.Feed(utf8.AppendRune(nil, r))
+	}
+
+	var events []Event
+	for n := max(key.RepeatCount, 1); n > 0; n-- {
+		events = append(events, d.Feed(utf8.AppendRune(nil, rune(key.UnicodeChar)))...)
+	}
+
+	return events
+}
+
+// Flush decodes the bytes held back, reporting a lone ESC as the Escape key and the
+// start of an incomplete sequence as the keys of its characters. An open bracketed paste,
+// possibly with the start of its end marker held back, is left as it is.
+//
+// Returns:
+//
+//	[]Event: The events decoded from the held back bytes.
+func (d *VTInputDecoder) Flush() []Event {
+	if d.pasting {
+		return nil
+	}
+
+	return d.decode(true)
+}
+
+// decode consumes the complete sequences and characters at the start of the buffer.
+// When final is true, incomplete ones are consumed too.
+func (d *VTInputDecoder) decode(final bool) []Event {
+	var events []Event
+	for len(d.buf) > 0 {
+		if d.pasting {
+			i := bytes.Index(d.buf, pasteEnd)
+			if i < 0 {
+				// Keep the bytes that could be the start of the end marker.
+				keep := 0
+				for n := min(len(pasteEnd)-1, len(d.buf)); n > 0; n-- {
+					if bytes.HasPrefix(pasteEnd, d.buf[len(d.buf)-n:]) {
+						keep = n
+						break
+					}
+				}
+				d.paste = append(d.paste, d.buf[:len(d.buf)-keep]...)
+				d.buf = d.buf[len(d.buf)-keep:]
+				break
+			}
+
+			events = append(events, PasteEvent{Text: string(append(d.paste, d.buf[:i]...))})
+			d.paste, d.pasting = d.paste[:0], false
+			d.buf = d.buf[i+len(pasteEnd):]
+			continue
+		}
+
+		n, evs := d.decodeOne(d.buf, final)
+		if n == 0 {
+			break
+		}
+
+		if bytes.HasPrefix(d.buf, pasteStart) {
+			d.pasting = true
+		}
+
+		events = append(events, evs...)
+		d.buf = d.buf[n:]
+	}
+
+	if len(d.buf) == 0 {
+		d.buf = nil
+	}
+
+	return events
+}
+
+// decodeOne decodes the sequence or character at the start of b. It returns the number of
+// bytes consumed, which is 0 when b is incomplete and final is false.
+func (d *VTInputDecoder) decodeOne(b []byte, final bool) (int, []Event) {
+	if b[0] != 0x1b {
+		if !utf8.FullRune(b) && !final {
+			return 0, nil
+		}
+
+		r, size := utf8.DecodeRune(b)
+		return size, keyEvents(runeKeyEvents(r))
+	}
+
+	if len(b) == 1 {
+		if !final {
+			return 0, nil
+		}
+		return 1, keyEvents(runeKeyEvents(0x1b))
+	}
+
+	switch b[1] {
+	case '[':
+		n, events, ok := d.decodeCSI(b)
+		if ok {
+			return n, events
+		}
+		if n == 0 && !final {
+			return 0, nil
+		}
+	case 'O':
+		if len(b) == 2 {
+			if !final {
+				return 0, nil
+			}
+			break
+		}
+
+		if key, ok := ss3Key(b[2]); ok {
+			return 3, []Event{key}
+		}
+	case 0x1b:
+		// ESC ESC is the Escape key pressed twice, or Alt with a sequence; report the first one.
+		return 1, keyEvents(runeKeyEvents(0x1b))
+	default:
+		if !utf8.FullRune(b[1:]) && !final {
+			return 0, nil
+		}
+
+		// ESC followed by a character is the character typed with Alt.
+		r, size := utf8.DecodeRune(b[1:])
+		keys := runeKeyEvents(r)
+		for i := range keys {
+			keys[i].ControlKeyState |= LeftAltPressed
+		}
+		return 1 + size, keyEvents(keys)
+	}
+
+	// An unrecognised or incomplete sequence: report the introducer as Alt with its character.
+	keys := runeKeyEvents(rune(b[1]))
+	for i := range keys {
+		keys[i].ControlKeyState |= LeftAltPressed
+	}
+	return 2, keyEvents(keys)
+}
+
+// decodeCSI decodes a control sequence starting with ESC [. It returns ok false with n 0 when the
+// sequence is incomplete, and ok false with n non-zero when it is malformed.
+func (d *VTInputDecoder) decodeCSI(b []byte) (n int, events []Event, ok bool) {
+	end := 2
+	for ; end < len(b); end++ {
+		c := b[end]
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+		if c < 0x20 || c > 0x3f || end >= vtInputMaxSequence {
+			return end, nil, false
+		}
+	}
+	if end == len(b) {
+		return 0, nil, false
+	}
+
+	body, final := string(b[2:end]), b[end]
+	n = end + 1
+
+	switch {
+	case final == 'M' && body == "":
+		// An X10 mouse report carries three more bytes after the final byte.
+		if len(b) < n+3 {
+			return 0, nil, false
+		}
+		n += 3
+		fallthrough
+	case (final == 'M' || final == 'm') && strings.HasPrefix(body, "<"),
+		final == 'M' && strings.Count(body, ";") == 2:
+		if ev, ok := d.Mouse.Decode(b[:n]); ok {
+			return n, []Event{ev}, true
+		}
+		return n, nil, true
+	}
+
+	if body != "" && (body[0] < '0' || body[0] > ';') {
+		// Private sequences, such as the replies to device queries, are not keys.
+		return n, nil, true
+	}
+
+	var params []int
+	for _, field := range bytes.Split([]byte(body), []byte{';'}) {
+		v, _ := strconv.Atoi(string(bytes.SplitN(field, []byte{':'}, 2)[0]))
+		params = append(params, v)
+	}
+	param := func(i, def int) int {
+		if i < len(params) && params[i] > 0 {
+			return params[i]
+		}
+		return def
+	}
+	state := modifierState(param(1, 1))
+
+	switch final {
+	case 'A', 'B', 'C', 'D', 'H', 'F':
+		vk := map[byte]uint16{'A': VkUp, 'B': VkDown, 'C': VkRight, 'D': VkLeft, 'H': VkHome, 'F': VkEnd}[final]
+		return n, []Event{specialKey(vk, state|EnhancedKey)}, true
+	case 'P', 'Q', 'R', 'S':
+		return n, []Event{specialKey(VkF1+uint16(final-'P'), state)}, true
+	case 'Z':
+		return n, []Event{specialKey(VkTab, ShiftPressed)}, true
+	case 'I':
+		return n, []Event{FocusEventRecord{SetFocus: true}}, true
+	case 'O':
+		return n, []Event{FocusEventRecord{SetFocus: false}}, true
+	case 'u':
+		// CSI code ; modifiers u, the fixterms and kitty keyboard encoding.
+		keys := runeKeyEvents(rune(param(0, 0)))
+		for i := range keys {
+			keys[i].ControlKeyState |= state
+		}
+		return n, keyEvents(keys), true
+	case '~':
+		code := param(0, 0)
+		if code == 200 || code == 201 {
+			// The paste markers are handled by the decoder; a stray end marker is dropped.
+			return n, nil, true
+		}
+
+		if vk, enhanced, known := tildeKey(code); known {
+			if enhanced {
+				state |= EnhancedKey
+			}
+			return n, []Event{specialKey(vk, state)}, true
+		}
+	}
+
+	return n, nil, true
+}
+
+// tildeKey maps the code of a CSI code ~ sequence to its virtual key.
+func tildeKey(code int) (vk uint16, enhanced, ok bool) {
+	switch code {
+	case 1, 7:
+		return VkHome, true, true
+	case 2:
+		return VkInsert, true, true
+	case 3:
+		return VkDelete, true, true
+	case 4, 8:
+		return VkEnd, true, true
+	case 5:
+		return VkPrior, true, true
+	case 6:
+		return VkNext, true, true
+	case 11, 12, 13, 14, 15:
+		return VkF1 + uint16(code-11), false, true
+	case 17, 18, 19, 20, 21:
+		return VkF6 + uint16(code-17), false, true
+	case 23, 24:
+		return VkF11 + uint16(code-23), false, true
+	}
+
+	return 0, false, false
+}
+
+// ss3Key maps the final byte of an ESC O sequence, sent for the cursor and function keys
+// in application mode, to its key event.
+func ss3Key(c byte) (KeyEventRecord, bool) {
+	switch c {
+	case 'A', 'B', 'C', 'D', 'H', 'F':
+		vk := map[byte]uint16{'A': VkUp, 'B': VkDown, 'C': VkRight, 'D': VkLeft, 'H': VkHome, 'F': VkEnd}[c]
+		return specialKey(vk, EnhancedKey), true
+	case 'P', 'Q', 'R', 'S':
+		return specialKey(VkF1+uint16(c-'P'), 0), true
+	case 'M':
+		key := runeKeyEvents('\r')[0]
+		key.ControlKeyState |= EnhancedKey
+		return key, true
+	}
+
+	return KeyEventRecord{}, false
+}
+
+// modifierState converts the modifier parameter of a sequence, 1 plus a bit mask of
+// Shift (1), Alt (2) and Ctrl (4), to a control key state.
+func modifierState(param int) uint32 {
+	var state uint32
+	mask := param - 1
+	if mask&1 != 0 {
+		state |= ShiftPressed
+	}
+	if mask&2 != 0 {
+		state |= LeftAltPressed
+	}
+	if mask&4 != 0 {
+		state |= LeftCtrlPressed
+	}
+
+	return state
+}
+
+// specialKey returns the key down record of a key that does not produce a character.
+func specialKey(vk uint16, state uint32) KeyEventRecord {
+	return KeyEventRecord{KeyDown: 1, RepeatCount: 1, VirtualKeyCode: vk, ControlKeyState: state}
+}
+
+func keyEvents(keys []KeyEventRecord) []Event {
+	events := make([]Event, len(keys))
+	for i, key := range keys {
+		events[i] = key
+	}
+
+	return events
+}
+
+// runeKeyEvents converts a character read from a terminal to the key down records kernel32 would report.
+// Characters outside the Basic Multilingual Plane become two records carrying the surrogate pair.
+func runeKeyEvents(r rune) []KeyEventRecord {
+	key := KeyEventRecord{KeyDown: 1, RepeatCount: 1, UnicodeChar: uint16(r)}
+
+	switch {
+	case r == '\r' || r == '\n':
+		key.VirtualKeyCode, key.UnicodeChar = VkReturn, '\r'
+	case r == '\t':
+		key.VirtualKeyCode = VkTab
+	case r == 0x08 || r == 0x7f:
+		key.VirtualKeyCode, key.UnicodeChar = VkBack, 0x08
+	case r == 0x1b:
+		key.VirtualKeyCode = VkEscape
+	case r == 0:
+		key.VirtualKeyCode, key.ControlKeyState = VkSpace, LeftCtrlPressed
+	case r < ' ':
+		key.VirtualKeyCode, key.ControlKeyState = uint16('A'+r-1), LeftCtrlPressed
+	case r == ' ':
+		key.VirtualKeyCode = VkSpace
+	case r >= 'a' && r <= 'z':
+		key.VirtualKeyCode = uint16(r - 'a' + 'A')
+	case r >= 'A' && r <= 'Z':
+		key.VirtualKeyCode, key.ControlKeyState = uint16(r), ShiftPressed
+	case r >= '0' && r <= '9':
+		key.VirtualKeyCode = uint16(r)
+	case r > 0xffff:
+		high, low := utf16.EncodeRune(r)
+		second := key
+		key.UnicodeChar, second.UnicodeChar = uint16(high), uint16(low)
+		return []KeyEventRecord{key, second}
+	}
+
+	return []KeyEventRecord{key}
+}
@@ -0,0 +1,53 @@
+package cons
+
+import "testing"
+
+func TestVTInputDecoderPaste(t *testing.T) {
+	d := NewVTInputDecoder()
+
+	// An open paste is neither pending nor flushed, also with the start of its end marker held back.
+	for _, b := range []string{"\x1b[200~abc", "def\x1b[20"} {
+		if events := d.Feed([]byte(b)); len(events) != 0 {
+			t.Fatalf("Feed(%q) = %v, want no events", b, events)
+		}
+		if d.Pending() {
+			t.Fatalf("Pending after %q, want false during a paste", b)
+		}
+		if events := d.Flush(); len(events) != 0 {
+			t.Fatalf("Flush after %q = %v, want no events", b, events)
+		}
+	}
+
+	events := d.Feed([]byte("1~x"))
+	if len(events) != 2 {
+		t.Fatalf("events = %v, want the paste and x", events)
+	}
+	if paste, ok := events[0].(PasteEvent); !ok || paste.Text != "abcdef" {
+		t.Errorf("events[0] = %#v, want PasteEvent{Text: \"abcdef\"}", events[0])
+	}
+	if key, ok := events[1].(KeyEventRecord); !ok || key.UnicodeChar != 'x' {
+		t.Errorf("events[1] = %#v, want the x key", events[1])
+	}
+}
+
+func TestVTInputDecoderFlush(t *testing.T) {
+	d := NewVTInputDecoder()
+
+	if events := d.Feed([]byte("\x1b")); len(events) != 0 {
+		t.Fatalf("Feed(ESC) = %v, want no events", events)
+	}
+	if !d.Pending() {
+		t.Fatal("a lone ESC is not pending")
+	}
+
+	events := d.Flush()
+	if len(events) != 1 {
+		t.Fatalf("Flush = %v, want the Escape key", events)
+	}
+	if key, ok := events[0].(KeyEventRecord); !ok || key.VirtualKeyCode != VkEscape {
+		t.Errorf("Flush = %#v, want the Escape key", events[0])
+	}
+	if d.Pending() {
+		t.Error("still pending after Flush")
+	}
+}
@@ -0,0 +1,293 @@
+package cons
+
+import (
+	"unicode/utf8"
+)
+
+// VTHandler receives the actions recognised by a VTParser.
+type VTHandler interface {
+	// Print is called for every printable character.
+	Print(r rune)
+	// Execute is called for every C0 control character, such as CR, LF or BS.
+	Execute(c byte)
+	// EscDispatch is called for an escape sequence such as ESC 7 or ESC ( B.
+	EscDispatch(intermediates []byte, final byte)
+	// CsiDispatch is called for a control sequence. private is the private marker (such as '?'),
+	// or 0, and omitted parameters are reported as -1.
+	CsiDispatch(private byte, params []int, intermediates []byte, final byte)
+	// OscDispatch is called for an operating system command, with the text between ESC ] and the terminator.
+	OscDispatch(data []byte)
+}
+
+// VTSubParamHandler is a VTHandler that reads the sub-parameters of a control sequence, separated by ':'
+// instead of ';', as in the ITU T.416 color CSI 38:2::255:128:0 m. The parser calls CsiDispatchGroups instead
+// of CsiDispatch for it, with each parameter followed by its sub-parameters in a group.
+type VTSubParamHandler interface {
+	VTHandler
+
+	// CsiDispatchGroups is called for a control sequence, with a group per parameter separated by ';'.
+	// Omitted parameters and sub-parameters are reported as -1.
+	CsiDispatchGroups(private byte, groups [][]int, intermediates []byte, final byte)
+}
+
+// vtState is a state of the VTParser state machine.
+type vtState uint8
+
+const (
+	vtGround vtState = iota
+	vtEscape
+	vtEscapeIntermediate
+	vtCsiEntry
+	vtCsiParam
+	vtCsiIntermediate
+	vtCsiIgnore
+	vtOscString
+	vtIgnoreString
+)
+
+const (
+	vtMaxParams = 32
+	vtMaxOsc    = 4096
+)
+
+// VTParser is a streaming parser for VT100/xterm output, following the DEC ANSI parser state machine.
+// Input may be split at any byte, including in the middle of an escape sequence or a UTF-8 character.
+type VTParser struct {
+	handler       VTHandler
+	state         vtState
+	private       byte
+	params        []int
+	sub           []bool // Whether each parameter is a sub-parameter of the one before, following a ':'.
+	intermediates []byte
+	osc           []byte
+	stringEsc     bool
+	utf8          []byte
+}
+
+// NewVTParser creates a parser that reports the recognised actions to handler.
+//
+// Parameters:
+//
+//	handler: The receiver of the printable characters, controls and sequences.
+//
+// Returns:
+//
+//	*VTParser: The new parser, in the ground state.
+func NewVTParser(handler VTHandler) *VTParser {
+	return &VTParser{handler: handler}
+}
+
+// Write feeds bytes to the parser. It never fails.
+func (p *VTParser) Write(b []byte) (int, error) {
+	for _, c := range b {
+		p.feed(c)
+	}
+
+	return len(b), nil
+}
+
+func (p *VTParser) feed(c byte) {
+	// Bytes of a multi-byte UTF-8 character are only meaningful in the ground state.
+	if len(p.utf8) > 0 {
+		if c&0xc0 == 0x80 {
+			p.utf8 = append(p.utf8, c)
+			if utf8.FullRune(p.utf8) {
+				r, _ := utf8.DecodeRune(p.utf8)
+				p.utf8 = p.utf8[:0]
+				p.handler.Print(r)
+			}
+			return
+		}
+
+		p.utf8 = p.utf8[:0]
+		p.handler.Print(utf8.RuneError)
+	}
+
+	switch {
+	case c == 0x18 || c == 0x1a:
+		p.state = vtGround
+		return
+	case c == 0x1b && p.state != vtOscString && p.state != vtIgnoreString:
+		p.clear()
+		p.state = vtEscape
+		return
+	}
+
+	switch p.state {
+	case vtGround:
+		switch {
+		case c < 0x20:
+			p.handler.Execute(c)
+		case c < 0x7f:
+			p.handler.Print(rune(c))
+		case c >= 0xc0:
+			if p.utf8 = append(p.utf8, c); utf8.FullRune(p.utf8) {
+				p.utf8 = p.utf8[:0]
+				p.handler.Print(utf8.RuneError)
+			}
+		case c != 0x7f:
+			p.handler.Print(utf8.RuneError)
+		}
+	case vtEscape:
+		switch {
+		case c < 0x20:
+			p.handler.Execute(c)
+		case c < 0x30:
+			p.intermediates = append(p.intermediates, c)
+			p.state = vtEscapeIntermediate
+		case c == '[':
+			p.state = vtCsiEntry
+		case c == ']':
+			p.state = vtOscString
+		case c == 'P' || c == 'X' || c == '^' || c == '_':
+			p.state = vtIgnoreString
+		case c < 0x7f:
+			p.handler.EscDispatch(p.intermediates, c)
+			p.state = vtGround
+		}
+	case vtEscapeIntermediate:
+		switch {
+		case c < 0x20:
+			p.handler.Execute(c)
+		case c < 0x30:
+			p.intermediates = append(p.intermediates, c)
+		case c < 0x7f:
+			p.handler.EscDispatch(p.intermediates, c)
+			p.state = vtGround
+		}
+	case vtCsiEntry, vtCsiParam:
+		switch {
+		case c < 0x20:
+			p.handler.Execute(c)
+		case c >= '<' && c <= '?' && p.state == vtCsiEntry:
+			p.private = c
+			p.state = vtCsiParam
+		case c >= '0' && c <= '9':
+			p.digit(c)
+			p.state = vtCsiParam
+		case c == ';' || c == ':':
+			p.separator(c)
+			p.state = vtCsiParam
+		case c < 0x30:
+			p.intermediates = append(p.intermediates, c)
+			p.state = vtCsiIntermediate
+		case c < 0x40:
+			p.state = vtCsiIgnore
+		case c < 0x7f:
+			p.dispatchCsi(c)
+		}
+	case vtCsiIntermediate:
+		switch {
+		case c < 0x20:
+			p.handler.Execute(c)
+		case c < 0x30:
+			p.intermediates = append(p.intermediates, c)
+		case c < 0x40:
+			p.state = vtCsiIgnore
+		case c < 0x7f:
+			p.dispatchCsi(c)
+		}
+	case vtCsiIgnore:
+		switch {
+		case c < 0x20:
+			p.handler.Execute(c)
+		case c >= 0x40 && c < 0x7f:
+			p.state = vtGround
+		}
+	case vtOscString, vtIgnoreString:
+		p.string(c)
+	}
+}
+
+// string consumes a byte of an OSC, DCS, SOS, PM or APC string, which ends with BEL or ST (ESC \).
+func (p *VTParser) string(c byte) {
+	if p.stringEsc {
+		p.stringEsc = false
+		if c == '\\' {
+			p.endString()
+			return
+		}
+
+		p.endString()
+		p.clear()
+		p.state = vtEscape
+		p.feed(c)
+		return
+	}
+
+	switch {
+	case c == 0x1b:
+		p.stringEsc = true
+	case c == 0x07:
+		p.endString()
+	case p.state == vtOscString && len(p.osc) < vtMaxOsc:
+		p.osc = append(p.osc, c)
+	}
+}
+
+func (p *VTParser) endString() {
+	if p.state == vtOscString {
+		p.handler.OscDispatch(p.osc)
+	}
+
+	p.state = vtGround
+}
+
+func (p *VTParser) digit(c byte) {
+	if len(p.params) == 0 {
+		p.addParam(false)
+	}
+
+	last := &p.params[len(p.params)-1]
+	if *last < 0 {
+		*last = 0
+	}
+
+	if *last < 65535 {
+		*last = *last*10 + int(c-'0')
+	}
+}
+
+func (p *VTParser) separator(c byte) {
+	if len(p.params) == 0 {
+		p.addParam(false)
+	}
+
+	if len(p.params) < vtMaxParams {
+		p.addParam(c == ':')
+	}
+}
+
+// addParam starts an omitted parameter, or sub-parameter of the one before.
+func (p *VTParser) addParam(sub bool) {
+	p.params = append(p.params, -1)
+	p.sub = append(p.sub, sub)
+}
+
+func (p *VTParser) dispatchCsi(final byte) {
+	if h, ok := p.handler.(VTSubParamHandler); ok {
+		var groups [][]int
+		for i, param := range p.params {
+			if p.sub[i] && len(groups) > 0 {
+				groups[len(groups)-1] = append(groups[len(groups)-1], param)
+			} else {
+				groups = append(groups, []int{param})
+			}
+		}
+
+		h.CsiDispatchGroups(p.private, groups, p.intermediates, final)
+	} else {
+		p.handler.CsiDispatch(p.private, p.params, p.intermediates, final)
+	}
+
+	p.state = vtGround
+}
+
+func (p *VTParser) clear() {
+	p.private = 0
+	p.params = p.params[:0]
+	p.sub = p.sub[:0]
+	p.intermediates = p.intermediates[:0]
+	p.osc = p.osc[:0]
+	p.stringEsc = false
+}
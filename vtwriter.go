@@ -0,0 +1,613 @@
+package cons
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// VTWriter is an io.Writer that interprets VT100/xterm escape sequences itself and renders the output
+// through the screen buffer functions of an OutputTarget (SetCursorPosition, WriteCharacter, WriteAttribute,
+// FillCharacter, FillAttribute and ScrollScreenBuffer). It renders ANSI colored output correctly on consoles
+// where EnableVirtualTerminalProcessing cannot be enabled, and on any other backend such as VirtualConsole.
+//
+// Coordinates of the escape sequences are relative to the window of the screen buffer. The cursor position
+// and window are read back from the target at the beginning of every Write, so output written by other
+// means is taken into account; the current attributes belong to the writer.
+type VTWriter struct {
+	mu     sync.Mutex
+	parser *VTParser
+	r      *vtRenderer
+}
+
+// vtRenderer is the VTHandler that turns the actions of the parser into screen buffer calls.
+type vtRenderer struct {
+	out         OutputTarget
+	err         error     // The first error returned by the target during the current Write.
+	window      SmallRect // The window of the screen buffer, in buffer coordinates.
+	cursor      Coord     // The cursor position, relative to the window.
+	attr        uint16    // The current attributes, before reverse video is applied.
+	defaultAttr uint16    // The attributes selected by SGR 0.
+	saved       Coord     // The cursor position saved by DECSC or SCOSC.
+	savedAttr   uint16    // The attributes saved by DECSC.
+	top         int16     // The first row of the scrolling region, relative to the window.
+	bottom      int16     // The last row of the scrolling region, relative to the window, or -1 for the last window row.
+	autowrap    bool      // Indicates whether printing past the last column wraps to the next line.
+	pendingWrap bool      // Indicates whether the last column was printed and the next character wraps.
+	autoReturn  bool      // Indicates whether LF also returns to the first column.
+}
+
+// NewVTWriter creates a VTWriter rendering to out, starting with the attributes of the screen buffer.
+//
+// Parameters:
+//
+//	out: The output target the escape sequences are rendered to.
+//
+// Returns:
+//
+//	*VTWriter: The new writer.
+//	error: If the function successfully reads the screen buffer information, it returns nil. Otherwise, it returns an error.
+func NewVTWriter(out OutputTarget) (*VTWriter, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return nil, err
+	}
+
+	r := &vtRenderer{
+		out:         out,
+		attr:        scrbufinfo.Attributes,
+		defaultAttr: scrbufinfo.Attributes,
+		savedAttr:   scrbufinfo.Attributes,
+		bottom:      -1,
+		autowrap:    true,
+		autoReturn:  true,
+	}
+
+	if mode, err := out.GetMode(); err == nil {
+		r.autowrap = mode.IsEnableMode(EnableWrapAtEolOutput)
+		r.autoReturn = !mode.IsEnableMode(DisableNewlineAutoReturn)
+	}
+
+	return &VTWriter{parser: NewVTParser(r), r: r}, nil
+}
+
+// Write interprets p and renders it to the output target.
+//
+// Parameters:
+//
+//	p: The output to render, UTF-8 text mixed with escape sequences.
+//
+// Returns:
+//
+//	int: The number of bytes consumed, len(p) unless an error occurs.
+//	error: If the target accepts every call, it returns nil. Otherwise, it returns the first error of the target.
+func (w *VTWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	r := w.r
+	if err := r.sync(); err != nil {
+		return 0, err
+	}
+
+	r.err = nil
+	w.parser.Write(p)
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	return len(p), r.out.SetCursorPosition(r.abs(r.cursor))
+}
+
+// sync reads the window and the cursor position back from the target.
+func (r *vtRenderer) sync() error {
+	var scrbufinfo ScreenBufferInfo
+	if err := r.out.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return err
+	}
+
+	cursor := Coord{
+		X: scrbufinfo.CursorPosition.X - scrbufinfo.Window.Left,
+		Y: scrbufinfo.CursorPosition.Y - scrbufinfo.Window.Top,
+	}
+
+	if r.window != scrbufinfo.Window || cursor != r.cursor {
+		r.pendingWrap = false
+	}
+
+	r.window, r.cursor = scrbufinfo.Window, cursor
+	r.cursor.X = max(0, min(r.cursor.X, r.width()-1))
+	r.cursor.Y = max(0, min(r.cursor.Y, r.height()-1))
+	return nil
+}
+
+func (r *vtRenderer) width() int16 {
+	return r.window.Right - r.window.Left + 1
+}
+
+func (r *vtRenderer) height() int16 {
+	return r.window.Bottom - r.window.Top + 1
+}
+
+// region returns the scrolling region, relative to the window.
+func (r *vtRenderer) region() (int16, int16) {
+	bottom := r.bottom
+	if bottom < 0 || bottom >= r.height() {
+		bottom = r.height() - 1
+	}
+
+	return min(r.top, bottom), bottom
+}
+
+// abs converts a position relative to the window to buffer coordinates.
+func (r *vtRenderer) abs(pos Coord) Coord {
+	return Coord{X: r.window.Left + pos.X, Y: r.window.Top + pos.Y}
+}
+
+// effective returns the attributes written to the cells, with reverse video applied by swapping the colors.
+func (r *vtRenderer) effective() uint16 {
+	attr := r.attr
+	if attr&CommonLvbReverseVideo != 0 {
+		attr = attr&^(CommonLvbReverseVideo|0xff) | attr&0x0f<<4 | attr&0xf0>>4
+	}
+
+	return attr
+}
+
+// blank returns the cell used to erase, a space with the current background.
+func (r *vtRenderer) blank() CharInfo {
+	return CharInfo{UnicodeChar: ' ', Attributes: r.effective() &^ (CommonLvbUnderscore | CommonLvbLeadingByte | CommonLvbTrailingByte)}
+}
+
+func (r *vtRenderer) fail(err error) {
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *vtRenderer) Print(c rune) {
+	// A cell holds a single character, so combining marks and other characters of no width are dropped.
+	width := RuneWidth(c)
+	if width == 0 {
+		return
+	}
+
+	// A wide character is never split across rows: on the last column it wraps first,
+	// or becomes U+FFFD when it cannot.
+	cells := layoutRune(c, width, 0)
+	if len(cells) == 2 && !r.pendingWrap && r.cursor.X >= r.width()-1 {
+		if r.autowrap && r.width() > 1 {
+			r.pendingWrap = true
+		} else {
+			cells = layoutRune(utf8.RuneError, 1, 0)
+		}
+	}
+
+	if r.pendingWrap {
+		r.pendingWrap = false
+		r.cursor.X = 0
+		r.lineFeed()
+	}
+
+	for i := range cells {
+		cells[i].Attributes |= r.effective()
+	}
+	r.fail(writeCells(r.out, cells, r.abs(r.cursor)))
+	r.cursor.X += int16(len(cells) - 1)
+
+	if r.cursor.X < r.width()-1 {
+		r.cursor.X++
+	} else if r.autowrap {
+		r.pendingWrap = true
+	}
+}
+
+func (r *vtRenderer) Execute(c byte) {
+	switch c {
+	case '\b':
+		r.pendingWrap = false
+		r.cursor.X = max(0, r.cursor.X-1)
+	case '\t':
+		r.cursor.X = min(r.width()-1, (r.cursor.X/8+1)*8)
+	case '\n', '\v', '\f':
+		r.pendingWrap = false
+		if r.autoReturn {
+			r.cursor.X = 0
+		}
+		r.lineFeed()
+	case '\r':
+		r.pendingWrap = false
+		r.cursor.X = 0
+	}
+}
+
+// lineFeed moves the cursor down one row, scrolling the scrolling region when the cursor is on its last row.
+func (r *vtRenderer) lineFeed() {
+	if _, bottom := r.region(); r.cursor.Y == bottom {
+		r.scrollUp(1)
+	} else if r.cursor.Y < r.height()-1 {
+		r.cursor.Y++
+	}
+}
+
+// reverseIndex moves the cursor up one row, scrolling the scrolling region when the cursor is on its first row.
+func (r *vtRenderer) reverseIndex() {
+	if top, _ := r.region(); r.cursor.Y == top {
+		r.scrollDown(1)
+	} else if r.cursor.Y > 0 {
+		r.cursor.Y--
+	}
+}
+
+// scrollRows moves the rows from..to of the scrolling region by n rows, up when n is negative,
+// and blanks the rows left behind.
+func (r *vtRenderer) scrollRows(from, to, n int16) {
+	top, bottom := r.region()
+	from, to = max(from, top), min(to, bottom)
+	if from > to || n == 0 {
+		return
+	}
+
+	clip := SmallRect{Left: r.window.Left, Top: r.window.Top + from, Right: r.window.Right, Bottom: r.window.Top + to}
+	if n >= to-from+1 || -n >= to-from+1 {
+		r.erase(SmallRect{Left: 0, Top: from, Right: r.width() - 1, Bottom: to})
+		return
+	}
+
+	scroll := clip
+	r.fail(r.out.ScrollScreenBuffer(&scroll, &clip, Coord{X: clip.Left, Y: clip.Top + n}, r.blank()))
+}
+
+func (r *vtRenderer) scrollUp(n int16) {
+	top, bottom := r.region()
+	r.scrollRows(top, bottom, -n)
+}
+
+func (r *vtRenderer) scrollDown(n int16) {
+	top, bottom := r.region()
+	r.scrollRows(top, bottom, n)
+}
+
+// erase blanks a rectangle given relative to the window.
+func (r *vtRenderer) erase(rect SmallRect) {
+	blank := r.blank()
+	for y := rect.Top; y <= rect.Bottom; y++ {
+		length := int(rect.Right - rect.Left + 1)
+		if length <= 0 {
+			continue
+		}
+
+		pos := r.abs(Coord{X: rect.Left, Y: y})
+		_, err := r.out.FillCharacter(rune(blank.UnicodeChar), length, pos)
+		r.fail(err)
+		_, err = r.out.FillAttribute(blank.Attributes, length, pos)
+		r.fail(err)
+	}
+}
+
+// shiftLine moves the cells of the cursor row from the cursor to the right margin by n columns, left when
+// n is negative, as used by ICH and DCH.
+func (r *vtRenderer) shiftLine(n int16) {
+	right := r.width() - 1
+	if n >= right-r.cursor.X+1 || -n >= right-r.cursor.X+1 {
+		r.erase(SmallRect{Left: r.cursor.X, Top: r.cursor.Y, Right: right, Bottom: r.cursor.Y})
+		return
+	}
+
+	pos := r.abs(r.cursor)
+	clip := SmallRect{Left: pos.X, Top: pos.Y, Right: r.window.Right, Bottom: pos.Y}
+	scroll := clip
+	r.fail(r.out.ScrollScreenBuffer(&scroll, &clip, Coord{X: pos.X + n, Y: pos.Y}, r.blank()))
+}
+
+func (r *vtRenderer) EscDispatch(intermediates []byte, final byte) {
+	if len(intermediates) > 0 {
+		return
+	}
+
+	switch final {
+	case '7':
+		r.saved, r.savedAttr = r.cursor, r.attr
+	case '8':
+		r.cursor, r.attr = r.saved, r.savedAttr
+		r.pendingWrap = false
+	case 'D':
+		r.lineFeed()
+	case 'E':
+		r.cursor.X = 0
+		r.lineFeed()
+	case 'M':
+		r.reverseIndex()
+	case 'c':
+		r.attr, r.top, r.bottom, r.autowrap = r.defaultAttr, 0, -1, true
+		r.erase(SmallRect{Right: r.width() - 1, Bottom: r.height() - 1})
+		r.cursor = Coord{}
+	}
+}
+
+// param returns the i-th parameter, or def when it is omitted or zero.
+func param(params []int, i, def int) int {
+	if i >= len(params) || params[i] <= 0 {
+		return def
+	}
+
+	return params[i]
+}
+
+func (r *vtRenderer) CsiDispatch(private byte, params []int, intermediates []byte, final byte) {
+	if len(intermediates) > 0 {
+		return
+	}
+
+	if private == '?' {
+		r.privateMode(params, final)
+		return
+	}
+
+	if private != 0 {
+		return
+	}
+
+	n := int16(min(param(params, 0, 1), 32767))
+	r.pendingWrap = false
+
+	switch final {
+	case 'A':
+		top, _ := r.region()
+		if r.cursor.Y < top {
+			top = 0
+		}
+		r.cursor.Y = max(top, r.cursor.Y-n)
+	case 'B':
+		_, bottom := r.region()
+		if r.cursor.Y > bottom {
+			bottom = r.height() - 1
+		}
+		r.cursor.Y = min(bottom, r.cursor.Y+n)
+	case 'C':
+		r.cursor.X = min(r.width()-1, r.cursor.X+n)
+	case 'D':
+		r.cursor.X = max(0, r.cursor.X-n)
+	case 'E':
+		r.cursor.X, r.cursor.Y = 0, min(r.height()-1, r.cursor.Y+n)
+	case 'F':
+		r.cursor.X, r.cursor.Y = 0, max(0, r.cursor.Y-n)
+	case 'G', '`':
+		r.cursor.X = min(r.width()-1, n-1)
+	case 'd':
+		r.cursor.Y = min(r.height()-1, n-1)
+	case 'H', 'f':
+		r.cursor.Y = min(r.height()-1, int16(min(param(params, 0, 1), 32767))-1)
+		r.cursor.X = min(r.width()-1, int16(min(param(params, 1, 1), 32767))-1)
+	case 'J':
+		r.eraseDisplay(param(params, 0, 0))
+	case 'K':
+		r.eraseLine(param(params, 0, 0))
+	case '@':
+		r.shiftLine(n)
+	case 'P':
+		r.shiftLine(-n)
+	case 'X':
+		r.erase(SmallRect{Left: r.cursor.X, Top: r.cursor.Y, Right: min(r.width()-1, r.cursor.X+n-1), Bottom: r.cursor.Y})
+	case 'L':
+		if top, bottom := r.region(); r.cursor.Y >= top && r.cursor.Y <= bottom {
+			r.scrollRows(r.cursor.Y, bottom, n)
+			r.cursor.X = 0
+		}
+	case 'M':
+		if top, bottom := r.region(); r.cursor.Y >= top && r.cursor.Y <= bottom {
+			r.scrollRows(r.cursor.Y, bottom, -n)
+			r.cursor.X = 0
+		}
+	case 'S':
+		r.scrollUp(n)
+	case 'T':
+		r.scrollDown(n)
+	case 'm':
+		groups := make([][]int, len(params))
+		for i := range params {
+			groups[i] = params[i : i+1]
+		}
+		r.sgr(groups)
+	case 'r':
+		if len(params) == 0 {
+			r.top, r.bottom, r.cursor = 0, -1, Coord{}
+			break
+		}
+
+		top, bottom := int16(param(params, 0, 1))-1, int16(param(params, 1, int(r.height())))-1
+		if top < bottom && bottom < r.height() {
+			r.top, r.bottom = top, bottom
+			r.cursor = Coord{}
+		}
+	case 's':
+		r.saved = r.cursor
+	case 'u':
+		r.cursor = r.saved
+	}
+}
+
+func (r *vtRenderer) eraseDisplay(mode int) {
+	right, last := r.width()-1, r.height()-1
+	switch mode {
+	case 0:
+		r.eraseLine(0)
+		if r.cursor.Y < last {
+			r.erase(SmallRect{Left: 0, Top: r.cursor.Y + 1, Right: right, Bottom: last})
+		}
+	case 1:
+		r.eraseLine(1)
+		if r.cursor.Y > 0 {
+			r.erase(SmallRect{Left: 0, Top: 0, Right: right, Bottom: r.cursor.Y - 1})
+		}
+	case 2, 3:
+		r.erase(SmallRect{Left: 0, Top: 0, Right: right, Bottom: last})
+	}
+}
+
+func (r *vtRenderer) eraseLine(mode int) {
+	line := SmallRect{Left: 0, Top: r.cursor.Y, Right: r.width() - 1, Bottom: r.cursor.Y}
+	switch mode {
+	case 0:
+		line.Left = r.cursor.X
+	case 1:
+		line.Right = r.cursor.X
+	}
+
+	r.erase(line)
+}
+
+func (r *vtRenderer) privateMode(params []int, final byte) {
+	if final != 'h' && final != 'l' {
+		return
+	}
+
+	set := final == 'h'
+	for _, p := range params {
+		switch p {
+		case 7:
+			r.autowrap = set
+		case 25:
+			r.fail(SetCursorVisible(r.out, set))
+		}
+	}
+}
+
+// CsiDispatchGroups reads the colors of SGR 38 and 48 given as sub-parameters. The other sequences take
+// no sub-parameters, and the first parameter of each group stands for the group.
+func (r *vtRenderer) CsiDispatchGroups(private byte, groups [][]int, intermediates []byte, final byte) {
+	if private == 0 && len(intermediates) == 0 && final == 'm' {
+		r.sgr(groups)
+		return
+	}
+
+	params := make([]int, len(groups))
+	for i, group := range groups {
+		params[i] = group[0]
+	}
+
+	r.CsiDispatch(private, params, intermediates, final)
+}
+
+// sgr applies a Select Graphic Rendition sequence to the current attributes, with a group per parameter
+// holding its sub-parameters after it.
+func (r *vtRenderer) sgr(groups [][]int) {
+	if len(groups) == 0 {
+		groups = [][]int{{0}}
+	}
+
+	const (
+		fgMask = ForegroundBlue | ForegroundGreen | ForegroundRed | ForegroundIntensity
+		bgMask = BackgroundBlue | BackgroundGreen | BackgroundRed | BackgroundIntensity
+	)
+
+	for i := 0; i < len(groups); i++ {
+		switch p := max(groups[i][0], 0); {
+		case p == 0:
+			r.attr = r.defaultAttr
+		case p == 1:
+			r.attr |= ForegroundIntensity
+		case p == 22:
+			r.attr &^= ForegroundIntensity
+		case p == 4:
+			r.attr |= CommonLvbUnderscore
+		case p == 24:
+			r.attr &^= CommonLvbUnderscore
+		case p == 7:
+			r.attr |= CommonLvbReverseVideo
+		case p == 27:
+			r.attr &^= CommonLvbReverseVideo
+		case p >= 30 && p <= 37:
+			r.attr = r.attr&^(fgMask&^ForegroundIntensity) | uint16(consoleNibble(p-30))
+		case p >= 90 && p <= 97:
+			r.attr = r.attr&^fgMask | uint16(consoleNibble(p-90)) | ForegroundIntensity
+		case p == 39:
+			r.attr = r.attr&^fgMask | r.defaultAttr&fgMask
+		case p >= 40 && p <= 47:
+			r.attr = r.attr&^(bgMask&^BackgroundIntensity) | uint16(consoleNibble(p-40))<<4
+		case p >= 100 && p <= 107:
+			r.attr = r.attr&^bgMask | uint16(consoleNibble(p-100))<<4 | BackgroundIntensity
+		case p == 49:
+			r.attr = r.attr&^bgMask | r.defaultAttr&bgMask
+		case p == 38 || p == 48:
+			var nibble int
+			if len(groups[i]) > 1 {
+				nibble = subParamColor(groups[i][1:])
+			} else {
+				// The arguments follow as parameters of their own, as in 38;2;r;g;b.
+				var args []int
+				for _, group := range groups[i+1:] {
+					if len(group) > 1 {
+						break
+					}
+					args = append(args, group[0])
+				}
+
+				var used int
+				nibble, used = extendedColor(args)
+				i += used
+			}
+
+			if nibble < 0 {
+				continue
+			}
+
+			if p == 38 {
+				r.attr = r.attr&^fgMask | uint16(nibble)
+			} else {
+				r.attr = r.attr&^bgMask | uint16(nibble)<<4
+			}
+		}
+	}
+}
+
+// extendedColor decodes the arguments of SGR 38 and 48 (5;n or 2;r;g;b) to the nearest console color nibble.
+// It returns -1 as the nibble when the arguments are malformed, and the number of arguments consumed.
+func extendedColor(args []int) (int, int) {
+	switch param(args, 0, 0) {
+	case 5:
+		if len(args) < 2 {
+			return -1, len(args)
+		}
+		return int(PaletteColor(colorComponent(args[1])).Nibble()), 2
+	case 2:
+		if len(args) < 4 {
+			return -1, len(args)
+		}
+		return int(RGBColor(colorComponent(args[1]), colorComponent(args[2]), colorComponent(args[3])).Nibble()), 4
+	}
+
+	return -1, min(len(args), 1)
+}
+
+// subParamColor decodes the sub-parameters of SGR 38 and 48 to the nearest console color nibble: 5:n, or 2:r:g:b
+// with the color space of T.416 before the components, as in 2::r:g:b, or without it as xterm also accepts.
+// It returns -1 when the sub-parameters are malformed.
+func subParamColor(args []int) int {
+	switch {
+	case args[0] == 5 && len(args) == 2:
+		return int(PaletteColor(colorComponent(args[1])).Nibble())
+	case args[0] == 2 && len(args) >= 5:
+		return int(RGBColor(colorComponent(args[2]), colorComponent(args[3]), colorComponent(args[4])).Nibble())
+	case args[0] == 2 && len(args) == 4:
+		return int(RGBColor(colorComponent(args[1]), colorComponent(args[2]), colorComponent(args[3])).Nibble())
+	}
+
+	return -1
+}
+
+// colorComponent clamps a color argument to a byte, an omitted argument counting as 0.
+func colorComponent(arg int) uint8 {
+	return uint8(min(max(arg, 0), 255))
+}
+
+func (r *vtRenderer) OscDispatch(data []byte) {
+	cmd, title, ok := strings.Cut(string(data), ";")
+	if !ok || (cmd != "0" && cmd != "2") {
+		return
+	}
+
+	if setter, ok := r.out.(interface{ SetWindowTitle(title string) error }); ok {
+		r.fail(setter.SetWindowTitle(title))
+	}
+}
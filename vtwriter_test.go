@@ -0,0 +1,110 @@
+package cons
+
+import "testing"
+
+// renderAttr writes s followed by X through a VTWriter and returns the attributes of the X.
+func renderAttr(t *testing.T, s string) uint16 {
+	t.Helper()
+
+	vc := NewVirtualConsole(10, 2)
+	w, err := NewVTWriter(vc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write([]byte(s + "X")); err != nil {
+		t.Fatal(err)
+	}
+
+	return vc.Cell(0, 0).Attributes
+}
+
+func TestVTWriterExtendedColors(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		same string
+	}{
+		{"T.416 rgb", "\x1b[38:2::255:128:0m", "\x1b[38;2;255;128;0m"},
+		{"rgb without color space", "\x1b[38:2:255:128:0m", "\x1b[38;2;255;128;0m"},
+		{"palette", "\x1b[48:5:196m", "\x1b[48;5;196m"},
+		{"followed by a parameter", "\x1b[38:2::0:0:255;4m", "\x1b[38;2;0;0;255;4m"},
+		{"clamped", "\x1b[38;2;300;999;0m", "\x1b[38;2;255;255;0m"},
+		{"clamped sub-parameters", "\x1b[38:2::256:0:1000m", "\x1b[38;2;255;0;255m"},
+		{"palette clamped", "\x1b[38;5;300m", "\x1b[38;5;255m"},
+		{"malformed", "\x1b[31;38:2:1m", "\x1b[31m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, want := renderAttr(t, tt.seq), renderAttr(t, tt.same)
+			if got != want {
+				t.Errorf("%q gives %#x, want %#x as %q", tt.seq, got, want, tt.same)
+			}
+		})
+	}
+
+	if renderAttr(t, "\x1b[38:2::255:128:0m") == renderAttr(t, "") {
+		t.Error("the color given as sub-parameters was ignored")
+	}
+	if attr := renderAttr(t, "\x1b[38:2::0:0:255;4m"); attr&CommonLvbUnderscore == 0 {
+		t.Errorf("the parameter after a color group was consumed: %#x", attr)
+	}
+}
+
+// csiRecorder is a VTHandler keeping the control sequences it receives.
+type csiRecorder struct {
+	VTHandler
+	params [][]int
+}
+
+func (r *csiRecorder) CsiDispatch(private byte, params []int, intermediates []byte, final byte) {
+	r.params = append(r.params, append([]int(nil), params...))
+}
+
+func TestVTParserSubParams(t *testing.T) {
+	var groups [][]int
+	p := NewVTParser(groupRecorder{&groups})
+	p.Write([]byte("\x1b[1;38:2::10:20:30;;4:3m"))
+
+	want := [][]int{{1}, {38, 2, -1, 10, 20, 30}, {-1}, {4, 3}}
+	if len(groups) != len(want) {
+		t.Fatalf("groups = %v, want %v", groups, want)
+	}
+	for i := range want {
+		if len(groups[i]) != len(want[i]) {
+			t.Fatalf("groups = %v, want %v", groups, want)
+		}
+		for j := range want[i] {
+			if groups[i][j] != want[i][j] {
+				t.Fatalf("groups = %v, want %v", groups, want)
+			}
+		}
+	}
+
+	// A handler without sub-parameter support still receives every parameter.
+	r := &csiRecorder{}
+	NewVTParser(r).Write([]byte("\x1b[38:5:1m"))
+	if len(r.params) != 1 || len(r.params[0]) != 3 || r.params[0][2] != 1 {
+		t.Errorf("params = %v, want [[38 5 1]]", r.params)
+	}
+}
+
+// groupRecorder is a VTSubParamHandler keeping the parameter groups of the last control sequence.
+type groupRecorder struct {
+	groups *[][]int
+}
+
+func (groupRecorder) Print(r rune)                                 {}
+func (groupRecorder) Execute(c byte)                               {}
+func (groupRecorder) EscDispatch(intermediates []byte, final byte) {}
+func (groupRecorder) OscDispatch(data []byte)                      {}
+func (groupRecorder) CsiDispatch(private byte, params []int, intermediates []byte, final byte) {
+}
+
+func (g groupRecorder) CsiDispatchGroups(private byte, groups [][]int, intermediates []byte, final byte) {
+	*g.groups = nil
+	for _, group := range groups {
+		*g.groups = append(*g.groups, append([]int(nil), group...))
+	}
+}
@@ -0,0 +1,488 @@
+package cons
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// RuneWidth returns the number of cells a character takes on a console or terminal: 2 for the Wide and Fullwidth
+// characters of East Asian Width, which include the emoji with emoji presentation, 0 for combining marks,
+// format characters and controls, and 1 otherwise.
+//
+// Parameters:
+//
+//	r: The character to measure.
+//
+// Returns:
+//
+//	int: The number of cells, 0, 1 or 2.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || r >= 0x7f && r < 0xa0:
+		return 0
+	case r < 0x7f || r == 0xad:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1160 && r <= 0x11ff || r >= 0xd7b0 && r <= 0xd7ff:
+		// The medial vowels and final consonants of conjoining Hangul join the initial consonant before them.
+		return 0
+	case unicode.Is(wideTable, r):
+		return 2
+	}
+
+	return 1
+}
+
+// StringWidth returns the number of cells a text takes on a single row, measured grapheme cluster by grapheme
+// cluster as NextGrapheme does.
+//
+// Parameters:
+//
+//	s: The text to measure.
+//
+// Returns:
+//
+//	int: The number of cells.
+func StringWidth(s string) int {
+	total := 0
+	for s != "" {
+		var width int
+		_, s, width = NextGrapheme(s)
+		total += width
+	}
+
+	return total
+}
+
+// NextGrapheme splits the first grapheme cluster, the characters displayed as one, from a text. Clusters follow
+// the extended grapheme cluster rules of UAX #29 without the Prepend rule: combining marks and variation
+// selectors stay with their base, Hangul jamo with their syllable, emoji sequences joined by ZERO WIDTH JOINER
+// and regional indicator pairs are kept whole, and CR LF is one cluster.
+//
+// The width of a cluster is the width of its first character, or 2 for a regional indicator pair or a
+// character followed by VARIATION SELECTOR-16, which asks for emoji presentation.
+//
+// Parameters:
+//
+//	s: The text to split.
+//
+// Returns:
+//
+//	string: The first grapheme cluster of s, empty if s is empty.
+//	string: The rest of s.
+//	int: The number of cells the cluster takes.
+func NextGrapheme(s string) (string, string, int) {
+	if s == "" {
+		return "", "", 0
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	prev := graphemeClassOf(r)
+	width := RuneWidth(r)
+	pict := prev == gcPictographic // Whether the cluster is a pictograph followed by extending characters.
+	regional := 0                  // The number of regional indicators ending the cluster.
+	if prev == gcRegional {
+		regional = 1
+	}
+
+	i := size
+	for i < len(s) {
+		r, size = utf8.DecodeRuneInString(s[i:])
+		next := graphemeClassOf(r)
+		if !graphemeJoins(prev, next, pict, regional) {
+			break
+		}
+
+		switch {
+		case next == gcRegional:
+			regional++
+			width = 2
+		case r == 0xfe0f && width == 1:
+			width = 2
+		}
+
+		switch next {
+		case gcPictographic:
+			pict = true
+		case gcExtend, gcZWJ:
+		default:
+			pict = false
+		}
+
+		if next != gcRegional {
+			regional = 0
+		}
+
+		prev = next
+		i += size
+	}
+
+	return s[:i], s[i:], width
+}
+
+// Graphemes splits a text into grapheme clusters.
+//
+// Parameters:
+//
+//	s: The text to split.
+//
+// Returns:
+//
+//	[]string: The grapheme clusters of s, in order.
+func Graphemes(s string) []string {
+	var clusters []string
+	for s != "" {
+		var cluster string
+		cluster, s, _ = NextGrapheme(s)
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters
+}
+
+// Truncate shortens a text to fit in a number of cells, never splitting a grapheme cluster.
+//
+// Parameters:
+//
+//	s: The text to shorten.
+//	width: The number of cells available.
+//	tail: The text appended when s is shortened, such as "…"; it is left out if it does not fit.
+//
+// Returns:
+//
+//	string: s if it fits, otherwise its longest prefix that fits with the tail, followed by the tail.
+func Truncate(s string, width int, tail string) string {
+	if StringWidth(s) <= width {
+		return s
+	}
+
+	room := width - StringWidth(tail)
+	if room < 0 {
+		room, tail = width, ""
+	}
+
+	var sb strings.Builder
+	for used := 0; s != ""; {
+		cluster, rest, w := NextGrapheme(s)
+		if used+w > room {
+			break
+		}
+
+		sb.WriteString(cluster)
+		used, s = used+w, rest
+	}
+
+	sb.WriteString(tail)
+	return sb.String()
+}
+
+// TextCells lays out a text in cells, one cell per grapheme cluster of width 1 and a leading and a trailing
+// cell, marked with CommonLvbLeadingByte and CommonLvbTrailingByte, per cluster of width 2. A cell holds a
+// single character, so the combining marks and joined characters of a cluster are left out, and a character
+// of width 1 outside the Basic Multilingual Plane, which a single cell cannot hold, becomes U+FFFD.
+//
+// Parameters:
+//
+//	s: The text to lay out.
+//	attr: The attributes of the cells.
+//
+// Returns:
+//
+//	[]CharInfo: The cells, as many as StringWidth(s).
+func TextCells(s string, attr uint16) []CharInfo {
+	return fitCells(s, attr, math.MaxInt)
+}
+
+// WriteString writes a text on a row of the screen buffer starting at wcoord, laid out as TextCells does,
+// so wide characters take their two cells and the columns that follow stay aligned. The text is clipped
+// at the end of the row; a wide character that does not fit in the last column leaves it blank.
+//
+// Parameters:
+//
+//	hStdout: The output side of the console.
+//	s: The text to write.
+//	attr: The attributes of the cells.
+//	wcoord: The position of the first cell.
+//
+// Returns:
+//
+//	Coord: The position following the last cell written.
+//	error: If the function successfully writes the text, it returns nil. Otherwise, it returns an error.
+func WriteString(hStdout OutputTarget, s string, attr uint16, wcoord Coord) (Coord, error) {
+	var scrbufinfo ScreenBufferInfo
+	if err := hStdout.GetScreenBufferInfo(&scrbufinfo); err != nil {
+		return wcoord, err
+	}
+
+	if wcoord.X < 0 || wcoord.Y < 0 || wcoord.X >= scrbufinfo.Size.X || wcoord.Y >= scrbufinfo.Size.Y {
+		return wcoord, ErrInvalidParameter
+	}
+
+	cells := fitCells(s, attr, int(scrbufinfo.Size.X-wcoord.X))
+	if err := writeCells(hStdout, cells, wcoord); err != nil {
+		return wcoord, err
+	}
+
+	return Coord{X: wcoord.X + int16(len(cells)), Y: wcoord.Y}, nil
+}
+
+// fitCells lays out as much of a text as fits in room cells, padding with a blank the cell left by a wide
+// character that does not fit.
+func fitCells(s string, attr uint16, room int) []CharInfo {
+	var cells []CharInfo
+	for s != "" {
+		cluster, rest, width := NextGrapheme(s)
+		if len(cells)+width > room {
+			if len(cells) < room {
+				cells = append(cells, CharInfo{UnicodeChar: ' ', Attributes: attr})
+			}
+			break
+		}
+
+		r, _ := utf8.DecodeRuneInString(cluster)
+		cells = append(cells, layoutRune(r, width, attr)...)
+		s = rest
+	}
+
+	return cells
+}
+
+// layoutRune returns the cells of a character taking width cells. A wide character fills a leading and a
+// trailing cell holding either the character twice or its surrogate pair.
+func layoutRune(r rune, width int, attr uint16) []CharInfo {
+	units := runeUnits(r)
+	switch width {
+	case 0:
+		return nil
+	case 1:
+		if len(units) == 2 {
+			units = []uint16{utf8.RuneError}
+		}
+		return []CharInfo{{UnicodeChar: units[0], Attributes: attr}}
+	}
+
+	return []CharInfo{
+		{UnicodeChar: units[0], Attributes: attr | CommonLvbLeadingByte},
+		{UnicodeChar: units[len(units)-1], Attributes: attr | CommonLvbTrailingByte},
+	}
+}
+
+// cellWriter is implemented by the outputs that write a row of cells exactly as given, leading and trailing
+// cells included, where WriteCharacter would lay out a wide character by itself.
+type cellWriter interface {
+	writeCells(cells []CharInfo, wcoord Coord) error
+}
+
+// writeCells writes cells to a row of the screen buffer, as one call on a cellWriter
+// or as runs of WriteAttribute and WriteCharacter calls.
+func writeCells(out OutputTarget, cells []CharInfo, at Coord) error {
+	if cw, ok := out.(cellWriter); ok {
+		return cw.writeCells(cells, at)
+	}
+
+	for i := 0; i < len(cells); {
+		n := 1
+		for i+n < len(cells) && cells[i+n].Attributes == cells[i].Attributes {
+			n++
+		}
+
+		if _, err := out.WriteAttribute(cells[i].Attributes, uint32(n), Coord{X: at.X + int16(i), Y: at.Y}); err != nil {
+			return err
+		}
+		i += n
+	}
+
+	for i := 0; i < len(cells); {
+		n := 1
+		for i+n < len(cells) && cells[i+n].UnicodeChar == cells[i].UnicodeChar {
+			n++
+		}
+
+		if _, err := out.WriteCharacter(cells[i].UnicodeChar, uint32(n), Coord{X: at.X + int16(i), Y: at.Y}); err != nil {
+			return err
+		}
+		i += n
+	}
+
+	return nil
+}
+
+// cellSpan widens a span of a row of cells, inclusive on both sides, so it does not split a wide character
+// or a surrogate pair, and so it covers the other half of one whose half the span overwrites.
+func cellSpan(row []CharInfo, from, to int) (int, int) {
+	if from > 0 && (isSecondHalf(row[from]) || isFirstHalf(row[from-1])) {
+		from--
+	}
+
+	if to < len(row)-1 && (isFirstHalf(row[to]) || isSecondHalf(row[to+1])) {
+		to++
+	}
+
+	return from, to
+}
+
+// isFirstHalf reports whether a cell is the leading cell of a wide character or holds a high surrogate.
+func isFirstHalf(cell CharInfo) bool {
+	return cell.Attributes&CommonLvbLeadingByte != 0 || isHighSurrogate(cell.UnicodeChar)
+}
+
+// isSecondHalf reports whether a cell is the trailing cell of a wide character or holds a low surrogate.
+func isSecondHalf(cell CharInfo) bool {
+	return cell.Attributes&CommonLvbTrailingByte != 0 || isLowSurrogate(cell.UnicodeChar)
+}
+
+// halfAttributes are the attributes marking the halves of a wide character.
+const halfAttributes = CommonLvbLeadingByte | CommonLvbTrailingByte
+
+// cellsText returns the text of a row of cells, writing each wide character once.
+func cellsText(cells []CharInfo) string {
+	units := make([]uint16, 0, len(cells))
+	for _, cell := range cells {
+		// The trailing cell of a wide character repeats it, unless it holds the low surrogate.
+		if cell.Attributes&CommonLvbTrailingByte != 0 && !isLowSurrogate(cell.UnicodeChar) {
+			continue
+		}
+		units = append(units, cell.UnicodeChar)
+	}
+
+	return string(utf16.Decode(units))
+}
+
+// appendVTCells appends the text of a span of cells to sb, with an SGR sequence wherever the attributes change.
+// A wide character is written once for its two cells, and half of one whose other half was overwritten is
+// written as a space, so the terminal cursor advances by exactly one column per cell.
+func appendVTCells(sb *strings.Builder, cells []CharInfo) {
+	attr := -1
+	for i := 0; i < len(cells); i++ {
+		cell := cells[i]
+		if a := int(cell.Attributes &^ halfAttributes); a != attr {
+			attr = a
+			sb.WriteString(attributesSGR(cell.Attributes))
+		}
+
+		r := rune(cell.UnicodeChar)
+		next := rune(-1)
+		if i+1 < len(cells) {
+			next = rune(cells[i+1].UnicodeChar)
+		}
+
+		switch {
+		case cell.Attributes&CommonLvbLeadingByte != 0 && next >= 0 && cells[i+1].Attributes&CommonLvbTrailingByte != 0:
+			if utf16.IsSurrogate(r) {
+				r = utf16.DecodeRune(r, next)
+			}
+			if RuneWidth(r) == 2 {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteString("  ")
+			}
+			i++
+		case cell.Attributes&halfAttributes != 0:
+			sb.WriteByte(' ')
+		case isHighSurrogate(cell.UnicodeChar) && next >= 0:
+			sb.WriteRune(utf16.DecodeRune(r, next))
+			i++
+		case utf16.IsSurrogate(r):
+			sb.WriteRune(utf8.RuneError)
+		case r < ' ' || r == 0x7f:
+			sb.WriteByte(' ')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+func isHighSurrogate(c uint16) bool {
+	return c >= 0xd800 && c < 0xdc00
+}
+
+func isLowSurrogate(c uint16) bool {
+	return c >= 0xdc00 && c < 0xe000
+}
+
+// graphemeClass is the Grapheme_Cluster_Break property of a character, with Extended_Pictographic.
+type graphemeClass uint8
+
+const (
+	gcOther graphemeClass = iota
+	gcCR
+	gcLF
+	gcControl
+	gcExtend
+	gcZWJ
+	gcRegional
+	gcSpacingMark
+	gcL
+	gcV
+	gcT
+	gcLV
+	gcLVT
+	gcPictographic
+)
+
+func graphemeClassOf(r rune) graphemeClass {
+	switch {
+	case r == '\r':
+		return gcCR
+	case r == '\n':
+		return gcLF
+	case r < 0x20 || r >= 0x7f && r < 0xa0 || r == 0x2028 || r == 0x2029:
+		return gcControl
+	case r < 0x7f:
+		return gcOther
+	case r == 0x200d:
+		return gcZWJ
+	case r >= 0x1f1e6 && r <= 0x1f1ff:
+		return gcRegional
+	case r == 0x200c || r >= 0x1f3fb && r <= 0x1f3ff || r >= 0xe0020 && r <= 0xe007f || r == 0xff9e || r == 0xff9f ||
+		unicode.In(r, unicode.Mn, unicode.Me):
+		return gcExtend
+	case unicode.Is(unicode.Cf, r):
+		return gcControl
+	case unicode.Is(unicode.Mc, r):
+		return gcSpacingMark
+	case r >= 0x1100 && r <= 0x115f || r >= 0xa960 && r <= 0xa97c:
+		return gcL
+	case r >= 0x1160 && r <= 0x11a7 || r >= 0xd7b0 && r <= 0xd7c6:
+		return gcV
+	case r >= 0x11a8 && r <= 0x11ff || r >= 0xd7cb && r <= 0xd7fb:
+		return gcT
+	case r >= 0xac00 && r <= 0xd7a3 && (r-0xac00)%28 == 0:
+		return gcLV
+	case r >= 0xac00 && r <= 0xd7a3:
+		return gcLVT
+	case unicode.Is(pictographicTable, r):
+		return gcPictographic
+	}
+
+	return gcOther
+}
+
+// graphemeJoins reports whether no grapheme cluster boundary lies between two characters, given whether the
+// cluster is a pictograph followed by extending characters and the number of regional indicators ending it.
+func graphemeJoins(prev, next graphemeClass, pict bool, regional int) bool {
+	switch {
+	case prev == gcCR && next == gcLF:
+		return true
+	case prev == gcCR || prev == gcLF || prev == gcControl || next == gcCR || next == gcLF || next == gcControl:
+		return false
+	case prev == gcL && (next == gcL || next == gcV || next == gcLV || next == gcLVT):
+		return true
+	case (prev == gcLV || prev == gcV) && (next == gcV || next == gcT):
+		return true
+	case (prev == gcLVT || prev == gcT) && next == gcT:
+		return true
+	case next == gcExtend || next == gcZWJ || next == gcSpacingMark:
+		return true
+	case prev == gcZWJ && next == gcPictographic:
+		return pict
+	case prev == gcRegional && next == gcRegional:
+		return regional%2 == 1
+	}
+
+	return false
+}
@@ -0,0 +1,295 @@
+package cons
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want int
+	}{
+		{'a', 1},
+		{'\t', 0},
+		{0x7f, 0},
+		{0x9b, 0},
+		{0xad, 1},    // SOFT HYPHEN
+		{0x301, 0},   // COMBINING ACUTE ACCENT
+		{0x200d, 0},  // ZERO WIDTH JOINER
+		{0xfe0f, 0},  // VARIATION SELECTOR-16
+		{'é', 1},     // Latin-1
+		{'─', 1},     // box drawing, Ambiguous
+		{'日', 2},     // CJK ideograph
+		{0x9fff, 2},  // unassigned in the CJK ideograph block
+		{'Ａ', 2},     // FULLWIDTH LATIN CAPITAL LETTER A
+		{'ｶ', 1},     // HALFWIDTH KATAKANA LETTER KA
+		{0x1100, 2},  // HANGUL CHOSEONG KIYEOK
+		{0x1161, 0},  // HANGUL JUNGSEONG A
+		{0x11a8, 0},  // HANGUL JONGSEONG KIYEOK
+		{'한', 2},     // Hangul syllable
+		{'😀', 2},     // emoji presentation
+		{'⌚', 2},     // WATCH, emoji presentation in the BMP
+		{0x1f321, 1}, // THERMOMETER, text presentation
+		{0x1f3f3, 1}, // WAVING WHITE FLAG
+		{0x1f54a, 1}, // DOVE OF PEACE
+		{0x1f5a5, 1}, // DESKTOP COMPUTER
+		{0x1f5fa, 1}, // WORLD MAP
+		{0x1f90b, 1}, // DOWNWARD FACING NOTCHED HOOK WITH DOT
+		{0x1f90c, 2}, // PINCHED FINGERS
+		{0x20000, 2}, // CJK Unified Ideographs Extension B
+		{0x2fffd, 2}, // unassigned in plane 2
+		{0x1f1ef, 1}, // REGIONAL INDICATOR SYMBOL LETTER J
+		{0xe0001, 0}, // LANGUAGE TAG
+		{0x10ffff, 1},
+	}
+
+	for _, tt := range tests {
+		if got := RuneWidth(tt.r); got != tt.want {
+			t.Errorf("RuneWidth(%U) = %d, want %d", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestNextGrapheme(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		want   []string
+		widths []int
+	}{
+		{"ascii", "ab", []string{"a", "b"}, []int{1, 1}},
+		{"combining marks", "e\u0301\u0323x", []string{"e\u0301\u0323", "x"}, []int{1, 1}},
+		{"combining mark on a wide character", "日\u0301", []string{"日\u0301"}, []int{2}},
+		{"zwj sequence", "👨\u200d👩\u200d👧!", []string{"👨\u200d👩\u200d👧", "!"}, []int{2, 1}},
+		{"zwj after a letter", "a\u200d😀", []string{"a\u200d", "😀"}, []int{1, 2}},
+		{"skin tone", "👍\U0001f3fd", []string{"👍\U0001f3fd"}, []int{2}},
+		{"regional indicator pairs", "🇯🇵🇫🇷🇩", []string{"🇯🇵", "🇫🇷", "🇩"}, []int{2, 2, 1}},
+		{"hangul L V T", "\u1100\u1161\u11a8\u1100", []string{"\u1100\u1161\u11a8", "\u1100"}, []int{2, 2}},
+		{"hangul LV T", "가\u11a8각", []string{"가\u11a8", "각"}, []int{2, 2}},
+		{"cr lf", "\r\n\n\r", []string{"\r\n", "\n", "\r"}, []int{0, 0, 0}},
+		{"control before a mark", "\t\u0301", []string{"\t", "\u0301"}, []int{0, 0}},
+		{"vs16", "🌡\ufe0f🌡", []string{"🌡\ufe0f", "🌡"}, []int{2, 1}},
+		{"vs16 on a wide character", "😀\ufe0f", []string{"😀\ufe0f"}, []int{2}},
+		{"vs15", "⌚\ufe0e", []string{"⌚\ufe0e"}, []int{2}},
+		{"spacing mark", "कि", []string{"कि"}, []int{1}},
+		{"empty", "", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got    []string
+				widths []int
+			)
+			for s := tt.s; s != ""; {
+				var (
+					cluster string
+					width   int
+				)
+				cluster, s, width = NextGrapheme(s)
+				got, widths = append(got, cluster), append(widths, width)
+			}
+
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Fatalf("clusters = %q, want %q", got, tt.want)
+			}
+			for i := range widths {
+				if widths[i] != tt.widths[i] {
+					t.Errorf("width of %q = %d, want %d", got[i], widths[i], tt.widths[i])
+				}
+			}
+			if g := Graphemes(tt.s); strings.Join(g, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Graphemes = %q, want %q", g, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"日本語", 6},
+		{"e\u0301te\u0301", 3},
+		{"🇯🇵 flag", 7},
+		{"👨\u200d👩\u200d👧", 2},
+		{"🖥 desktop", 9},
+		{"🖥\ufe0f desktop", 10},
+		{"\x1b", 0},
+	}
+
+	for _, tt := range tests {
+		if got := StringWidth(tt.s); got != tt.want {
+			t.Errorf("StringWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		tail  string
+		want  string
+	}{
+		{"hello", 5, "…", "hello"},
+		{"hello world", 8, "…", "hello w…"},
+		{"hello", 3, "...", "..."},
+		{"hello", 2, "...", "he"},
+		{"日本語", 5, "…", "日本…"},
+		{"日本語", 4, "…", "日…"},
+		{"e\u0301e\u0301e\u0301", 2, "", "e\u0301e\u0301"},
+		{"🇯🇵🇫🇷", 3, "", "🇯🇵"},
+		{"abc", 0, "…", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.width, tt.tail); got != tt.want {
+			t.Errorf("Truncate(%q, %d, %q) = %q, want %q", tt.s, tt.width, tt.tail, got, tt.want)
+		}
+	}
+}
+
+func TestTextCells(t *testing.T) {
+	const attr = ForegroundGreen
+
+	tests := []struct {
+		name string
+		s    string
+		want []CharInfo
+	}{
+		{"narrow", "ab", []CharInfo{{UnicodeChar: 'a', Attributes: attr}, {UnicodeChar: 'b', Attributes: attr}}},
+		{"wide", "日", []CharInfo{
+			{UnicodeChar: '日', Attributes: attr | CommonLvbLeadingByte},
+			{UnicodeChar: '日', Attributes: attr | CommonLvbTrailingByte},
+		}},
+		{"wide outside the BMP", "😀", []CharInfo{
+			{UnicodeChar: 0xd83d, Attributes: attr | CommonLvbLeadingByte},
+			{UnicodeChar: 0xde00, Attributes: attr | CommonLvbTrailingByte},
+		}},
+		{"combining mark left out", "e\u0301", []CharInfo{{UnicodeChar: 'e', Attributes: attr}}},
+		{"narrow outside the BMP", "\U0001d400", []CharInfo{{UnicodeChar: 0xfffd, Attributes: attr}}},
+		{"controls take no cell", "a\tb", []CharInfo{{UnicodeChar: 'a', Attributes: attr}, {UnicodeChar: 'b', Attributes: attr}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TextCells(tt.s, attr)
+			if len(got) != len(tt.want) || len(got) != StringWidth(tt.s) {
+				t.Fatalf("TextCells(%q) = %+v, want %+v", tt.s, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("cell %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteString(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		at   Coord
+		next Coord
+		line string
+	}{
+		{"narrow", "abc", Coord{X: 1}, Coord{X: 4}, " abc"},
+		{"wide", "日本", Coord{}, Coord{X: 4}, "日本"},
+		{"clipped", "abcdefgh", Coord{X: 3}, Coord{X: 6}, "   abc"},
+		{"wide in the last column", "ab日", Coord{X: 3}, Coord{X: 6}, "   ab"},
+		{"wide fitting the last columns", "a日", Coord{X: 3}, Coord{X: 6}, "   a日"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := NewVirtualConsole(6, 2)
+
+			next, err := WriteString(vc, tt.s, vc.attributes, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next != tt.next {
+				t.Errorf("next = %+v, want %+v", next, tt.next)
+			}
+			if line := vc.Line(0); line != tt.line {
+				t.Errorf("line = %q, want %q", line, tt.line)
+			}
+		})
+	}
+
+	vc := NewVirtualConsole(6, 2)
+	if _, err := WriteString(vc, "x", vc.attributes, Coord{X: 6}); err != ErrInvalidParameter {
+		t.Errorf("WriteString past the row = %v, want ErrInvalidParameter", err)
+	}
+
+	// The blank left in the last column by a wide character that does not fit.
+	WriteString(vc, "abcde日", vc.attributes, Coord{Y: 1})
+	if cell := vc.Cell(5, 1); cell.UnicodeChar != ' ' || cell.Attributes&halfAttributes != 0 {
+		t.Errorf("last cell = %+v, want a blank", cell)
+	}
+}
+
+func TestCellSpan(t *testing.T) {
+	row := append(TextCells("a日b", 0), StringCells("😀", 0)...)
+	// a, 日 leading, 日 trailing, b, high surrogate, low surrogate
+
+	tests := []struct {
+		from, to         int
+		wantFrom, wantTo int
+	}{
+		{0, 0, 0, 0},
+		{3, 3, 3, 3},
+		{1, 1, 1, 2},
+		{2, 2, 1, 2},
+		{2, 3, 1, 3},
+		{0, 1, 0, 2},
+		{4, 4, 4, 5},
+		{5, 5, 4, 5},
+		{3, 4, 3, 5},
+	}
+
+	for _, tt := range tests {
+		from, to := cellSpan(row, tt.from, tt.to)
+		if from != tt.wantFrom || to != tt.wantTo {
+			t.Errorf("cellSpan(%d, %d) = %d, %d, want %d, %d", tt.from, tt.to, from, to, tt.wantFrom, tt.wantTo)
+		}
+	}
+}
+
+func TestAppendVTCells(t *testing.T) {
+	white, red := uint16(ForegroundRed|ForegroundGreen|ForegroundBlue), uint16(ForegroundRed)
+	wide := TextCells("日", white)
+	emoji := TextCells("😀", white)
+
+	tests := []struct {
+		name  string
+		cells []CharInfo
+		want  string
+	}{
+		{"text", TextCells("ab", white), attributesSGR(white) + "ab"},
+		{"attribute change", append(TextCells("a", white), TextCells("b", red)...), attributesSGR(white) + "a" + attributesSGR(red) + "b"},
+		{"wide", wide, attributesSGR(white) + "日"},
+		{"wide surrogate pair", emoji, attributesSGR(white) + "😀"},
+		{"leading half alone", wide[:1], attributesSGR(white) + " "},
+		{"trailing half alone", append(wide[1:], TextCells("x", white)...), attributesSGR(white) + " x"},
+		{"surrogate pair", StringCells("\U0001d400", white), attributesSGR(white) + "\U0001d400"},
+		{"lone surrogate", []CharInfo{{UnicodeChar: 0xdc00, Attributes: white}}, attributesSGR(white) + "�"},
+		{"controls", []CharInfo{{UnicodeChar: 0x1b, Attributes: white}, {UnicodeChar: 0, Attributes: white}}, attributesSGR(white) + "  "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			appendVTCells(&sb, tt.cells)
+			if got := sb.String(); got != tt.want {
+				t.Errorf("appendVTCells = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}